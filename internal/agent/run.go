@@ -0,0 +1,244 @@
+// Package agent runs a coding agent CLI (codex, claude, gemini) non-interactively
+// with a prompt and returns its output.
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"codectl/internal/tools"
+)
+
+// Options configure a streaming agent run.
+type Options struct {
+	// Model is passed to the agent's model flag when set.
+	Model string
+	// Progress receives the agent's live output (logs, tool calls, partial
+	// text); nil discards it.
+	Progress io.Writer
+}
+
+// Result separates the agent's final answer from everything else it printed.
+type Result struct {
+	Output string
+	Log    string
+}
+
+// Parse maps an agent name (codex, claude, gemini; any case) to its tool id.
+func Parse(name string) (tools.ToolID, error) {
+	names := make([]string, 0, len(tools.Tools))
+	for _, t := range tools.Tools {
+		if strings.EqualFold(string(t.ID), strings.TrimSpace(name)) {
+			return t.ID, nil
+		}
+		names = append(names, strings.ToLower(string(t.ID)))
+	}
+	return "", fmt.Errorf("unknown agent %q (want %s)", name, strings.Join(names, "|"))
+}
+
+// Find returns the path of the first binary of tool id found on PATH.
+func Find(id tools.ToolID) (string, error) {
+	for _, t := range tools.Tools {
+		if t.ID != id {
+			continue
+		}
+		for _, cand := range t.Binaries {
+			if p, err := exec.LookPath(cand); err == nil && p != "" {
+				return p, nil
+			}
+		}
+		return "", fmt.Errorf("未找到 %s（请先安装 %s 并确保在 PATH 中）", t.DisplayName, t.Package)
+	}
+	return "", fmt.Errorf("unknown agent %q", id)
+}
+
+// Run sends prompt to the agent id non-interactively, streaming its output
+// to opt.Progress. Cancelling ctx interrupts the agent.
+func Run(ctx context.Context, id tools.ToolID, prompt string, opt Options) (Result, error) {
+	bin, err := Find(id)
+	if err != nil {
+		return Result{}, err
+	}
+	progress := opt.Progress
+	if progress == nil {
+		progress = io.Discard
+	}
+	switch id {
+	case tools.ToolCodex:
+		return runCodex(ctx, bin, prompt, opt.Model, progress)
+	case tools.ToolClaude:
+		return runClaude(ctx, bin, prompt, opt.Model, progress)
+	default:
+		return runPlain(ctx, bin, prompt, opt.Model, progress)
+	}
+}
+
+// command prepares bin with the prompt on stdin; cancellation sends an
+// interrupt and kills the agent if it has not exited shortly after.
+func command(ctx context.Context, bin, prompt string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Env = append(os.Environ(), "NO_COLOR=1")
+	cmd.Stdin = strings.NewReader(prompt)
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = 3 * time.Second
+	return cmd
+}
+
+// wait runs cmd and maps a cancelled context to its error.
+func wait(ctx context.Context, cmd *exec.Cmd) error {
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// syncWriter serializes writes from the stdout and stderr copiers.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// runCodex uses `codex exec`, which writes its final message to the
+// --output-last-message file while progress goes to the terminal streams.
+func runCodex(ctx context.Context, bin, prompt, model string, progress io.Writer) (Result, error) {
+	dir, err := os.MkdirTemp("", "codectl-agent-")
+	if err != nil {
+		return Result{}, err
+	}
+	defer os.RemoveAll(dir)
+	last := filepath.Join(dir, "last-message.txt")
+	args := []string{"exec", "--color", "never", "--output-last-message", last}
+	if model != "" {
+		args = append(args, "-m", model)
+	}
+	args = append(args, "-") // prompt from stdin
+	var log bytes.Buffer
+	out := &syncWriter{w: io.MultiWriter(progress, &log)}
+	cmd := command(ctx, bin, prompt, args...)
+	cmd.Stdout, cmd.Stderr = out, out
+	runErr := wait(ctx, cmd)
+	res := Result{Log: log.String()}
+	if b, err := os.ReadFile(last); err == nil {
+		res.Output = string(b)
+	}
+	return res, runErr
+}
+
+// runClaude uses `claude -p --output-format stream-json`: assistant events
+// are rendered as progress and the result event carries the final answer.
+func runClaude(ctx context.Context, bin, prompt, model string, progress io.Writer) (Result, error) {
+	args := []string{"-p", "--output-format", "stream-json", "--verbose"}
+	if model != "" {
+		args = append(args, "--model", model)
+	}
+	var log bytes.Buffer
+	pw := &syncWriter{w: io.MultiWriter(progress, &log)}
+	cmd := command(ctx, bin, prompt, args...)
+	cmd.Stderr = pw
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return Result{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return Result{}, err
+	}
+	var res Result
+	var resultErr error
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		var ev claudeEvent
+		if json.Unmarshal([]byte(line), &ev) != nil {
+			fmt.Fprintln(pw, line)
+			continue
+		}
+		switch ev.Type {
+		case "assistant":
+			for _, c := range ev.Message.Content {
+				switch c.Type {
+				case "text":
+					fmt.Fprintln(pw, c.Text)
+				case "tool_use":
+					fmt.Fprintf(pw, "→ %s\n", c.Name)
+				}
+			}
+		case "result":
+			res.Output = ev.Result
+			if ev.IsError {
+				resultErr = fmt.Errorf("claude: %s", strings.TrimSpace(ev.Result))
+			}
+		}
+	}
+	err = cmd.Wait()
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	res.Log = log.String()
+	if err == nil {
+		err = resultErr
+	}
+	return res, err
+}
+
+type claudeEvent struct {
+	Type    string `json:"type"`
+	Result  string `json:"result"`
+	IsError bool   `json:"is_error"`
+	Message struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+			Name string `json:"name"`
+		} `json:"content"`
+	} `json:"message"`
+}
+
+// runPlain covers agents that print the answer on stdout and logs on
+// stderr (gemini): both are streamed, stdout is the output.
+func runPlain(ctx context.Context, bin, prompt, model string, progress io.Writer) (Result, error) {
+	var args []string
+	if model != "" {
+		args = append(args, "-m", model)
+	}
+	var out, log bytes.Buffer
+	pw := &syncWriter{w: progress}
+	cmd := command(ctx, bin, prompt, args...)
+	cmd.Stdout = io.MultiWriter(pw, &out)
+	cmd.Stderr = io.MultiWriter(pw, &log)
+	err := wait(ctx, cmd)
+	return Result{Output: out.String(), Log: log.String()}, err
+}
+
+// StripFence removes a markdown code fence wrapping the whole answer, as
+// agents often return documents inside ```markdown blocks.
+func StripFence(s string) string {
+	t := strings.TrimSpace(s)
+	first := strings.IndexByte(t, '\n')
+	if first < 0 || !strings.HasPrefix(t, "```") || !strings.HasSuffix(t, "```") {
+		return s
+	}
+	switch strings.TrimSpace(t[3:first]) {
+	case "", "md", "mdx", "markdown":
+	default:
+		return s
+	}
+	return strings.TrimSpace(strings.TrimSuffix(t[first+1:], "```")) + "\n"
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"codectl/internal/tools"
+)
+
+func TestParse(t *testing.T) {
+	for in, want := range map[string]tools.ToolID{"codex": tools.ToolCodex, "Claude": tools.ToolClaude, " gemini ": tools.ToolGemini} {
+		got, err := Parse(in)
+		if err != nil || got != want {
+			t.Fatalf("Parse(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := Parse("cursor"); err == nil || !strings.Contains(err.Error(), "codex|claude|gemini") {
+		t.Fatalf("want unknown agent error listing choices, got %v", err)
+	}
+}
+
+func TestStripFence(t *testing.T) {
+	cases := map[string]string{
+		"```markdown\n# A\n\ntext\n```\n": "# A\n\ntext\n",
+		"```\n---\ntitle: x\n---\n```":    "---\ntitle: x\n---\n",
+		"# A\n\n```go\nx\n```\n":          "# A\n\n```go\nx\n```\n",
+		"```go\nx\n```":                   "```go\nx\n```",
+	}
+	for in, want := range cases {
+		if got := StripFence(in); got != want {
+			t.Errorf("StripFence(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// fakeAgent installs an executable shell script named name on PATH.
+func fakeAgent(t *testing.T, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script agents")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestRunClaudeSeparatesResult(t *testing.T) {
+	fakeAgent(t, "claude", `cat >/dev/null
+echo "$@" >&2
+echo '{"type":"system","subtype":"init"}'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"thinking"},{"type":"tool_use","name":"Read"}]}}'
+printf '%s\n' '{"type":"result","result":"# Spec\n","is_error":false}'
+`)
+	var progress strings.Builder
+	res, err := Run(context.Background(), tools.ToolClaude, "hi", Options{Model: "sonnet", Progress: &progress})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Output != "# Spec\n" {
+		t.Fatalf("output = %q", res.Output)
+	}
+	for _, want := range []string{"--model sonnet", "thinking", "→ Read"} {
+		if !strings.Contains(progress.String(), want) {
+			t.Errorf("progress %q misses %q", progress.String(), want)
+		}
+	}
+	if strings.Contains(progress.String(), "# Spec") {
+		t.Errorf("final answer leaked into progress: %q", progress.String())
+	}
+}
+
+func TestRunCodexReadsLastMessage(t *testing.T) {
+	fakeAgent(t, "codex", `prompt=$(cat)
+while [ $# -gt 0 ]; do
+  if [ "$1" = "--output-last-message" ]; then out=$2; fi
+  shift
+done
+echo "working on: $prompt"
+printf '# Done\n' > "$out"
+`)
+	var progress strings.Builder
+	res, err := Run(context.Background(), tools.ToolCodex, "draft", Options{Progress: &progress})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Output != "# Done\n" || !strings.Contains(res.Log, "working on: draft") {
+		t.Fatalf("res = %+v", res)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	fakeAgent(t, "gemini", "exec sleep 30\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, tools.ToolGemini, "x", Options{}); err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
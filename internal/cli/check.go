@@ -1,17 +1,23 @@
 package cli
 
 import (
-	"bufio"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/aymanbagabas/go-udiff"
+	"github.com/aymanbagabas/go-udiff/lcs"
 	"github.com/spf13/cobra"
 
+	"codectl/internal/speccheck"
+	"codectl/internal/specgraph"
+	"codectl/internal/spechistory"
+	"codectl/internal/specstatus"
+	"codectl/internal/specversion"
 	"codectl/internal/system"
+	"codectl/internal/taskstore"
 )
 
 type checkItem struct {
@@ -20,6 +26,10 @@ type checkItem struct {
 	Fields         map[string]string `json:"fields,omitempty"`
 	Errors         []string          `json:"errors,omitempty"`
 	Warnings       []string          `json:"warnings,omitempty"`
+	// Findings carries the rule id and severity behind each message.
+	Findings []speccheck.Finding `json:"findings,omitempty"`
+	// Fixed lists the changes applied by --fix.
+	Fixed []speccheck.Finding `json:"fixed,omitempty"`
 }
 
 type checkReport struct {
@@ -28,31 +38,56 @@ type checkReport struct {
 	Items    []checkItem `json:"items"`
 	Errors   int         `json:"errors"`
 	Warnings int         `json:"warnings"`
+	Fixed    int         `json:"fixed,omitempty"`
 }
 
 var (
-	checkJSON bool
+	checkJSON   bool
+	checkFormat string
+	checkFix    bool
+	checkDryRun bool
+	checkBase   string
 )
 
 func init() {
 	rootCmd.AddCommand(checkCmd)
-	checkCmd.Flags().BoolVar(&checkJSON, "json", false, "output JSON report")
+	checkCmd.Flags().BoolVar(&checkJSON, "json", false, "output JSON report (same as --format json)")
+	checkCmd.Flags().StringVar(&checkFormat, "format", formatText, "output format: "+strings.Join(checkFormats, "|"))
+	checkCmd.Flags().BoolVar(&checkFix, "fix", false, "rewrite files in place to fix mechanical findings")
+	checkCmd.Flags().StringVar(&checkBase, "base", "HEAD", "git revision specs are compared with for specVersion bumps and changelog entries")
+	checkCmd.Flags().BoolVar(&checkDryRun, "dry-run", false, "with --fix, print a unified diff of the planned changes instead of writing")
 }
 
 var checkCmd = &cobra.Command{
 	Use:   "check",
-	Short: "Check MDX frontmatter under vibe-docs/spec (*.spec.mdx)",
+	Short: "Check spec and task docs under vibe-docs/spec and vibe-docs/task",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// determine repo root (fallback to CWD)
-		cwd, _ := os.Getwd()
-		root := cwd
-		if giRoot, err := system.GitRoot(cmd.Context(), cwd); err == nil && strings.TrimSpace(giRoot) != "" {
-			root = giRoot
+		format := strings.ToLower(strings.TrimSpace(checkFormat))
+		if checkJSON {
+			format = formatJSON
+		}
+		if !slices.Contains(checkFormats, format) {
+			return fmt.Errorf("unknown format %q (want %s)", checkFormat, strings.Join(checkFormats, "|"))
 		}
-		dirs := []string{
-			filepath.Join(root, "vibe-docs", "spec"),
+		root := repoRootOrCwd(cmd)
+		if checkWatch {
+			if checkFix || checkDryRun || format != formatText {
+				return fmt.Errorf("--watch prints text output and cannot be combined with --fix or --format; use --serve for JSON")
+			}
+			return watchCheck(cmd, root)
 		}
+		dirs := checkDirs(root)
 		rep := checkReport{Root: root, Dirs: make([]string, 0, len(dirs))}
+		rules, err := speccheck.Load(root)
+		if err != nil {
+			// a broken rules file is an error of its own; continue with defaults
+			rep.Items = append(rep.Items, checkItem{
+				Path:     filepath.Join(root, filepath.FromSlash(speccheck.RulesFile)),
+				Errors:   []string{err.Error()},
+				Findings: []speccheck.Finding{{Rule: speccheck.RuleRulesFile, Severity: speccheck.SeverityError, Message: err.Error()}},
+			})
+			rep.Errors++
+		}
 		for _, d := range dirs {
 			if st, err := os.Stat(d); err != nil || !st.IsDir() {
 				// skip silently if missing
@@ -70,10 +105,30 @@ var checkCmd = &cobra.Command{
 					return nil
 				}
 				name := strings.ToLower(de.Name())
-				if !strings.HasSuffix(name, ".spec.mdx") { // only spec docs
+				if !isCheckedDoc(name) {
 					return nil
 				}
-				it := checkMDX(path)
+				var fixed []speccheck.Finding
+				if checkFix || checkDryRun {
+					changed, fx, err := fixMDX(rules, root, path, checkDryRun)
+					if err != nil {
+						rep.Items = append(rep.Items, checkItem{Path: path, Errors: []string{err.Error()}})
+						rep.Errors++
+						return nil
+					}
+					if len(fx) > 0 {
+						rep.Fixed++
+						fixed = fx
+					}
+					if checkDryRun {
+						if len(fx) > 0 {
+							fmt.Print(fixDiff(root, path, changed))
+						}
+						return nil
+					}
+				}
+				it := checkMDX(rules, root, path)
+				it.Fixed = fixed
 				if len(it.Errors) > 0 {
 					rep.Errors += len(it.Errors)
 				}
@@ -88,27 +143,17 @@ var checkCmd = &cobra.Command{
 			}
 		}
 
-		if checkJSON {
-			// pretty JSON to stdout
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			if err := enc.Encode(rep); err != nil {
+		if !checkDryRun {
+			if err := addCrossFindings(cmd, &rep, root, rules); err != nil {
 				return err
 			}
-		} else {
-			// text summary
-			for _, it := range rep.Items {
-				if len(it.Errors) > 0 {
-					fmt.Printf("ERR  %s  %s\n", relFrom(root, it.Path), strings.Join(it.Errors, "; "))
-					continue
-				}
-				if len(it.Warnings) > 0 {
-					fmt.Printf("WARN %s  %s\n", relFrom(root, it.Path), strings.Join(it.Warnings, "; "))
-				} else {
-					fmt.Printf("OK   %s\n", relFrom(root, it.Path))
-				}
-			}
-			fmt.Printf("\nSummary: %d file(s), %d error(s), %d warning(s)\n", len(rep.Items), rep.Errors, rep.Warnings)
+		}
+		if checkDryRun {
+			fmt.Printf("\n%d file(s) would be fixed\n", rep.Fixed)
+			return nil
+		}
+		if err := writeCheckReport(os.Stdout, rep, format); err != nil {
+			return err
 		}
 
 		if rep.Errors > 0 {
@@ -119,6 +164,16 @@ var checkCmd = &cobra.Command{
 	},
 }
 
+// repoRootOrCwd returns the git top-level of the working directory, or the
+// working directory itself outside a repository.
+func repoRootOrCwd(cmd *cobra.Command) string {
+	cwd, _ := os.Getwd()
+	if giRoot, err := system.GitRoot(cmd.Context(), cwd); err == nil && strings.TrimSpace(giRoot) != "" {
+		return giRoot
+	}
+	return cwd
+}
+
 func relFrom(root, p string) string {
 	if r, err := filepath.Rel(root, p); err == nil {
 		return r
@@ -126,70 +181,218 @@ func relFrom(root, p string) string {
 	return p
 }
 
-// parse MDX frontmatter and validate required keys
-func checkMDX(path string) checkItem {
-	it := checkItem{Path: path}
-	b, err := os.ReadFile(path)
+// checkDirs returns the directories scanned by check.
+func checkDirs(root string) []string {
+	return []string{
+		filepath.Join(root, "vibe-docs", "spec"),
+		filepath.Join(root, "vibe-docs", "task"),
+	}
+}
+
+// isCheckedDoc reports whether the file name is a spec or task document.
+func isCheckedDoc(name string) bool {
+	return strings.HasSuffix(name, ".spec.mdx") || strings.HasSuffix(name, ".task.mdx")
+}
+
+// addCrossFindings adds the findings that depend on other documents or on
+// git history: links, duplicate numbers, task dependencies, versions and
+// workflow.
+func addCrossFindings(cmd *cobra.Command, rep *checkReport, root string, rules *speccheck.Rules) error {
+	addLinkFindings(rep, root)
+	addNumberFindings(rep, root, rules)
+	addDependencyFindings(rep, root)
+	return addVersionFindings(cmd, rep, root, rules)
+}
+
+// addDependencyFindings adds missing dependsOn targets and dependency cycles
+// between task documents.
+func addDependencyFindings(rep *checkReport, root string) {
+	s, _ := taskstore.Open(root)
+	g, err := s.Graph()
 	if err != nil {
-		it.Errors = append(it.Errors, err.Error())
-		return it
-	}
-	s := string(b)
-	// Support files without BOM only; trim leading whitespace to be lenient
-	trimmed := s
-	// but ensure '---' is the very first non-empty line
-	rd := bufio.NewReader(strings.NewReader(s))
-	firstLine, _ := rd.ReadString('\n')
-	firstLine = strings.TrimRight(firstLine, "\r\n")
-	if firstLine != "---" {
-		it.Errors = append(it.Errors, "missing frontmatter start '---' on first line")
-		return it
-	}
-	// find closing '---' on its own line
-	// We'll scan lines until the next '---'
-	lines := strings.Split(trimmed, "\n")
-	endIdx := -1
-	for i := 1; i < len(lines); i++ {
-		if strings.TrimRight(lines[i], "\r") == "---" { // end delimiter
-			endIdx = i
-			break
-		}
-	}
-	if endIdx < 0 {
-		it.Errors = append(it.Errors, "missing frontmatter end '---'")
-		return it
-	}
-	it.HasFrontmatter = true
-	fmLines := lines[1:endIdx]
-	fields := make(map[string]string)
-	keyRe := regexp.MustCompile(`^([A-Za-z0-9_-]+)\s*:\s*(.*)$`)
-	for _, ln := range fmLines {
-		l := strings.TrimSpace(ln)
-		if l == "" || strings.HasPrefix(l, "#") {
+		return
+	}
+	found := g.Findings()
+	for i := range rep.Items {
+		it := &rep.Items[i]
+		for _, f := range found[filepath.ToSlash(relFrom(root, it.Path))] {
+			addFinding(rep, it, f)
+		}
+	}
+}
+
+// addLinkFindings adds broken link and anchor findings to the checked items.
+func addLinkFindings(rep *checkReport, root string) {
+	g, err := specgraph.Build(root)
+	if err != nil {
+		rep.Items = append(rep.Items, checkItem{Path: root, Errors: []string{err.Error()}})
+		rep.Errors++
+		return
+	}
+	for i := range rep.Items {
+		it := &rep.Items[i]
+		for _, f := range g.Findings(filepath.ToSlash(relFrom(root, it.Path))) {
+			addFinding(rep, it, f)
+		}
+	}
+}
+
+// addNumberFindings reports checked documents that share a number prefix.
+func addNumberFindings(rep *checkReport, root string, rules *speccheck.Rules) {
+	rels := make([]string, len(rep.Items))
+	for i, it := range rep.Items {
+		rels[i] = filepath.ToSlash(relFrom(root, it.Path))
+	}
+	dups := speccheck.Duplicates(rules, rels)
+	for i := range rep.Items {
+		it := &rep.Items[i]
+		for _, f := range dups[rels[i]] {
+			addFinding(rep, it, f)
+		}
+	}
+}
+
+// addFinding records f on the checked item it.
+func addFinding(rep *checkReport, it *checkItem, f speccheck.Finding) {
+	it.Findings = append(it.Findings, f)
+	if f.Severity == speccheck.SeverityError {
+		it.Errors = append(it.Errors, f.Message)
+		rep.Errors++
+	} else {
+		it.Warnings = append(it.Warnings, f.Message)
+		rep.Warnings++
+	}
+}
+
+// addVersionFindings compares each spec with its version at --base: version
+// bumps, changelog entries, locked specs and status transitions. Outside
+// a git repository (or before the first commit, with the default base) the
+// check is skipped. A document that cannot be compared gets an error
+// finding and the remaining documents are still checked.
+func addVersionFindings(cmd *cobra.Command, rep *checkReport, root string, rules *speccheck.Rules) error {
+	if _, err := system.GitRoot(cmd.Context(), root); err != nil {
+		return nil
+	}
+	if err := spechistory.Verify(cmd.Context(), root, checkBase); err != nil {
+		if cmd.Flags().Changed("base") {
+			return err
+		}
+		return nil
+	}
+	for i := range rep.Items {
+		it := &rep.Items[i]
+		rel := filepath.ToSlash(relFrom(root, it.Path))
+		b, err := os.ReadFile(it.Path)
+		if err != nil {
 			continue
 		}
-		if m := keyRe.FindStringSubmatch(l); len(m) == 3 {
-			key := strings.ToLower(strings.TrimSpace(m[1]))
-			val := strings.TrimSpace(m[2])
-			// strip surrounding quotes if present
-			if len(val) >= 2 {
-				if (val[0] == '\'' && val[len(val)-1] == '\'') || (val[0] == '"' && val[len(val)-1] == '"') {
-					val = val[1 : len(val)-1]
-				}
+		fs, err := specversion.CheckAgainst(cmd.Context(), root, rel, checkBase, b, rules.For(rel).Versioning)
+		if err != nil {
+			fs = append(fs, compareFailed(speccheck.RuleVersionBump, err))
+		}
+		if strings.HasSuffix(rel, ".spec.mdx") {
+			wfs, err := specstatus.CheckAgainst(cmd.Context(), root, rel, checkBase, b, rules.For(rel).Workflow)
+			if err != nil {
+				wfs = append(wfs, compareFailed(speccheck.RuleTransition, err))
 			}
-			fields[key] = val
+			fs = append(fs, wfs...)
+		}
+		for _, f := range fs {
+			addFinding(rep, it, f)
 		}
 	}
-	it.Fields = fields
-	// minimal required: title must exist and be non-empty
-	if strings.TrimSpace(fields["title"]) == "" {
-		it.Errors = append(it.Errors, "missing required field 'title'")
+	return nil
+}
+
+// compareFailed reports that rule could not be checked against --base.
+func compareFailed(rule string, err error) speccheck.Finding {
+	return speccheck.Finding{
+		Rule:     rule,
+		Severity: speccheck.SeverityError,
+		Message:  fmt.Sprintf("cannot compare with %s: %v", checkBase, err),
+	}
+}
+
+// fixMDX computes the fixes for the document at path. Unless dryRun is set the
+// fixed content is written back; the returned content is the fixed one.
+func fixMDX(rules *speccheck.Rules, root, path string, dryRun bool) ([]byte, []speccheck.Finding, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, fixed := speccheck.Fix(rules, filepath.ToSlash(relFrom(root, path)), b)
+	if len(fixed) == 0 || dryRun {
+		return out, fixed, nil
+	}
+	mode := os.FileMode(0o644)
+	if st, err := os.Stat(path); err == nil {
+		mode = st.Mode().Perm()
+	}
+	if err := os.WriteFile(path, out, mode); err != nil {
+		return nil, nil, err
+	}
+	return out, fixed, nil
+}
+
+// fixDiff renders the planned change of path as a unified diff.
+func fixDiff(root, path string, changed []byte) string {
+	old, _ := os.ReadFile(path)
+	rel := filepath.ToSlash(relFrom(root, path))
+	out, err := udiff.ToUnified("a/"+rel, "b/"+rel, string(old), lineEdits(string(old), string(changed)), udiff.DefaultContextLines)
+	if err != nil {
+		return udiff.Unified("a/"+rel, "b/"+rel, string(old), string(changed))
 	}
-	// heuristics: if filename ends with .spec.mdx, recommend specVersion
-	if strings.HasSuffix(strings.ToLower(filepath.Base(path)), ".spec.mdx") {
-		if strings.TrimSpace(fields["specversion"]) == "" {
-			it.Warnings = append(it.Warnings, "recommended field 'specVersion' is missing")
+	return out
+}
+
+// lineEdits diffs before and after line by line. A character diff of the
+// whole text matches stray characters across lines, which turns a one-line
+// fix into hunks rewriting unrelated lines; comparing whole lines keeps the
+// preview to the lines a fix actually changed.
+func lineEdits(before, after string) []udiff.Edit {
+	a, b := strings.SplitAfter(before, "\n"), strings.SplitAfter(after, "\n")
+	ids := map[string]rune{}
+	seq := func(lines []string) []rune {
+		out := make([]rune, len(lines))
+		for i, l := range lines {
+			id, ok := ids[l]
+			if !ok {
+				id = rune(len(ids))
+				ids[l] = id
+			}
+			out[i] = id
 		}
+		return out
+	}
+	sa, sb := seq(a), seq(b)
+	off := make([]int, len(a)+1)
+	for i, l := range a {
+		off[i+1] = off[i] + len(l)
+	}
+	var edits []udiff.Edit
+	for _, d := range lcs.DiffRunes(sa, sb) {
+		edits = append(edits, udiff.Edit{Start: off[d.Start], End: off[d.End], New: strings.Join(b[d.ReplStart:d.ReplEnd], "")})
+	}
+	return edits
+}
+
+// checkMDX validates the document at path against the repository rules.
+func checkMDX(rules *speccheck.Rules, root, path string) checkItem {
+	it := checkItem{Path: path}
+	var res speccheck.Result
+	if b, err := os.ReadFile(path); err != nil {
+		res = speccheck.ReadError(err)
+	} else {
+		res = speccheck.Check(rules, filepath.ToSlash(relFrom(root, path)), b)
+	}
+	it.HasFrontmatter = res.HasFrontmatter
+	it.Fields = res.Fields
+	it.Findings = res.Findings
+	if errs := res.Errors(); len(errs) > 0 {
+		it.Errors = errs
+	}
+	if warns := res.Warnings(); len(warns) > 0 {
+		it.Warnings = warns
 	}
 	return it
 }
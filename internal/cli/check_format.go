@@ -0,0 +1,332 @@
+package cli
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"codectl/internal/speccheck"
+	appver "codectl/internal/version"
+)
+
+// Output formats accepted by `codectl check --format`.
+const (
+	formatText   = "text"
+	formatJSON   = "json"
+	formatSARIF  = "sarif"
+	formatJUnit  = "junit"
+	formatGitHub = "github"
+)
+
+var checkFormats = []string{formatText, formatJSON, formatSARIF, formatJUnit, formatGitHub}
+
+// itemFindings returns the findings of it, synthesizing read errors for
+// items that only carry messages (e.g. walk failures).
+func itemFindings(it checkItem) []speccheck.Finding {
+	if len(it.Findings) > 0 {
+		return it.Findings
+	}
+	out := make([]speccheck.Finding, 0, len(it.Errors)+len(it.Warnings))
+	for _, e := range it.Errors {
+		out = append(out, speccheck.Finding{Rule: speccheck.RuleRead, Severity: speccheck.SeverityError, Message: e})
+	}
+	for _, w := range it.Warnings {
+		out = append(out, speccheck.Finding{Rule: speccheck.RuleRead, Severity: speccheck.SeverityWarning, Message: w})
+	}
+	return out
+}
+
+// writeCheckReport renders rep in the given format.
+func writeCheckReport(w io.Writer, rep checkReport, format string) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	case formatSARIF:
+		return writeSARIF(w, rep)
+	case formatJUnit:
+		return writeJUnit(w, rep)
+	case formatGitHub:
+		return writeGitHub(w, rep)
+	default:
+		writeText(w, rep)
+		return nil
+	}
+}
+
+func writeText(w io.Writer, rep checkReport) {
+	for _, it := range rep.Items {
+		writeTextItem(w, rep.Root, it)
+	}
+	fmt.Fprintf(w, "\nSummary: %d file(s), %d error(s), %d warning(s)", len(rep.Items), rep.Errors, rep.Warnings)
+	if rep.Fixed > 0 {
+		fmt.Fprintf(w, ", %d fixed", rep.Fixed)
+	}
+	fmt.Fprintln(w)
+}
+
+// writeTextItem writes the result lines of one checked document.
+func writeTextItem(w io.Writer, root string, it checkItem) {
+	if len(it.Fixed) > 0 {
+		msgs := make([]string, 0, len(it.Fixed))
+		for _, f := range it.Fixed {
+			msgs = append(msgs, f.Message)
+		}
+		fmt.Fprintf(w, "FIX  %s  %s\n", relFrom(root, it.Path), strings.Join(msgs, "; "))
+	}
+	switch {
+	case len(it.Errors) > 0:
+		fmt.Fprintf(w, "ERR  %s  %s\n", relFrom(root, it.Path), findingText(it, speccheck.SeverityError))
+	case len(it.Warnings) > 0:
+		fmt.Fprintf(w, "WARN %s  %s\n", relFrom(root, it.Path), findingText(it, speccheck.SeverityWarning))
+	default:
+		fmt.Fprintf(w, "OK   %s\n", relFrom(root, it.Path))
+	}
+}
+
+// findingText joins the findings of one severity as "[rule] message".
+func findingText(it checkItem, sev string) string {
+	parts := make([]string, 0, len(it.Findings))
+	for _, f := range itemFindings(it) {
+		if f.Severity == sev {
+			parts = append(parts, fmt.Sprintf("[%s] %s", f.Rule, f.Message))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ---- SARIF 2.1.0 ----
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLoc `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult               `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version,omitempty"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysical `json:"physicalLocation"`
+}
+
+type sarifPhysical struct {
+	ArtifactLocation sarifArtifactLoc `json:"artifactLocation"`
+	Region           *sarifRegion     `json:"region,omitempty"`
+}
+
+type sarifArtifactLoc struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+func writeSARIF(w io.Writer, rep checkReport) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "codectl", Version: appver.AppVersion, Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+	root := filepath.ToSlash(rep.Root)
+	if !strings.HasSuffix(root, "/") {
+		root += "/"
+	}
+	if !strings.HasPrefix(root, "/") {
+		root = "/" + root // windows drive paths
+	}
+	run.OriginalURIBaseIDs = map[string]sarifArtifactLoc{"%SRCROOT%": {URI: "file://" + root}}
+	seen := map[string]bool{}
+	for _, it := range rep.Items {
+		// escaped as a relative URI reference (spaces, "%", a colon in the
+		// first segment)
+		uri := (&url.URL{Path: filepath.ToSlash(relFrom(rep.Root, it.Path))}).String()
+		for _, f := range itemFindings(it) {
+			if !seen[f.Rule] {
+				seen[f.Rule] = true
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: f.Rule})
+			}
+			loc := sarifPhysical{ArtifactLocation: sarifArtifactLoc{URI: uri, URIBaseID: "%SRCROOT%"}}
+			if f.Line > 0 {
+				loc.Region = &sarifRegion{StartLine: f.Line, StartColumn: f.Column}
+			}
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    f.Rule,
+				Level:     sarifLevel(f.Severity),
+				Message:   sarifMessage{Text: f.Message},
+				Locations: []sarifLocation{{PhysicalLocation: loc}},
+			})
+		}
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool { return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID })
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+func sarifLevel(sev string) string {
+	if sev == speccheck.SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// ---- JUnit XML ----
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit emits one test case per document; errors fail the case and
+// warnings are attached as system-out.
+func writeJUnit(w io.Writer, rep checkReport) error {
+	suite := junitSuite{Name: "codectl check", Cases: []junitCase{}}
+	for _, it := range rep.Items {
+		rel := filepath.ToSlash(relFrom(rep.Root, it.Path))
+		tc := junitCase{Name: rel, Classname: "spec"}
+		var errs, warns []string
+		for _, f := range itemFindings(it) {
+			line := fmt.Sprintf("%s [%s] %s", findingLoc(rel, f), f.Rule, f.Message)
+			if f.Severity == speccheck.SeverityError {
+				errs = append(errs, line)
+			} else {
+				warns = append(warns, line)
+			}
+		}
+		if len(errs) > 0 {
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d error(s)", len(errs)),
+				Type:    "spec",
+				Text:    strings.Join(errs, "\n"),
+			}
+			suite.Failures++
+		}
+		if len(warns) > 0 {
+			tc.SystemOut = strings.Join(warns, "\n")
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+	out := junitSuites{Name: "codectl", Tests: suite.Tests, Failures: suite.Failures, Suites: []junitSuite{suite}}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// findingLoc returns "path[:line[:column]]" for the known parts of the
+// finding's position.
+func findingLoc(rel string, f speccheck.Finding) string {
+	switch {
+	case f.Line > 0 && f.Column > 0:
+		return fmt.Sprintf("%s:%d:%d", rel, f.Line, f.Column)
+	case f.Line > 0:
+		return fmt.Sprintf("%s:%d", rel, f.Line)
+	}
+	return rel
+}
+
+// ---- GitHub Actions workflow commands ----
+
+func writeGitHub(w io.Writer, rep checkReport) error {
+	for _, it := range rep.Items {
+		rel := filepath.ToSlash(relFrom(rep.Root, it.Path))
+		for _, f := range itemFindings(it) {
+			cmd := "error"
+			if f.Severity == speccheck.SeverityWarning {
+				cmd = "warning"
+			}
+			props := "file=" + ghEscapeProp(rel)
+			if f.Line > 0 {
+				props += fmt.Sprintf(",line=%d", f.Line)
+				if f.Column > 0 {
+					props += fmt.Sprintf(",col=%d", f.Column)
+				}
+			}
+			props += ",title=" + ghEscapeProp("codectl check ("+f.Rule+")")
+			if _, err := fmt.Fprintf(w, "::%s %s::%s\n", cmd, props, ghEscapeData(f.Message)); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "Summary: %d file(s), %d error(s), %d warning(s)\n", len(rep.Items), rep.Errors, rep.Warnings)
+	return err
+}
+
+func ghEscapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func ghEscapeProp(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codectl/internal/speccheck"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func TestFixDiff(t *testing.T) {
+	root := t.TempDir()
+	p := filepath.Join(root, "vibe-docs", "spec", "a.spec.mdx")
+	_ = os.MkdirAll(filepath.Dir(p), 0o755)
+	old := "---\ntitle: Login flow\nStatus: Draft\nowner: ann\n---\n# Login flow\n\nSome body text.\n"
+	if err := os.WriteFile(p, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fixed := "---\ntitle: Login flow\nstatus: draft\nowner: ann\nspecVersion: 0.1.0\n---\n# Login flow\n\nSome body text.\n"
+	got := fixDiff(root, p, []byte(fixed))
+
+	checkGolden(t, "fixdiff.golden", got)
+}
+
+// checkGolden compares got with testdata/name, rewriting it with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCheckFormats(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	doc := func(rel string) string { return filepath.Join(root, filepath.FromSlash(rel)) }
+	rep := checkReport{
+		Root: root,
+		Items: []checkItem{
+			{
+				Path:     doc("vibe-docs/spec/100-a.spec.mdx"),
+				Errors:   []string{"x"},
+				Warnings: []string{"y"},
+				Findings: []speccheck.Finding{
+					{Rule: speccheck.RuleEnum, Severity: speccheck.SeverityError, Field: "status", Line: 3, Column: 9,
+						Message: `status: "<done> & more" not in [draft, review]` + "\n100% sure"},
+					{Rule: speccheck.RuleEmptySection, Severity: speccheck.SeverityWarning, Line: 12, Message: "section '背景' is empty"},
+				},
+			},
+			{
+				Path:     doc("vibe-docs/task/a,b:c d.task.mdx"),
+				Warnings: []string{"y"},
+				Findings: []speccheck.Finding{
+					{Rule: speccheck.RuleMissingDependency, Severity: speccheck.SeverityWarning, Message: "no position, 50%: \r\nnext"},
+				},
+			},
+			{Path: doc("vibe-docs/spec/200-ok.spec.mdx")},
+			{Path: doc("vibe-docs/spec/300-broken.spec.mdx"), Errors: []string{"read failed: <permission denied>"}},
+		},
+		Errors:   2,
+		Warnings: 2,
+	}
+	for _, format := range []string{formatSARIF, formatJUnit, formatGitHub} {
+		var b strings.Builder
+		if err := writeCheckReport(&b, rep, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		checkGolden(t, "check."+format+".golden", b.String())
+	}
+}
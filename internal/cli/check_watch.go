@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"codectl/internal/speccheck"
+)
+
+var (
+	checkWatch    bool
+	checkInterval time.Duration
+	checkServe    string
+)
+
+func init() {
+	checkCmd.Flags().BoolVarP(&checkWatch, "watch", "w", false, "keep running and re-check spec/task docs when they change")
+	checkCmd.Flags().DurationVar(&checkInterval, "interval", 500*time.Millisecond, "with --watch, how often files are polled for changes")
+	checkCmd.Flags().StringVar(&checkServe, "serve", "", "with --watch, serve the latest report on this address (GET /api/check, SSE /api/check/events)")
+}
+
+// fileStamp identifies a version of a file for change detection.
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+// checkWatcher re-validates changed documents and keeps the per-document
+// results (without cross-document findings) between rounds.
+type checkWatcher struct {
+	cmd    *cobra.Command
+	root   string
+	rules  *speccheck.Rules
+	rerr   error
+	stamps map[string]fileStamp
+	items  map[string]checkItem
+	report checkReport
+}
+
+func watchCheck(cmd *cobra.Command, root string) error {
+	if checkInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cw := &checkWatcher{cmd: cmd, root: root, items: map[string]checkItem{}}
+	var hub *reportHub
+	if checkServe != "" {
+		hub = &reportHub{subs: map[chan []byte]struct{}{}}
+		ln, err := net.Listen("tcp", checkServe)
+		if err != nil {
+			return err
+		}
+		srv := &http.Server{Handler: hub.handler(), ReadHeaderTimeout: 5 * time.Second}
+		go func() { _ = srv.Serve(ln) }()
+		defer srv.Close()
+		fmt.Fprintf(cmd.ErrOrStderr(), "serving check results on http://%s/api/check\n", ln.Addr())
+	}
+
+	cw.refresh()
+	if err := cw.assemble(); err != nil {
+		return err
+	}
+	writeText(os.Stdout, cw.report)
+	hub.publish(cw.report)
+
+	tick := time.NewTicker(checkInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+		}
+		changed, removed := cw.refresh()
+		if len(changed) == 0 && len(removed) == 0 {
+			continue
+		}
+		prev := cw.report
+		if err := cw.assemble(); err != nil {
+			return err
+		}
+		writeWatchUpdate(os.Stdout, prev, cw.report, removed, time.Now())
+		hub.publish(cw.report)
+	}
+}
+
+// refresh polls the documents and the rules file and re-checks what
+// changed; a changed rules file re-checks every document.
+func (cw *checkWatcher) refresh() (changed, removed []string) {
+	cur := map[string]fileStamp{}
+	rulesPath := filepath.Join(cw.root, filepath.FromSlash(speccheck.RulesFile))
+	if st, err := os.Stat(rulesPath); err == nil {
+		cur[rulesPath] = fileStamp{st.ModTime(), st.Size()}
+	}
+	for _, d := range checkDirs(cw.root) {
+		_ = filepath.WalkDir(d, func(p string, de os.DirEntry, err error) error {
+			if err != nil || de.IsDir() || !isCheckedDoc(de.Name()) {
+				return nil
+			}
+			if info, err := de.Info(); err == nil {
+				cur[p] = fileStamp{info.ModTime(), info.Size()}
+			}
+			return nil
+		})
+	}
+	all := cw.stamps == nil || cur[rulesPath] != cw.stamps[rulesPath]
+	if all {
+		cw.rules, cw.rerr = speccheck.Load(cw.root)
+	}
+	for p, st := range cur {
+		if p == rulesPath {
+			continue
+		}
+		if old, ok := cw.stamps[p]; all || !ok || old != st {
+			cw.items[p] = checkMDX(cw.rules, cw.root, p)
+			changed = append(changed, p)
+		}
+	}
+	for p := range cw.stamps {
+		if _, ok := cur[p]; !ok && p != rulesPath {
+			delete(cw.items, p)
+			removed = append(removed, p)
+		}
+	}
+	if all && cw.stamps != nil {
+		// report the rules file itself when only it changed
+		changed = append(changed, rulesPath)
+	}
+	cw.stamps = cur
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return changed, removed
+}
+
+// assemble builds the full report from the cached per-document results and
+// fresh cross-document findings.
+func (cw *checkWatcher) assemble() error {
+	rep := checkReport{Root: cw.root}
+	for _, d := range checkDirs(cw.root) {
+		if st, err := os.Stat(d); err == nil && st.IsDir() {
+			rep.Dirs = append(rep.Dirs, d)
+		}
+	}
+	if cw.rerr != nil {
+		rep.Items = append(rep.Items, checkItem{
+			Path:     filepath.Join(cw.root, filepath.FromSlash(speccheck.RulesFile)),
+			Errors:   []string{cw.rerr.Error()},
+			Findings: []speccheck.Finding{{Rule: speccheck.RuleRulesFile, Severity: speccheck.SeverityError, Message: cw.rerr.Error()}},
+		})
+	}
+	paths := make([]string, 0, len(cw.items))
+	for p := range cw.items {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		it := cw.items[p]
+		// copy so cross findings do not accumulate in the cache
+		it.Findings = slices.Clone(it.Findings)
+		it.Errors = slices.Clone(it.Errors)
+		it.Warnings = slices.Clone(it.Warnings)
+		rep.Items = append(rep.Items, it)
+	}
+	for _, it := range rep.Items {
+		rep.Errors += len(it.Errors)
+		rep.Warnings += len(it.Warnings)
+	}
+	if err := addCrossFindings(cw.cmd, &rep, cw.root, cw.rules); err != nil {
+		return err
+	}
+	cw.report = rep
+	return nil
+}
+
+// writeWatchUpdate prints the documents whose result changed between prev
+// and cur, the removed ones, and a timestamped summary.
+func writeWatchUpdate(w io.Writer, prev, cur checkReport, removed []string, now time.Time) {
+	before := map[string]string{}
+	for _, it := range prev.Items {
+		var b bytes.Buffer
+		writeTextItem(&b, prev.Root, it)
+		before[it.Path] = b.String()
+	}
+	fmt.Fprintf(w, "\n[%s]\n", now.Format("15:04:05"))
+	for _, it := range cur.Items {
+		var b bytes.Buffer
+		writeTextItem(&b, cur.Root, it)
+		if line, ok := before[it.Path]; !ok || line != b.String() {
+			_, _ = w.Write(b.Bytes())
+		}
+	}
+	for _, p := range removed {
+		fmt.Fprintf(w, "DEL  %s\n", relFrom(cur.Root, p))
+	}
+	fmt.Fprintf(w, "Summary: %d file(s), %d error(s), %d warning(s)\n", len(cur.Items), cur.Errors, cur.Warnings)
+}
+
+// reportHub serves the latest report and pushes updates to SSE clients.
+type reportHub struct {
+	mu     sync.Mutex
+	latest []byte
+	subs   map[chan []byte]struct{}
+}
+
+func (h *reportHub) publish(rep checkReport) {
+	if h == nil {
+		return
+	}
+	b, err := json.Marshal(rep)
+	if err != nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.latest = b
+	for ch := range h.subs {
+		select {
+		case ch <- b:
+		default: // slow client; it gets the next update
+		}
+	}
+}
+
+func (h *reportHub) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/check", func(w http.ResponseWriter, r *http.Request) {
+		h.mu.Lock()
+		b := h.latest
+		h.mu.Unlock()
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(b)
+	})
+	mux.HandleFunc("GET /api/check/events", func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		ch := make(chan []byte, 1)
+		h.mu.Lock()
+		h.subs[ch] = struct{}{}
+		latest := h.latest
+		h.mu.Unlock()
+		defer func() {
+			h.mu.Lock()
+			delete(h.subs, ch)
+			h.mu.Unlock()
+		}()
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		send := func(b []byte) {
+			fmt.Fprintf(w, "event: report\ndata: %s\n\n", b)
+			flusher.Flush()
+		}
+		if latest != nil {
+			send(latest)
+		}
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case b := <-ch:
+				send(b)
+			}
+		}
+	})
+	return mux
+}
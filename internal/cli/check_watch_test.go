@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const watchRules = `{
+  "dirs": {
+    "vibe-docs/task": {
+      "fields": {
+        "status": { "enum": ["todo", "done"] }
+      }
+    }
+  }
+}`
+
+func TestCheckWatcher_Incremental(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	dir := filepath.Join(root, "vibe-docs", "task")
+	_ = os.MkdirAll(dir, 0o755)
+	writeFile := func(p, s string) {
+		t.Helper()
+		if err := os.WriteFile(p, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules := filepath.Join(root, "vibe-docs", ".specrules.json")
+	a := filepath.Join(dir, "a.task.mdx")
+	b := filepath.Join(dir, "b.task.mdx")
+	writeFile(rules, watchRules)
+	writeFile(a, "---\ntitle: A\nstatus: todo\n---\n")
+	writeFile(b, "---\ntitle: B\nstatus: todo\n---\n")
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(t.Context())
+	cw := &checkWatcher{cmd: cmd, root: root, items: map[string]checkItem{}}
+	if changed, _ := cw.refresh(); !slices.Equal(changed, []string{a, b}) {
+		t.Fatalf("first round: changed %v", changed)
+	}
+	if err := cw.assemble(); err != nil {
+		t.Fatal(err)
+	}
+	if cw.report.Errors != 0 || len(cw.report.Items) != 2 {
+		t.Fatalf("first round: %+v", cw.report)
+	}
+	if changed, removed := cw.refresh(); len(changed) != 0 || len(removed) != 0 {
+		t.Fatalf("nothing changed, got %v %v", changed, removed)
+	}
+
+	// only the edited document is re-checked
+	cached := cw.items[a]
+	cached.Warnings = []string{"cached"}
+	cw.items[a] = cached
+	writeFile(b, "---\ntitle: B\nstatus: later\n---\n")
+	changed, removed := cw.refresh()
+	if !slices.Equal(changed, []string{b}) || len(removed) != 0 {
+		t.Fatalf("after edit: changed %v, removed %v", changed, removed)
+	}
+	if !slices.Equal(cw.items[a].Warnings, []string{"cached"}) {
+		t.Fatalf("unchanged document re-checked: %+v", cw.items[a])
+	}
+	cw.items[a] = checkMDX(cw.rules, root, a)
+	prev := cw.report
+	if err := cw.assemble(); err != nil {
+		t.Fatal(err)
+	}
+	if cw.report.Errors != 1 || len(cw.items[b].Errors) != 1 {
+		t.Fatalf("after edit: %+v", cw.report)
+	}
+	var out bytes.Buffer
+	writeWatchUpdate(&out, prev, cw.report, removed, time.Now())
+	if got := out.String(); !strings.Contains(got, "b.task.mdx") || strings.Contains(got, "a.task.mdx") {
+		t.Fatalf("update should list only b:\n%s", got)
+	}
+
+	// a changed rules file re-checks every document
+	writeFile(rules, strings.Replace(watchRules, `"done"`, `"done", "later"`, 1))
+	if changed, _ := cw.refresh(); !slices.Equal(changed, []string{rules, a, b}) {
+		t.Fatalf("after rules change: changed %v", changed)
+	}
+	if err := cw.assemble(); err != nil || cw.report.Errors != 0 {
+		t.Fatalf("after rules change: %v %+v", err, cw.report)
+	}
+
+	if err := os.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	changed, removed = cw.refresh()
+	if len(changed) != 0 || !slices.Equal(removed, []string{a}) {
+		t.Fatalf("after remove: changed %v, removed %v", changed, removed)
+	}
+	if err := cw.assemble(); err != nil || len(cw.report.Items) != 1 {
+		t.Fatalf("after remove: %v %+v", err, cw.report)
+	}
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"codectl/internal/specversion"
+)
+
+var (
+	specBumpNote string
+	specBumpDate string
+)
+
+func init() {
+	specCmd.AddCommand(specBumpCmd)
+	specBumpCmd.Flags().StringVarP(&specBumpNote, "message", "m", "", "changelog note for the new version")
+	specBumpCmd.Flags().StringVar(&specBumpDate, "date", "", "changelog date (YYYY-MM-DD, default today)")
+}
+
+var specBumpCmd = &cobra.Command{
+	Use:   "bump <path> major|minor|patch",
+	Short: "Bump a spec's specVersion and append a dated changelog entry",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := resolveSpecPath(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		date := specBumpDate
+		if date == "" {
+			date = time.Now().Format("2006-01-02")
+		} else if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("invalid --date %q (want YYYY-MM-DD)", date)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		res, err := specversion.BumpDoc(b, args[1], date, specBumpNote)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		st, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, res.Content, st.Mode().Perm()); err != nil {
+			return err
+		}
+		from := res.From
+		if from == "" {
+			from = "(none)"
+		}
+		fmt.Printf("%s: %s -> %s\n", path, from, res.To)
+		return nil
+	},
+}
+
+// resolveSpecPath accepts a path relative to the working directory, or a
+// file name (with or without .spec.mdx) under vibe-docs/spec of the repo.
+func resolveSpecPath(cmd *cobra.Command, p string) (string, error) {
+	if st, err := os.Stat(p); err == nil && !st.IsDir() {
+		return p, nil
+	}
+	root := repoRootOrCwd(cmd)
+	for _, c := range []string{p, p + ".spec.mdx"} {
+		full := filepath.Join(root, "vibe-docs", "spec", c)
+		if st, err := os.Stat(full); err == nil && !st.IsDir() {
+			return full, nil
+		}
+	}
+	return "", fmt.Errorf("spec not found: %s", p)
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"codectl/internal/specsite"
+)
+
+var specExportOut string
+
+func init() {
+	specCmd.AddCommand(specExportCmd)
+	specExportCmd.Flags().StringVar(&specExportOut, "out", "site", "output directory for the static site")
+}
+
+var specExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export spec and task docs to a static HTML site",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := repoRootOrCwd(cmd)
+		res, err := specsite.Export(cmd.Context(), root, specExportOut)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("exported %d document(s), %d file(s) to %s\n", res.Docs, len(res.Files), res.Out)
+		return nil
+	},
+}
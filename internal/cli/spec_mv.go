@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"codectl/internal/specgraph"
+	"codectl/internal/specmove"
+)
+
+var (
+	specMvDryRun bool
+	specMvJSON   bool
+)
+
+func init() {
+	specCmd.AddCommand(specMvCmd)
+	specMvCmd.Flags().BoolVar(&specMvDryRun, "dry-run", false, "print the rename and rewritten references without changing files")
+	specMvCmd.Flags().BoolVar(&specMvJSON, "json", false, "output the plan as JSON")
+}
+
+var specMvCmd = &cobra.Command{
+	Use:   "mv <文档> <新名称>",
+	Short: "Rename a spec or task doc and rewrite every reference to it",
+	Long: "Rename a spec or task document and rewrite the links, spec: references and frontmatter entries across vibe-docs that point at it.\n\n" +
+		"<文档> is a repository-relative path, a spec file name or a unique number prefix. " +
+		"<新名称> is a repository-relative path, a file name in the same directory, or a bare number that replaces the number prefix (e.g. `codectl spec mv 201-tui-spec-ui 203`).",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := repoRootOrCwd(cmd)
+		g, err := specgraph.Build(root)
+		if err != nil {
+			return err
+		}
+		from, err := specmove.Find(g, args[0])
+		if err != nil {
+			return err
+		}
+		to, err := specmove.Target(from, args[1])
+		if err != nil {
+			return err
+		}
+		plan, err := specmove.New(root, from, to)
+		if err != nil {
+			return err
+		}
+		if !specMvDryRun {
+			if err := plan.Apply(); err != nil {
+				return err
+			}
+		}
+		if specMvJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(plan)
+		}
+		fmt.Printf("%s -> %s\n", plan.From, plan.To)
+		for _, e := range plan.Edits {
+			fmt.Printf("  %s:%d: %s -> %s\n", e.Path, e.Line, e.Old, e.New)
+		}
+		if specMvDryRun {
+			fmt.Printf("%d reference(s) would be rewritten\n", len(plan.Edits))
+		} else {
+			fmt.Printf("%d reference(s) rewritten\n", len(plan.Edits))
+		}
+		return nil
+	},
+}
@@ -2,25 +2,48 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"codectl/internal/agent"
+	"codectl/internal/document"
+	"codectl/internal/speccheck"
+	"codectl/internal/spectemplate"
 	"codectl/internal/system"
+	"codectl/internal/taskgen"
+)
+
+var (
+	specNewTemplate string
+	specNewVars     []string
+	specNewAgent    string
+	specNewModel    string
+	specNewTimeout  time.Duration
+	specNewCategory string
 )
 
 func init() {
 	specCmd.AddCommand(specNewCmd)
+	specCmd.AddCommand(specTemplatesCmd)
+	specNewCmd.Flags().StringVarP(&specNewTemplate, "template", "t", "", "named template used as the skeleton (see `codectl spec templates`)")
+	specNewCmd.Flags().StringArrayVar(&specNewVars, "var", nil, "template variable as key=value (repeatable)")
+	specNewCmd.Flags().StringVar(&specNewAgent, "agent", "codex", "coding agent used to draft the spec: codex|claude|gemini")
+	specNewCmd.Flags().StringVar(&specNewModel, "model", "", "model passed through to the agent")
+	specNewCmd.Flags().StringVarP(&specNewCategory, "category", "c", "", "numbering category the spec number is taken from (see numbering in vibe-docs/.specrules.json)")
+	specNewCmd.Flags().DurationVar(&specNewTimeout, "timeout", 10*time.Minute, "abort the agent after this long")
 }
 
 var specNewCmd = &cobra.Command{
 	Use:   "new <说明>",
-	Short: "Generate a spec draft via Codex and save to vibe-docs/spec",
+	Short: "Generate a spec draft via a coding agent and save to vibe-docs/spec",
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		prompt := strings.TrimSpace(strings.Join(args, " "))
@@ -28,18 +51,6 @@ var specNewCmd = &cobra.Command{
 			return fmt.Errorf("用法：codectl spec new \"<说明>\"")
 		}
 
-		// find codex binary
-		bin := ""
-		for _, cand := range []string{"codex", "openai-codex"} {
-			if p, err := exec.LookPath(cand); err == nil && p != "" {
-				bin = p
-				break
-			}
-		}
-		if bin == "" {
-			return fmt.Errorf("未找到 codex CLI（请先安装 @openai/codex 并确保在 PATH 中）")
-		}
-
 		// resolve repo root
 		cwd, _ := os.Getwd()
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -48,15 +59,67 @@ var specNewCmd = &cobra.Command{
 		if err != nil || strings.TrimSpace(root) == "" {
 			root = cwd
 		}
+
+		// render the template skeleton first so template errors surface
+		// before the agent runs
+		var tpl spectemplate.Template
+		skeleton := ""
+		if specNewTemplate != "" {
+			if tpl, err = spectemplate.Find(root, specNewTemplate); err != nil {
+				return err
+			}
+			vars, err := specNewTemplateVars(root, prompt)
+			if err != nil {
+				return err
+			}
+			if skeleton, err = tpl.Render(vars); err != nil {
+				return err
+			}
+		}
+
+		id, err := agent.Parse(specNewAgent)
+		if err != nil {
+			return err
+		}
+		if _, err := agent.Find(id); err != nil {
+			return err
+		}
+
 		outDir := filepath.Join(root, "vibe-docs", "spec")
+		// fail on an unknown category before the agent runs
+		if _, err := specNewFileName(root, outDir, prompt); err != nil {
+			return err
+		}
 		if err := os.MkdirAll(outDir, 0o755); err != nil {
 			return fmt.Errorf("创建目录失败：%w", err)
 		}
 
-		// run codex exec (argument, then stdin fallback)
-		body, runErr := runCodexOnce(bin, prompt, 120*time.Second)
-		if runErr != nil && body == "" {
-			return fmt.Errorf("codex exec 失败：%w", runErr)
+		agentPrompt := prompt
+		if skeleton != "" {
+			agentPrompt = "请根据以下说明撰写规范（Spec）。严格使用给定骨架：保留 frontmatter、全部二级标题及其顺序，填充各节内容，只输出完整的 MDX 文档。\n\n" +
+				"说明：" + prompt + "\n\n骨架：\n" + skeleton
+		}
+		// agent progress streams to stderr; only the final answer becomes
+		// the document. Ctrl+C interrupts the agent and writes nothing.
+		runCtx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if specNewTimeout > 0 {
+			var cancel context.CancelFunc
+			runCtx, cancel = context.WithTimeout(runCtx, specNewTimeout)
+			defer cancel()
+		}
+		res, runErr := agent.Run(runCtx, id, agentPrompt, agent.Options{Model: specNewModel, Progress: cmd.ErrOrStderr()})
+		switch {
+		case errors.Is(runErr, context.Canceled):
+			return fmt.Errorf("已取消")
+		case errors.Is(runErr, context.DeadlineExceeded):
+			return fmt.Errorf("%s 超时（%s）", specNewAgent, specNewTimeout)
+		case runErr != nil:
+			return fmt.Errorf("%s 执行失败：%w", specNewAgent, runErr)
+		}
+		body := agent.StripFence(res.Output)
+		if strings.TrimSpace(body) == "" {
+			return fmt.Errorf("%s 未返回文档内容", specNewAgent)
 		}
 
 		content := body
@@ -69,88 +132,110 @@ var specNewCmd = &cobra.Command{
 				"status: draft\n" +
 				"lastUpdated: {auto}\n" +
 				"---\n\n"
+			if skeleton != "" {
+				if doc, err := document.Parse([]byte(skeleton)); err == nil && doc.HasFrontmatter {
+					doc.Body = "\n"
+					fm = string(doc.Bytes())
+				}
+			}
 			content = fm + body
 		}
 
-		ts := time.Now().Format("060102-150405")
-		name := fmt.Sprintf("draft-%s-%s.spec.mdx", ts, slugifyCLI(prompt))
+		name, err := specNewFileName(root, outDir, prompt)
+		if err != nil {
+			return err
+		}
 		outPath := filepath.Join(outDir, name)
 		if err := os.WriteFile(outPath, []byte(content), 0o644); err != nil {
 			return fmt.Errorf("写入失败：%w", err)
 		}
-		// optionally persist raw output when non-fatal error returned
-		if runErr != nil {
-			_ = os.WriteFile(outPath+".raw.txt", []byte(body), 0o644)
-		}
 		fmt.Println(outPath)
+		if skeleton != "" {
+			missing := tpl.Validate([]byte(content))
+			for _, f := range missing {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", outPath, f.Message)
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf("generated spec misses %d section(s) required by template %s", len(missing), tpl.Name)
+			}
+		}
 		return nil
 	},
 }
 
-// runCodexOnce mirrors the TUI fallback: try arg then stdin.
-func runCodexOnce(bin, prompt string, timeout time.Duration) (string, error) {
-	// try with argument
-	{
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		cmd := exec.CommandContext(ctx, bin, "exec", prompt)
-		cmd.Env = append(os.Environ(), "NO_COLOR=1")
-		out, err := cmd.CombinedOutput()
-		cancel()
-		if err == nil && len(out) > 0 {
-			return string(out), nil
-		}
-		if ctx.Err() == context.DeadlineExceeded {
-			return "", ctx.Err()
-		}
-		if len(out) > 0 {
-			return string(out), err
-		}
+// specNewFileName names a new spec after the next free number of
+// --category when the rules configure numbering for vibe-docs/spec, and
+// after the current time otherwise.
+func specNewFileName(root, outDir, prompt string) (string, error) {
+	rules, err := speccheck.Load(root)
+	if err != nil {
+		return "", err
 	}
-	// fallback via stdin
-	{
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		cmd := exec.CommandContext(ctx, bin, "exec")
-		cmd.Env = append(os.Environ(), "NO_COLOR=1")
-		cmd.Stdin = strings.NewReader(prompt)
-		out, err := cmd.CombinedOutput()
-		cancel()
-		if err == nil && len(out) > 0 {
-			return string(out), nil
+	num := rules.For("vibe-docs/spec/new.spec.mdx").Numbering
+	if !num.Enabled() {
+		if specNewCategory != "" {
+			return "", fmt.Errorf("--category needs numbering configured in %s", speccheck.RulesFile)
 		}
-		if len(out) > 0 {
-			return string(out), err
-		}
-		if err != nil {
-			return "", err
+		ts := time.Now().Format("060102-150405")
+		return fmt.Sprintf("draft-%s-%s.spec.mdx", ts, taskgen.Slug(prompt, "spec")), nil
+	}
+	var used []int
+	entries, _ := os.ReadDir(outDir)
+	for _, de := range entries {
+		if n, _, ok := speccheck.FileNumber(de.Name()); ok {
+			used = append(used, n)
 		}
-		return string(out), nil
 	}
+	n, err := num.Next(used, specNewCategory)
+	if err != nil {
+		return "", err
+	}
+	return num.Format(n) + "-" + taskgen.Slug(prompt, "spec") + ".spec.mdx", nil
 }
 
-// slugifyCLI is a local copy (no export) of slugify logic for filenames.
-func slugifyCLI(s string) string {
-	s = strings.ToLower(strings.TrimSpace(s))
-	b := make([]rune, 0, len(s))
-	lastDash := false
-	for _, r := range s {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
-			b = append(b, r)
-			lastDash = false
-			continue
-		}
-		if r >= 0x4E00 && r <= 0x9FFF { // keep CJK
-			b = append(b, r)
-			lastDash = false
-			continue
-		}
-		if !lastDash {
-			b = append(b, '-')
-			lastDash = true
-		}
+// specNewTemplateVars returns the built-in template variables (title,
+// description, date, author, slug) overridden by --var flags.
+func specNewTemplateVars(root, prompt string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	author, _ := system.GitUser(ctx, root)
+	cancel()
+	vars := map[string]string{
+		"title":       prompt,
+		"description": prompt,
+		"date":        time.Now().Format("2006-01-02"),
+		"author":      author,
+		"slug":        taskgen.Slug(prompt, "spec"),
 	}
-	res := strings.Trim(string(b), "-")
-	if res == "" {
-		res = "spec"
+	for _, kv := range specNewVars {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid --var %q (want key=value)", kv)
+		}
+		vars[strings.TrimSpace(k)] = v
 	}
-	return res
+	return vars, nil
+}
+
+var specTemplatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "List spec templates available to `spec new --template`",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ts, err := spectemplate.List(repoRootOrCwd(cmd))
+		if err != nil {
+			return err
+		}
+		for _, t := range ts {
+			where := t.Source
+			if t.Path != "" {
+				where = t.Path
+			}
+			fmt.Printf("%-12s %-8s %s\n", t.Name, t.Source, t.Description)
+			if t.Source != spectemplate.SourceBuiltin {
+				fmt.Printf("%-12s %-8s %s\n", "", "", where)
+			}
+			fmt.Printf("%-12s sections: %s\n", "", strings.Join(t.Sections(), " / "))
+		}
+		return nil
+	},
 }
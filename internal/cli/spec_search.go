@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"codectl/internal/docsearch"
+)
+
+var (
+	specSearchKind  string
+	specSearchLimit int
+	specSearchJSON  bool
+)
+
+func init() {
+	specCmd.AddCommand(specSearchCmd)
+	specSearchCmd.Flags().StringVar(&specSearchKind, "kind", "", "only search spec or task documents")
+	specSearchCmd.Flags().IntVarP(&specSearchLimit, "limit", "n", 10, "maximum number of results")
+	specSearchCmd.Flags().BoolVar(&specSearchJSON, "json", false, "output JSON")
+}
+
+var specSearchCmd = &cobra.Command{
+	Use:   "search <查询>",
+	Short: "Full-text search over specs and tasks, ranked by relevance",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch specSearchKind {
+		case "", "spec", "task":
+		default:
+			return fmt.Errorf("--kind must be spec or task")
+		}
+		ix, err := docsearch.Build(repoRootOrCwd(cmd))
+		if err != nil {
+			return err
+		}
+		res := ix.Search(strings.Join(args, " "), docsearch.Options{Kind: specSearchKind, Limit: specSearchLimit})
+		if specSearchJSON {
+			return writeJSONOut(res)
+		}
+		writeSearchResults(os.Stdout, res, colorOutput())
+		return nil
+	},
+}
+
+// colorOutput reports whether stdout is a terminal that accepts ANSI
+// styling.
+func colorOutput() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	st, err := os.Stdout.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+func writeSearchResults(w io.Writer, res []docsearch.Result, color bool) {
+	if len(res) == 0 {
+		fmt.Fprintln(w, "no matches")
+		return
+	}
+	open, end := "[", "]"
+	if color {
+		open, end = "\x1b[1;33m", "\x1b[0m"
+	}
+	for _, r := range res {
+		loc := r.Path
+		if r.Anchor != "" {
+			loc += "#" + r.Anchor
+		}
+		fmt.Fprintf(w, "%s:%d  %s  (%.2f)\n", loc, r.Line, r.Title, r.Score)
+		if r.Heading != "" {
+			fmt.Fprintf(w, "  § %s\n", r.Heading)
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range r.Matches {
+			b.WriteString(r.Snippet[last:m[0]])
+			b.WriteString(open + r.Snippet[m[0]:m[1]] + end)
+			last = m[1]
+		}
+		b.WriteString(r.Snippet[last:])
+		fmt.Fprintf(w, "  %s\n\n", b.String())
+	}
+}
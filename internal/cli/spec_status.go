@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"codectl/internal/speccheck"
+	"codectl/internal/specstatus"
+	"codectl/internal/system"
+)
+
+var (
+	specStatusBy        string
+	specStatusReviewers []string
+	specStatusJSON      bool
+)
+
+func init() {
+	specCmd.AddCommand(specStatusCmd)
+	specStatusCmd.Flags().StringVar(&specStatusBy, "by", "", "approver recorded when entering a locked state (default: git user.name)")
+	specStatusCmd.Flags().StringArrayVar(&specStatusReviewers, "reviewer", nil, "set the reviewers list (repeatable)")
+	specStatusCmd.Flags().BoolVar(&specStatusJSON, "json", false, "output JSON")
+}
+
+var specStatusCmd = &cobra.Command{
+	Use:   "status [文档] [新状态]",
+	Short: "Show review status of specs, or move a spec through the review workflow",
+	Long: "Without arguments, list every spec with its status and approvals. With a document, show its status and the states it may move to. " +
+		"With a new state, apply the transition: entering a locked state (accepted by default) records an approval, and reopening drops it.",
+	Args: cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := repoRootOrCwd(cmd)
+		rules, err := speccheck.Load(root)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			list, err := specstatus.List(root, rules)
+			if err != nil {
+				return err
+			}
+			if specStatusJSON {
+				return writeJSONOut(list)
+			}
+			writeSpecStatus(os.Stdout, list)
+			return nil
+		}
+		path, err := resolveSpecPath(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		rel := filepath.ToSlash(relFrom(root, absOr(path)))
+		wf := rules.For(rel).Workflow
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			in, err := specstatus.Read(wf, b)
+			if err != nil {
+				return fmt.Errorf("%s: %w", rel, err)
+			}
+			in.Path = rel
+			if specStatusJSON {
+				return writeJSONOut(in)
+			}
+			writeSpecStatus(os.Stdout, []specstatus.Info{in})
+			fmt.Printf("next: %s\n", strings.Join(in.Next, ", "))
+			return nil
+		}
+		by := specStatusBy
+		if by == "" {
+			ctx, cancel := context.WithTimeout(cmd.Context(), 2*time.Second)
+			by, _ = system.GitUser(ctx, root)
+			cancel()
+		}
+		out, in, err := specstatus.Transition(wf, b, specstatus.Request{To: args[1], By: by, Reviewers: specStatusReviewers, Now: time.Now()})
+		if err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+		st, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, out, st.Mode().Perm()); err != nil {
+			return err
+		}
+		in.Path = rel
+		if specStatusJSON {
+			return writeJSONOut(in)
+		}
+		fmt.Printf("%s: %s\n", rel, in.Status)
+		return nil
+	},
+}
+
+func writeJSONOut(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func absOr(p string) string {
+	if a, err := filepath.Abs(p); err == nil {
+		return a
+	}
+	return p
+}
+
+// writeSpecStatus prints one row per spec: path, status, lock and approvals.
+func writeSpecStatus(w io.Writer, list []specstatus.Info) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tSTATUS\tREVIEWERS\tAPPROVALS")
+	for _, in := range list {
+		status := in.Status
+		if status == "" {
+			status = "-"
+		}
+		if in.Locked {
+			status += " (locked)"
+		}
+		var approvals []string
+		for _, a := range in.Approvals {
+			at := a.At
+			if t, err := time.Parse(time.RFC3339, a.At); err == nil {
+				at = t.Format("2006-01-02")
+			}
+			approvals = append(approvals, a.By+"@"+at)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", in.Path, status, orDash(strings.Join(in.Reviewers, ", ")), orDash(strings.Join(approvals, ", ")))
+	}
+	tw.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,277 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"codectl/internal/agent"
+	"codectl/internal/history"
+	"codectl/internal/system"
+	"codectl/internal/taskgen"
+	"codectl/internal/taskstore"
+)
+
+var (
+	taskFromSpec  string
+	taskDryRun    bool
+	taskAgent     string
+	taskModel     string
+	taskTimeout   time.Duration
+	taskTemplate  string
+	taskOwner     string
+	taskPriority  string
+	taskStatus    string
+	taskDependsOn []string
+	taskSets      []string
+	taskEdit      bool
+	taskJSON      bool
+)
+
+func init() {
+	rootCmd.AddCommand(taskCmd)
+	taskCmd.AddCommand(taskNewCmd, taskEditCmd)
+	f := taskNewCmd.Flags()
+	f.StringVar(&taskFromSpec, "from-spec", "", "break a spec down into tasks via a coding agent (path or name under vibe-docs/spec)")
+	f.BoolVar(&taskDryRun, "dry-run", false, "with --from-spec, print the generated tasks without writing them")
+	f.StringVar(&taskAgent, "agent", "codex", "with --from-spec, coding agent used: codex|claude|gemini")
+	f.StringVar(&taskModel, "model", "", "with --from-spec, model passed through to the agent")
+	f.DurationVar(&taskTimeout, "timeout", 5*time.Minute, "with --from-spec, abort the agent after this long")
+	f.StringVarP(&taskTemplate, "template", "t", "", "render a spec template instead of the standard task skeleton (see `spec templates`)")
+	f.StringVar(&taskOwner, "owner", "", "task owner (default: git user.name; pass an empty value for none)")
+	f.StringVarP(&taskPriority, "priority", "p", "", "task priority (e.g. P1)")
+	f.StringVar(&taskStatus, "status", "", "initial status (default todo)")
+	f.StringSliceVar(&taskDependsOn, "depends-on", nil, "tasks this one is blocked by (repeatable or comma-separated)")
+	f.StringArrayVar(&taskSets, "set", nil, "set a frontmatter field key=value (repeatable)")
+	f.BoolVarP(&taskEdit, "edit", "e", false, "open the new task in $EDITOR")
+	f.BoolVar(&taskJSON, "json", false, "print the created task as JSON")
+}
+
+var taskCmd = &cobra.Command{
+	Use:   "task",
+	Short: "Manage task documents in vibe-docs/task",
+}
+
+var taskNewCmd = &cobra.Command{
+	Use:   "new [标题]",
+	Short: "Create a task from the template, or tasks from a spec with --from-spec",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := repoRootOrCwd(cmd)
+		now := time.Now()
+		if taskFromSpec == "" {
+			title := ""
+			if len(args) > 0 {
+				title = args[0]
+			}
+			return newTask(cmd, root, title, now)
+		}
+		if len(args) > 0 {
+			return fmt.Errorf("--from-spec does not take a title")
+		}
+		specPath, err := resolveSpecPath(cmd, taskFromSpec)
+		if err != nil {
+			return err
+		}
+		abs, err := filepath.Abs(specPath)
+		if err != nil {
+			return err
+		}
+		id, err := agent.Parse(taskAgent)
+		if err != nil {
+			return err
+		}
+		if _, err := agent.Find(id); err != nil {
+			return err
+		}
+		// agent progress streams to stderr; only the final answer is parsed.
+		// Ctrl+C interrupts the agent and writes nothing.
+		runCtx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if taskTimeout > 0 {
+			var cancel context.CancelFunc
+			runCtx, cancel = context.WithTimeout(runCtx, taskTimeout)
+			defer cancel()
+		}
+		run := func(ctx context.Context, prompt string) (string, error) {
+			res, err := agent.Run(ctx, id, prompt, agent.Options{Model: taskModel, Progress: cmd.ErrOrStderr()})
+			return res.Output, err
+		}
+		files, err := taskgen.Generate(runCtx, run, root, filepath.ToSlash(relFrom(root, abs)), now)
+		if err == nil {
+			err = runCtx.Err()
+		}
+		switch {
+		case errors.Is(err, context.Canceled):
+			return fmt.Errorf("已取消")
+		case errors.Is(err, context.DeadlineExceeded):
+			return fmt.Errorf("%s 超时（%s）", taskAgent, taskTimeout)
+		case err != nil:
+			return fmt.Errorf("%s 执行失败：%w", taskAgent, err)
+		}
+		if taskDryRun {
+			for _, f := range files {
+				fmt.Printf("==> %s\n%s\n", f.Path, f.Content)
+			}
+			return nil
+		}
+		if err := taskgen.Write(root, files); err != nil {
+			return fmt.Errorf("写入失败：%w", err)
+		}
+		for _, f := range files {
+			fmt.Println(filepath.Join(root, filepath.FromSlash(f.Path)))
+		}
+		return nil
+	},
+}
+
+// newTask creates one task from the flags of task new and prints its path.
+func newTask(cmd *cobra.Command, root, title string, now time.Time) error {
+	s, err := taskstore.Open(root)
+	if err != nil {
+		return err
+	}
+	fields, err := parseFieldArgs(taskSets)
+	if err != nil {
+		return err
+	}
+	owner := taskOwner
+	if !cmd.Flags().Changed("owner") {
+		owner = gitUserName(cmd, root)
+	}
+	for k, v := range map[string]string{"owner": owner, "priority": taskPriority, "status": taskStatus} {
+		if v != "" {
+			fields[k] = v
+		}
+	}
+	if len(taskDependsOn) > 0 {
+		fields[taskstore.DependsField] = strings.Join(taskDependsOn, ",")
+	}
+	if err := resolveDependsOn(s, fields); err != nil {
+		return err
+	}
+	t, err := s.Create(taskstore.Create{Title: title, Template: taskTemplate, Fields: fields, Now: now})
+	if err != nil {
+		return fmt.Errorf("写入失败：%w", err)
+	}
+	full := filepath.Join(s.Dir(), filepath.FromSlash(t.Path))
+	if taskEdit {
+		if err := editFile(cmd.Context(), full); err != nil {
+			return err
+		}
+		if t, _, err = s.Get(t.Path); err != nil {
+			return err
+		}
+	}
+	if taskJSON {
+		return writeJSONOut(t)
+	}
+	fmt.Println(full)
+	return nil
+}
+
+var taskEditCmd = &cobra.Command{
+	Use:   "edit <任务>",
+	Short: "Open a task in $EDITOR",
+	Long:  "Open a task in $VISUAL or $EDITOR (default vi). The previous content is kept in the local history. " + taskArgHelp,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, t, err := findTask(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := history.Snapshot(s.Dir(), t.Path, history.OpWrite); err != nil {
+			return err
+		}
+		return editFile(cmd.Context(), filepath.Join(s.Dir(), filepath.FromSlash(t.Path)))
+	},
+}
+
+// taskArgHelp describes how task arguments are resolved.
+const taskArgHelp = "A task is named by its path (relative to vibe-docs/task, the repository or the current directory), " +
+	"with or without .task.mdx, or by a unique part of its file name."
+
+// findTask opens the task store of the current repository and resolves name.
+func findTask(cmd *cobra.Command, name string) (*taskstore.Store, taskstore.Task, error) {
+	s, err := taskstore.Open(repoRootOrCwd(cmd))
+	if err != nil {
+		return nil, taskstore.Task{}, err
+	}
+	if _, err := os.Stat(name); err == nil {
+		name = absOr(name)
+	}
+	t, err := s.Find(name)
+	return s, t, err
+}
+
+// parseFieldArgs parses key=value arguments; an empty value removes the
+// field.
+func parseFieldArgs(args []string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, kv := range args {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid field %q (want key=value)", kv)
+		}
+		out[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return out, nil
+}
+
+// resolveDependsOn replaces the task names in the dependsOn field of
+// fields with their paths, so that any name task commands accept works.
+func resolveDependsOn(s *taskstore.Store, fields map[string]string) error {
+	v, ok := fields[taskstore.DependsField]
+	if !ok {
+		return nil
+	}
+	var paths []string
+	for _, name := range strings.Split(v, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		t, err := s.Find(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", taskstore.DependsField, err)
+		}
+		paths = append(paths, t.Path)
+	}
+	fields[taskstore.DependsField] = strings.Join(paths, ",")
+	return nil
+}
+
+// gitUserName returns the git user.name of the repository at root, empty
+// when unset.
+func gitUserName(cmd *cobra.Command, root string) string {
+	ctx, cancel := context.WithTimeout(cmd.Context(), 2*time.Second)
+	defer cancel()
+	name, _ := system.GitUser(ctx, root)
+	return name
+}
+
+// editFile opens path in $VISUAL, $EDITOR or vi, attached to the terminal.
+func editFile(ctx context.Context, path string) error {
+	ed := os.Getenv("VISUAL")
+	if ed == "" {
+		ed = os.Getenv("EDITOR")
+	}
+	if ed == "" {
+		ed = "vi"
+	}
+	argv := strings.Fields(ed)
+	c := exec.CommandContext(ctx, argv[0], append(argv[1:], path)...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("editor %s: %w", argv[0], err)
+	}
+	return nil
+}
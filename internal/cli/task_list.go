@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"codectl/internal/document"
+	"codectl/internal/taskgen"
+	"codectl/internal/taskstore"
+)
+
+var (
+	taskListFilter taskstore.Filter
+	taskListMine   bool
+	taskListJSON   bool
+	taskShowJSON   bool
+)
+
+func init() {
+	taskCmd.AddCommand(taskListCmd, taskShowCmd)
+	f := taskListCmd.Flags()
+	f.StringVar(&taskListFilter.Status, "status", "", "only tasks with this status")
+	f.StringVar(&taskListFilter.Owner, "owner", "", "only tasks of this owner")
+	f.StringVarP(&taskListFilter.Priority, "priority", "p", "", "only tasks with this priority")
+	f.StringVarP(&taskListFilter.Query, "query", "q", "", "only tasks whose title or path contains this text")
+	f.BoolVarP(&taskListFilter.Archived, "archived", "a", false, "include archived tasks")
+	f.BoolVar(&taskListMine, "mine", false, "only tasks owned by the git user.name")
+	f.BoolVar(&taskListJSON, "json", false, "output JSON")
+	taskShowCmd.Flags().BoolVar(&taskShowJSON, "json", false, "output JSON")
+}
+
+var taskListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List tasks with optional filters",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := repoRootOrCwd(cmd)
+		s, err := taskstore.Open(root)
+		if err != nil {
+			return err
+		}
+		f := taskListFilter
+		if taskListMine {
+			if f.Owner = gitUserName(cmd, root); f.Owner == "" {
+				return fmt.Errorf("--mine: git user.name is not set")
+			}
+		}
+		ts, err := s.List(f)
+		if err != nil {
+			return err
+		}
+		if taskListJSON {
+			return writeJSONOut(ts)
+		}
+		writeTaskTable(os.Stdout, ts)
+		return nil
+	},
+}
+
+// writeTaskTable prints one row per task.
+func writeTaskTable(w io.Writer, ts []taskstore.Task) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tSTATUS\tPRIORITY\tOWNER\tDUE\tTITLE")
+	for _, t := range ts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", t.Path, orDash(t.Status), orDash(t.Priority), orDash(t.Owner), orDash(t.Due), t.Title)
+	}
+	tw.Flush()
+}
+
+// taskDetail is the output of task show.
+type taskDetail struct {
+	taskstore.Task
+	File string `json:"file"` // repository-relative
+	// Blocked reports unfinished or missing dependencies.
+	Blocked bool `json:"blocked"`
+	// Blocks lists the tasks that depend on this one.
+	Blocks []string `json:"blocks,omitempty"`
+	Body   string   `json:"body"`
+}
+
+var taskShowCmd = &cobra.Command{
+	Use:   "show <任务>",
+	Short: "Show a task's fields, dependencies and body",
+	Long:  "Show a task's fields, dependencies and body. " + taskArgHelp,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, t, err := findTask(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		_, b, err := s.Get(t.Path)
+		if err != nil {
+			return err
+		}
+		d := taskDetail{Task: t, File: path.Join(taskgen.Dir, t.Path), Body: string(b)}
+		if doc, err := document.Parse(b); err == nil {
+			d.Body = doc.Body
+		}
+		g, err := s.Graph()
+		if err != nil {
+			return err
+		}
+		n, _ := g.Node(t.Path)
+		d.Blocked, d.Blocks = n.Blocked, g.Dependents(t.Path)
+		if taskShowJSON {
+			return writeJSONOut(d)
+		}
+		writeTaskDetail(os.Stdout, d, g)
+		return nil
+	},
+}
+
+// writeTaskDetail prints the fields of a task, its dependencies with their
+// status and its body.
+func writeTaskDetail(w io.Writer, d taskDetail, g *taskstore.Graph) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	row := func(k, v string) { fmt.Fprintf(tw, "%s:\t%s\n", k, v) }
+	row("file", d.File)
+	row("title", d.Title)
+	row("status", orDash(d.Status))
+	row("priority", orDash(d.Priority))
+	row("owner", orDash(d.Owner))
+	row("due", orDash(d.Due))
+	if n, ok := g.Node(d.Path); ok && len(d.DependsOn) > 0 {
+		deps := make([]string, 0, len(n.DependsOn))
+		for _, p := range n.DependsOn {
+			dn, _ := g.Node(p)
+			deps = append(deps, fmt.Sprintf("%s (%s)", p, orDash(dn.Status)))
+		}
+		for _, m := range g.Missing {
+			if m.Path == d.Path {
+				deps = append(deps, m.Ref+" (missing)")
+			}
+		}
+		state := "ready"
+		if d.Blocked {
+			state = "blocked"
+		}
+		row("depends on", strings.Join(deps, ", ")+" — "+state)
+	}
+	if len(d.Blocks) > 0 {
+		row("blocks", strings.Join(d.Blocks, ", "))
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%s", strings.TrimLeft(d.Body, "\n"))
+}
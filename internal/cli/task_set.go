@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"codectl/internal/taskstore"
+)
+
+var (
+	taskSetJSON   bool
+	taskRmArchive bool
+)
+
+func init() {
+	taskCmd.AddCommand(taskSetCmd, taskDoneCmd, taskRmCmd)
+	taskSetCmd.Flags().BoolVar(&taskSetJSON, "json", false, "print the updated task as JSON")
+	taskRmCmd.Flags().BoolVar(&taskRmArchive, "archive", false, "move the tasks to vibe-docs/task/archive instead of deleting them")
+}
+
+var taskSetCmd = &cobra.Command{
+	Use:   "set <任务> <key=value>...",
+	Short: "Change frontmatter fields of a task",
+	Long: "Change frontmatter fields of a task without touching its body. An empty value (key=) removes the field; " +
+		"dependsOn takes a comma-separated list. Values of fields with an enum in .specrules.json are validated. " + taskArgHelp,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fields, err := parseFieldArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		s, t, err := findTask(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		if err := resolveDependsOn(s, fields); err != nil {
+			return err
+		}
+		n, err := s.Set(t.Path, fields)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Path, err)
+		}
+		if taskSetJSON {
+			return writeJSONOut(n)
+		}
+		fmt.Printf("%s: updated %s\n", n.Path, strings.Join(sortedKeys(fields), ", "))
+		return printUnblocked(s, t, n)
+	},
+}
+
+var taskDoneCmd = &cobra.Command{
+	Use:   "done <任务>...",
+	Short: "Mark tasks as done and list the tasks they unblocked",
+	Long:  "Set status: done on each task and list the tasks whose dependencies are now all closed. " + taskArgHelp,
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, a := range args {
+			s, t, err := findTask(cmd, a)
+			if err != nil {
+				return err
+			}
+			n, err := s.Set(t.Path, map[string]string{"status": "done"})
+			if err != nil {
+				return fmt.Errorf("%s: %w", t.Path, err)
+			}
+			fmt.Printf("%s: %s\n", n.Path, n.Status)
+			if err := printUnblocked(s, t, n); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+var taskRmCmd = &cobra.Command{
+	Use:   "rm <任务>...",
+	Short: "Delete or archive tasks",
+	Long: "Delete tasks (the content is kept in the local history), or with --archive move them to vibe-docs/task/archive " +
+		"and rewrite links to them. " + taskArgHelp,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, a := range args {
+			s, t, err := findTask(cmd, a)
+			if err != nil {
+				return err
+			}
+			if taskRmArchive {
+				n, err := s.Archive(t.Path)
+				if err != nil {
+					return fmt.Errorf("%s: %w", t.Path, err)
+				}
+				fmt.Printf("archived %s -> %s\n", t.Path, n.Path)
+				continue
+			}
+			if err := s.Delete(t.Path); err != nil {
+				return fmt.Errorf("%s: %w", t.Path, err)
+			}
+			fmt.Printf("removed %s\n", t.Path)
+		}
+		return nil
+	},
+}
+
+// printUnblocked lists the tasks unblocked by an edit that closed a task.
+func printUnblocked(s *taskstore.Store, before, after taskstore.Task) error {
+	if s.Closed(before.Status) || !s.Closed(after.Status) {
+		return nil
+	}
+	ps, err := s.Released([]string{after.Path})
+	if err != nil {
+		return err
+	}
+	for _, p := range ps {
+		fmt.Printf("unblocked: %s\n", p)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"codectl/internal/taskstore"
+)
+
+const taskRules = `{
+  "dirs": {
+    "vibe-docs/task": {
+      "fields": {
+        "status": { "enum": ["todo", "in-progress", "done"] },
+        "priority": { "enum": ["P0", "P1", "P2"] }
+      }
+    }
+  }
+}`
+
+// taskRepo chdirs into a fresh repository with task rules and HOME in it,
+// and returns its task directory.
+func taskRepo(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Chdir(root)
+	dir := filepath.Join(root, "vibe-docs", "task")
+	_ = os.MkdirAll(dir, 0o755)
+	writeTaskFile(t, filepath.Join(root, "vibe-docs", ".specrules.json"), taskRules)
+	return dir
+}
+
+func writeTaskFile(t *testing.T, p, content string) {
+	t.Helper()
+	_ = os.MkdirAll(filepath.Dir(p), 0o755)
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runCLI executes codectl with args and returns what it printed to stdout.
+// Flags are reset first since cobra keeps them between runs.
+func runCLI(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	resetFlags(rootCmd)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	rootCmd.SetArgs(args)
+	err = rootCmd.ExecuteContext(t.Context())
+	os.Stdout = stdout
+	_ = w.Close()
+	return <-out, err
+}
+
+func resetFlags(c *cobra.Command) {
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			_ = sv.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
+
+func listPaths(t *testing.T, args ...string) []string {
+	t.Helper()
+	out, err := runCLI(t, append([]string{"task", "list", "--json"}, args...)...)
+	if err != nil {
+		t.Fatalf("task list %v: %v", args, err)
+	}
+	var ts []taskstore.Task
+	if err := json.Unmarshal([]byte(out), &ts); err != nil {
+		t.Fatalf("task list %v: %v\n%s", args, err, out)
+	}
+	var paths []string
+	for _, tk := range ts {
+		paths = append(paths, tk.Path)
+	}
+	return paths
+}
+
+func TestTaskList_Filters(t *testing.T) {
+	dir := taskRepo(t)
+	writeTaskFile(t, filepath.Join(dir, "a.task.mdx"), "---\ntitle: Add login\nstatus: todo\nowner: ann\npriority: P1\n---\n")
+	writeTaskFile(t, filepath.Join(dir, "b.task.mdx"), "---\ntitle: Fix crash\nstatus: done\nowner: bob\npriority: P0\n---\n")
+	writeTaskFile(t, filepath.Join(dir, "archive", "c.task.mdx"), "---\ntitle: Setup\nstatus: done\nowner: ann\n---\n")
+
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"a.task.mdx", "b.task.mdx"}},
+		{[]string{"--status", "done"}, []string{"b.task.mdx"}},
+		{[]string{"--owner", "ann"}, []string{"a.task.mdx"}},
+		{[]string{"-p", "P0"}, []string{"b.task.mdx"}},
+		{[]string{"-q", "login"}, []string{"a.task.mdx"}},
+		{[]string{"--owner", "ann", "--archived"}, []string{"a.task.mdx", "archive/c.task.mdx"}},
+		{[]string{"--status", "in-progress"}, nil},
+	}
+	for _, c := range cases {
+		if got := listPaths(t, c.args...); !slices.Equal(got, c.want) {
+			t.Fatalf("task list %v = %v, want %v", c.args, got, c.want)
+		}
+	}
+	out, err := runCLI(t, "task", "ls", "--status", "done")
+	if err != nil || !strings.Contains(out, "b.task.mdx") || strings.Contains(out, "a.task.mdx") {
+		t.Fatalf("task ls: %v\n%s", err, out)
+	}
+}
+
+func TestTaskSetDone_Enums(t *testing.T) {
+	dir := taskRepo(t)
+	a := filepath.Join(dir, "a.task.mdx")
+	writeTaskFile(t, a, "---\ntitle: A\nstatus: todo\n---\n\nBody\n")
+	writeTaskFile(t, filepath.Join(dir, "b.task.mdx"), "---\ntitle: B\nstatus: todo\ndependsOn: [a.task.mdx]\n---\n")
+
+	if _, err := runCLI(t, "task", "set", "a", "status=later"); !errors.Is(err, taskstore.ErrInvalid) {
+		t.Fatalf("set status=later: want ErrInvalid, got %v", err)
+	}
+	if _, err := runCLI(t, "task", "set", "a", "priority=P9"); !errors.Is(err, taskstore.ErrInvalid) {
+		t.Fatalf("set priority=P9: want ErrInvalid, got %v", err)
+	}
+	if _, err := runCLI(t, "task", "set", "a", "status"); err == nil {
+		t.Fatal("field without = accepted")
+	}
+	out, err := runCLI(t, "task", "set", "a", "priority=p1", "status=IN-PROGRESS")
+	if err != nil || out != "a.task.mdx: updated priority, status\n" {
+		t.Fatalf("set: %v %q", err, out)
+	}
+	b, _ := os.ReadFile(a)
+	if want := "---\ntitle: A\nstatus: in-progress\npriority: P1\n---\n\nBody\n"; string(b) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", b, want)
+	}
+
+	out, err = runCLI(t, "task", "done", "a")
+	if err != nil || out != "a.task.mdx: done\nunblocked: b.task.mdx\n" {
+		t.Fatalf("done: %v %q", err, out)
+	}
+
+	// done is validated against the enum like any other value
+	writeTaskFile(t, filepath.Join(filepath.Dir(dir), ".specrules.json"), strings.Replace(taskRules, `, "done"]`, `, "closed"]`, 1))
+	if _, err := runCLI(t, "task", "done", "b"); !errors.Is(err, taskstore.ErrInvalid) {
+		t.Fatalf("done without a done status: want ErrInvalid, got %v", err)
+	}
+}
+
+func TestTaskRm(t *testing.T) {
+	dir := taskRepo(t)
+	writeTaskFile(t, filepath.Join(dir, "a.task.mdx"), "---\ntitle: A\nstatus: done\n---\n")
+	writeTaskFile(t, filepath.Join(dir, "b.task.mdx"), "---\ntitle: B\nstatus: todo\n---\n\nAfter [A](./a.task.mdx).\n")
+	writeTaskFile(t, filepath.Join(dir, "c.task.mdx"), "---\ntitle: C\nstatus: todo\n---\n")
+
+	out, err := runCLI(t, "task", "rm", "--archive", "a")
+	if err != nil || out != "archived a.task.mdx -> archive/a.task.mdx\n" {
+		t.Fatalf("rm --archive: %v %q", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "archive", "a.task.mdx")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "b.task.mdx")); !strings.Contains(string(b), "(./archive/a.task.mdx)") {
+		t.Fatalf("link not rewritten:\n%s", b)
+	}
+
+	out, err = runCLI(t, "task", "rm", "c", "vibe-docs/task/b.task.mdx")
+	if err != nil || out != "removed c.task.mdx\nremoved b.task.mdx\n" {
+		t.Fatalf("rm: %v %q", err, out)
+	}
+	if got := listPaths(t, "--archived"); !slices.Equal(got, []string{"archive/a.task.mdx"}) {
+		t.Fatalf("remaining tasks: %v", got)
+	}
+	if _, err := runCLI(t, "task", "rm", "missing"); !errors.Is(err, taskstore.ErrNotFound) {
+		t.Fatalf("rm missing: want ErrNotFound, got %v", err)
+	}
+}
+
+func TestTaskNew_DependsOn(t *testing.T) {
+	dir := taskRepo(t)
+	writeTaskFile(t, filepath.Join(dir, "250914-100000-add-login.task.mdx"), "---\ntitle: Login\nstatus: todo\n---\n")
+	writeTaskFile(t, filepath.Join(dir, "archive", "250901-090000-setup.task.mdx"), "---\ntitle: Setup\nstatus: done\n---\n")
+
+	out, err := runCLI(t, "task", "new", "Logout", "--depends-on", "LOGIN,setup", "--owner", "", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tk taskstore.Task
+	if err := json.Unmarshal([]byte(out), &tk); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	want := []string{"250914-100000-add-login.task.mdx", "archive/250901-090000-setup.task.mdx"}
+	if !slices.Equal(tk.DependsOn, want) || tk.Owner != "" {
+		t.Fatalf("unexpected task: %+v", tk)
+	}
+	if got := listPaths(t, "-q", "logout"); !slices.Equal(got, []string{tk.Path}) {
+		t.Fatalf("created task not listed: %v", got)
+	}
+
+	if _, err := runCLI(t, "task", "new", "Other", "--depends-on", "nothing"); !errors.Is(err, taskstore.ErrNotFound) {
+		t.Fatalf("unknown dependency: want ErrNotFound, got %v", err)
+	}
+	if got := listPaths(t, "-q", "other"); len(got) != 0 {
+		t.Fatalf("task created despite unknown dependency: %v", got)
+	}
+}
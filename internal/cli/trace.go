@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"codectl/internal/spectrace"
+)
+
+var (
+	traceJSON      bool
+	traceUncovered bool
+)
+
+func init() {
+	rootCmd.AddCommand(traceCmd)
+	traceCmd.Flags().BoolVar(&traceJSON, "json", false, "output JSON report")
+	traceCmd.Flags().BoolVar(&traceUncovered, "uncovered", false, "only list sections without tasks and tasks pointing at missing specs")
+}
+
+var traceCmd = &cobra.Command{
+	Use:   "trace",
+	Short: "Report which tasks implement each spec section",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := repoRootOrCwd(cmd)
+		rep, err := spectrace.Build(root)
+		if err != nil {
+			return err
+		}
+		if traceUncovered {
+			rep.Specs = []spectrace.Spec{}
+		}
+		if traceJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(rep)
+		}
+		writeTrace(os.Stdout, rep)
+		return nil
+	},
+}
+
+func writeTrace(w io.Writer, rep *spectrace.Report) {
+	for _, s := range rep.Specs {
+		title := s.Title
+		if title == "" {
+			title = "-"
+		}
+		whole := ""
+		if s.Whole > 0 {
+			whole = fmt.Sprintf(", %d left to whole-spec tasks", s.Whole)
+		}
+		fmt.Fprintf(w, "%s  %s  (%d/%d sections covered%s, %d task(s))\n", s.Path, title, s.Covered, s.Total, whole, s.TaskCount())
+		for _, t := range s.Tasks {
+			fmt.Fprintf(w, "  (whole spec)\n    - %s\n", t)
+		}
+		for _, sec := range s.Sections {
+			mark := " "
+			switch {
+			case sec.Whole:
+				mark = "~"
+			case !sec.Covered:
+				mark = "!"
+			}
+			fmt.Fprintf(w, "  %s %s%s  #%s\n", mark, strings.Repeat("  ", sec.Level-spectrace.MinLevel), sec.Title, sec.Anchor)
+			for _, t := range sec.Tasks {
+				fmt.Fprintf(w, "    %s- %s\n", strings.Repeat("  ", sec.Level-spectrace.MinLevel), t)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "Uncovered sections: %d\n", len(rep.Uncovered))
+	for _, u := range rep.Uncovered {
+		note := ""
+		if u.Whole {
+			note = "  (spec has whole-spec tasks)"
+		}
+		fmt.Fprintf(w, "  %s:%d  %s%s\n", u.Spec, u.Line, u.Title, note)
+	}
+	fmt.Fprintf(w, "Tasks with missing specs: %d\n", len(rep.Dangling))
+	for _, d := range rep.Dangling {
+		fmt.Fprintf(w, "  %s:%d  -> %s (%s)\n", d.Task, d.Line, d.Target, d.Reason)
+	}
+}
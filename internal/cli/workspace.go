@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"codectl/internal/workspace"
+)
+
+var (
+	workspaceName string
+	workspaceJSON bool
+)
+
+func init() {
+	rootCmd.AddCommand(workspaceCmd)
+	workspaceCmd.AddCommand(workspaceListCmd, workspaceAddCmd, workspaceRmCmd)
+	workspaceListCmd.Flags().BoolVar(&workspaceJSON, "json", false, "output JSON")
+	workspaceAddCmd.Flags().StringVar(&workspaceName, "name", "", "display name")
+}
+
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Manage the workspace roots the Web UI may operate on",
+	Long: "Workspace roots are stored in ~/.codectl/workspace.json. The Web UI only lists them; " +
+		"adding or removing a root is done here or by editing the file.",
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List workspace roots, including the built-in repo root",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		roots, err := workspace.Roots(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if workspaceJSON {
+			return writeJSONOut(roots)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tPATH")
+		for _, r := range roots {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", r.ID, orDash(r.Name), r.Path)
+		}
+		return tw.Flush()
+	},
+}
+
+var workspaceAddCmd = &cobra.Command{
+	Use:   "add <id> <path>",
+	Short: "Add or replace a workspace root",
+	Long:  "Path is absolute, ~/-relative, or relative to the repository root.",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := workspace.Load()
+		if err != nil {
+			return err
+		}
+		c.Roots = slices.DeleteFunc(c.Roots, func(r workspace.Root) bool { return r.ID == args[0] })
+		c.Roots = append(c.Roots, workspace.Root{ID: args[0], Name: workspaceName, Path: args[1]})
+		if err := workspace.Save(c); err != nil {
+			return err
+		}
+		dir, err := workspace.Resolve(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("added %s -> %s\n", args[0], dir)
+		return nil
+	},
+}
+
+var workspaceRmCmd = &cobra.Command{
+	Use:   "rm <id>",
+	Short: "Remove a workspace root",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := workspace.Load()
+		if err != nil {
+			return err
+		}
+		n := len(c.Roots)
+		c.Roots = slices.DeleteFunc(c.Roots, func(r workspace.Root) bool { return r.ID == args[0] })
+		if len(c.Roots) == n {
+			return fmt.Errorf("%w: %s", workspace.ErrUnknownRoot, args[0])
+		}
+		if err := workspace.Save(c); err != nil {
+			return err
+		}
+		fmt.Printf("removed %s\n", args[0])
+		return nil
+	},
+}
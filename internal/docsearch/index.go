@@ -0,0 +1,333 @@
+// Package docsearch is an in-process BM25 full-text index over the spec and
+// task documents of a repository. Documents are indexed by section, so
+// results point at the heading that matched; the index refreshes changed
+// files on every search.
+package docsearch
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"codectl/internal/document"
+	"codectl/internal/specgraph"
+)
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+	// titleBoost repeats title terms in the first section of a document.
+	titleBoost = 3
+)
+
+// section is one indexed unit: the text under a heading.
+type section struct {
+	doc     *doc
+	heading string
+	anchor  string
+	line    int
+	text    string
+	front   string // frontmatter values, first section only
+	length  int
+	terms   []string // distinct terms, for removal
+}
+
+type doc struct {
+	path     string
+	kind     string
+	title    string
+	status   string
+	modTime  time.Time
+	size     int64
+	sections []*section
+}
+
+// Index is the search index of one repository. It is safe for concurrent
+// use.
+type Index struct {
+	root string
+
+	mu       sync.Mutex
+	docs     map[string]*doc
+	postings map[string]map[*section]int // term -> section -> term frequency
+	total    int                         // summed section lengths
+	count    int                         // number of sections
+}
+
+// New returns an empty index of the documents under root; Refresh fills it.
+func New(root string) *Index {
+	return &Index{root: root, docs: map[string]*doc{}, postings: map[string]map[*section]int{}}
+}
+
+// Build returns an index of the documents under root.
+func Build(root string) (*Index, error) {
+	ix := New(root)
+	return ix, ix.Refresh()
+}
+
+// Refresh re-indexes documents whose size or modification time changed and
+// drops deleted ones.
+func (ix *Index) Refresh() error {
+	seen := map[string]bool{}
+	for _, d := range specgraph.Dirs {
+		dir := filepath.Join(ix.root, filepath.FromSlash(d))
+		err := filepath.WalkDir(dir, func(p string, de os.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			kind := docKind(de.Name())
+			if de.IsDir() || kind == "" {
+				return nil
+			}
+			info, err := de.Info()
+			if err != nil {
+				return nil
+			}
+			rel := filepath.ToSlash(relOr(ix.root, p))
+			seen[rel] = true
+			ix.mu.Lock()
+			old := ix.docs[rel]
+			ix.mu.Unlock()
+			if old != nil && old.modTime.Equal(info.ModTime()) && old.size == info.Size() {
+				return nil
+			}
+			content, err := os.ReadFile(p)
+			if err != nil {
+				return nil
+			}
+			d := parse(rel, kind, content)
+			d.modTime, d.size = info.ModTime(), info.Size()
+			ix.mu.Lock()
+			ix.remove(rel)
+			ix.add(d)
+			ix.mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for rel := range ix.docs {
+		if !seen[rel] {
+			ix.remove(rel)
+		}
+	}
+	return nil
+}
+
+func docKind(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".spec.mdx"):
+		return specgraph.NodeSpec
+	case strings.HasSuffix(name, ".task.mdx"):
+		return specgraph.NodeTask
+	}
+	return ""
+}
+
+func relOr(root, p string) string {
+	if r, err := filepath.Rel(root, p); err == nil {
+		return r
+	}
+	return p
+}
+
+// parse splits a document into sections at its headings. The first section
+// holds the frontmatter values and any text before the first heading.
+func parse(rel, kind string, content []byte) *doc {
+	d := &doc{path: rel, kind: kind}
+	parsed, err := document.Parse(content)
+	body, first := string(content), 1
+	var front []string
+	if err == nil {
+		d.title, d.status = parsed.String("title"), parsed.String("status")
+		body, first = parsed.Body, parsed.BodyLine()
+		keys := parsed.Keys()
+		fields := parsed.Fields()
+		for _, k := range keys {
+			if v := fields[k]; v != "" {
+				front = append(front, v)
+			}
+		}
+	}
+	if d.title == "" {
+		d.title = strings.TrimSuffix(strings.TrimSuffix(filepath.Base(rel), ".mdx"), "."+kind)
+	}
+	lines := strings.Split(body, "\n")
+	cur := &section{doc: d, line: first}
+	start := 0
+	for _, h := range specgraph.Headings(body, first) {
+		idx := h.Line - first
+		if idx < start || idx >= len(lines) {
+			continue
+		}
+		cur.text = strings.TrimSpace(strings.Join(lines[start:idx], "\n"))
+		d.sections = append(d.sections, cur)
+		cur = &section{doc: d, heading: h.Text, anchor: h.Anchor, line: h.Line}
+		start = idx + 1
+	}
+	cur.text = strings.TrimSpace(strings.Join(lines[start:], "\n"))
+	d.sections = append(d.sections, cur)
+	d.sections[0].front = strings.Join(front, "\n")
+	return d
+}
+
+// add indexes the sections of d; the caller holds ix.mu.
+func (ix *Index) add(d *doc) {
+	ix.docs[d.path] = d
+	for i, s := range d.sections {
+		terms := Tokenize(s.heading + "\n" + s.front + "\n" + s.text)
+		if i == 0 {
+			title := Tokenize(d.title)
+			for range titleBoost {
+				terms = append(terms, title...)
+			}
+		}
+		s.length = len(terms)
+		s.terms = unique(append([]string{}, terms...))
+		ix.total += s.length
+		ix.count++
+		for _, t := range terms {
+			p := ix.postings[t]
+			if p == nil {
+				p = map[*section]int{}
+				ix.postings[t] = p
+			}
+			p[s]++
+		}
+	}
+}
+
+// remove drops the document rel from the index; the caller holds ix.mu.
+func (ix *Index) remove(rel string) {
+	d := ix.docs[rel]
+	if d == nil {
+		return
+	}
+	delete(ix.docs, rel)
+	for _, s := range d.sections {
+		ix.total -= s.length
+		ix.count--
+		for _, t := range s.terms {
+			delete(ix.postings[t], s)
+			if len(ix.postings[t]) == 0 {
+				delete(ix.postings, t)
+			}
+		}
+	}
+}
+
+// Options filter a search.
+type Options struct {
+	// Kind restricts results to "spec" or "task" documents.
+	Kind string
+	// Limit caps the number of results (default 20).
+	Limit int
+}
+
+// Result is a matching document with its best section.
+type Result struct {
+	Path    string  `json:"path"`
+	Kind    string  `json:"kind"`
+	Title   string  `json:"title"`
+	Status  string  `json:"status,omitempty"`
+	Score   float64 `json:"score"`
+	Heading string  `json:"heading,omitempty"`
+	Anchor  string  `json:"anchor,omitempty"`
+	Line    int     `json:"line"`
+	Snippet string  `json:"snippet"`
+	// Matches are [start, end) byte offsets of query matches in Snippet.
+	Matches [][2]int `json:"matches"`
+}
+
+// Search ranks documents for query with BM25 over their sections; a
+// document scores as its best section.
+func (ix *Index) Search(query string, opt Options) []Result {
+	terms := unique(Tokenize(query))
+	if len(terms) == 0 {
+		return []Result{}
+	}
+	limit := opt.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.count == 0 {
+		return []Result{}
+	}
+	avg := float64(ix.total) / float64(ix.count)
+	scores := map[*section]float64{}
+	for _, t := range terms {
+		p := ix.postings[t]
+		if len(p) == 0 {
+			continue
+		}
+		idf := math.Log(1 + (float64(ix.count)-float64(len(p))+0.5)/(float64(len(p))+0.5))
+		for s, tf := range p {
+			if opt.Kind != "" && s.doc.kind != opt.Kind {
+				continue
+			}
+			f := float64(tf)
+			scores[s] += idf * f * (k1 + 1) / (f + k1*(1-b+b*float64(s.length)/avg))
+		}
+	}
+	best := map[*doc]*section{}
+	for s, sc := range scores {
+		if cur := best[s.doc]; cur == nil || sc > scores[cur] || (sc == scores[cur] && s.line < cur.line) {
+			best[s.doc] = s
+		}
+	}
+	out := make([]Result, 0, len(best))
+	hl := highlightTerms(query)
+	for d, s := range best {
+		snip, matches := snippet(s.text, hl)
+		for _, alt := range []string{s.heading, d.title, s.front} {
+			if len(matches) > 0 {
+				break
+			}
+			if a, m := snippet(alt, hl); len(m) > 0 {
+				snip, matches = a, m
+			}
+		}
+		out = append(out, Result{
+			Path: d.path, Kind: d.kind, Title: d.title, Status: d.status,
+			Score:   math.Round(scores[s]*1000) / 1000,
+			Heading: s.heading, Anchor: s.anchor, Line: s.line,
+			Snippet: snip, Matches: matches,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Path < out[j].Path
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+func unique(ts []string) []string {
+	seen := map[string]bool{}
+	out := ts[:0]
+	for _, t := range ts {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
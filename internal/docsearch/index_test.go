@@ -0,0 +1,88 @@
+package docsearch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func write(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := strings.Join(Tokenize("Spec 状态机, BM25!"), " ")
+	if got != "spec 状 状态 态 态机 机 bm25" {
+		t.Fatalf("Tokenize = %q", got)
+	}
+	if got := strings.Join(highlightTerms("状态机 机"), " "); got != "状态 态机 机" {
+		t.Fatalf("highlightTerms = %q", got)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "vibe-docs/spec/400-spec.spec.mdx", "---\ntitle: 规范治理\nstatus: accepted\n---\n# 规范治理\n\n## 1. 目录\n\n放在 vibe-docs/spec 下。\n\n## 4. 状态流转\n\n默认流程为 draft → review → accepted，使用**状态机**约束流转。\n")
+	write(t, root, "vibe-docs/spec/300-mcp.spec.mdx", "---\ntitle: MCP\n---\n# MCP\n\n## 概述\n\nMCP server 管理，与状态无关的段落。\n")
+	write(t, root, "vibe-docs/task/t.task.mdx", "---\ntitle: 实现状态机\nstatus: todo\n---\n## 背景\n\n见规范。\n")
+
+	ix, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := ix.Search("状态机", Options{})
+	if len(res) != 3 {
+		t.Fatalf("results = %+v", res)
+	}
+	top := res[0]
+	if top.Path != "vibe-docs/spec/400-spec.spec.mdx" && top.Path != "vibe-docs/task/t.task.mdx" {
+		t.Fatalf("top = %+v", top)
+	}
+	var spec Result
+	for _, r := range res {
+		if r.Path == "vibe-docs/spec/400-spec.spec.mdx" {
+			spec = r
+		}
+	}
+	if spec.Anchor != "4-状态流转" || spec.Line != 11 || len(spec.Matches) == 0 {
+		t.Fatalf("spec result = %+v", spec)
+	}
+	m := spec.Matches[len(spec.Matches)-1]
+	if got := spec.Snippet[m[0]:m[1]]; got != "状态机" {
+		t.Fatalf("highlight = %q in %q", got, spec.Snippet)
+	}
+	if res[2].Path != "vibe-docs/spec/300-mcp.spec.mdx" {
+		t.Fatalf("weakest match should rank last: %+v", res)
+	}
+
+	if res := ix.Search("状态机", Options{Kind: "task"}); len(res) != 1 || res[0].Title != "实现状态机" {
+		t.Fatalf("kind filter = %+v", res)
+	}
+	if res := ix.Search("MCP server", Options{Limit: 1}); len(res) != 1 || res[0].Path != "vibe-docs/spec/300-mcp.spec.mdx" {
+		t.Fatalf("english = %+v", res)
+	}
+
+	// changed and deleted files are picked up by Refresh
+	write(t, root, "vibe-docs/spec/300-mcp.spec.mdx", "---\ntitle: MCP\n---\n# MCP\n\n## 概述\n\n新增 websocket 传输。\n")
+	later := time.Now().Add(time.Minute)
+	_ = os.Chtimes(filepath.Join(root, "vibe-docs/spec/300-mcp.spec.mdx"), later, later)
+	_ = os.Remove(filepath.Join(root, "vibe-docs/task/t.task.mdx"))
+	if err := ix.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if res := ix.Search("websocket", Options{}); len(res) != 1 {
+		t.Fatalf("after change = %+v", res)
+	}
+	if res := ix.Search("状态机", Options{}); len(res) != 1 {
+		t.Fatalf("after delete = %+v", res)
+	}
+}
@@ -0,0 +1,91 @@
+package docsearch
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Snippet window, in runes.
+const (
+	snippetBefore = 30
+	snippetLen    = 120
+)
+
+var (
+	mdNoiseRe = regexp.MustCompile("(?m)^\\s*(?:#{1,6}\\s+|[-*+]\\s+(?:\\[[ xX]\\]\\s+)?|>\\s*|\\d+[.)]\\s+|\\|)|`|\\*\\*|\\|\\s*$")
+	spaceRe   = regexp.MustCompile(`\s+`)
+)
+
+// snippet returns a window of text around the first match of terms and the
+// byte ranges of all matches inside it.
+func snippet(text string, terms []string) (string, [][2]int) {
+	text = strings.TrimSpace(spaceRe.ReplaceAllString(mdNoiseRe.ReplaceAllString(text, ""), " "))
+	if text == "" {
+		return "", [][2]int{}
+	}
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		lower = text // case folding changed byte offsets; match as written
+	}
+	first := -1
+	for _, t := range terms {
+		if i := strings.Index(lower, t); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	start := 0
+	if first > 0 {
+		start = first
+		for n := 0; n < snippetBefore && start > 0; n++ {
+			_, size := utf8.DecodeLastRuneInString(text[:start])
+			start -= size
+		}
+	}
+	end := start
+	for n := 0; n < snippetLen && end < len(text); n++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+	snip := text[start:end]
+	prefix, suffix := "", ""
+	if start > 0 {
+		prefix = "…"
+	}
+	if end < len(text) {
+		suffix = "…"
+	}
+	var ranges [][2]int
+	window := lower[start:end]
+	for _, t := range terms {
+		for off := 0; ; {
+			i := strings.Index(window[off:], t)
+			if i < 0 {
+				break
+			}
+			a := len(prefix) + off + i
+			ranges = append(ranges, [2]int{a, a + len(t)})
+			off += i + len(t)
+		}
+	}
+	return prefix + snip + suffix, mergeRanges(ranges)
+}
+
+// mergeRanges sorts ranges and joins overlapping or adjacent ones.
+func mergeRanges(rs [][2]int) [][2]int {
+	if len(rs) == 0 {
+		return [][2]int{}
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i][0] < rs[j][0] })
+	out := [][2]int{rs[0]}
+	for _, r := range rs[1:] {
+		last := &out[len(out)-1]
+		if r[0] <= last[1] {
+			last[1] = max(last[1], r[1])
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
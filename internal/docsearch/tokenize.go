@@ -0,0 +1,76 @@
+package docsearch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// isCJK reports whether r belongs to a script written without spaces.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Tokenize splits text into index terms: lower-cased words for scripts
+// separated by spaces, and the single characters plus overlapping bigrams
+// of CJK runs, so "状态机" yields 状, 态, 机, 状态 and 态机.
+func Tokenize(text string) []string {
+	var out []string
+	var word strings.Builder
+	var run []rune
+	flushWord := func() {
+		if word.Len() > 0 {
+			out = append(out, word.String())
+			word.Reset()
+		}
+	}
+	flushRun := func() {
+		for i, r := range run {
+			out = append(out, string(r))
+			if i+1 < len(run) {
+				out = append(out, string(run[i:i+2]))
+			}
+		}
+		run = run[:0]
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			run = append(run, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushRun()
+			word.WriteRune(unicode.ToLower(r))
+		default:
+			flushWord()
+			flushRun()
+		}
+	}
+	flushWord()
+	flushRun()
+	return out
+}
+
+// highlightTerms returns the query parts searched for in snippets: words
+// and CJK bigrams, or single CJK characters for one-character runs.
+func highlightTerms(query string) []string {
+	seen := map[string]bool{}
+	var out []string
+	toks := Tokenize(query)
+	for i, t := range toks {
+		r := []rune(t)
+		if len(r) == 1 && isCJK(r[0]) {
+			// a lone character matters only when no bigram follows it
+			if i+1 < len(toks) && strings.HasPrefix(toks[i+1], t) && len([]rune(toks[i+1])) == 2 {
+				continue
+			}
+			if i > 0 && len([]rune(toks[i-1])) == 2 && strings.HasSuffix(toks[i-1], t) {
+				continue
+			}
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,486 @@
+// Package document parses MDX/Markdown files with YAML frontmatter.
+//
+// The frontmatter is parsed with a real YAML parser (lists, nested maps,
+// multi-line strings and comments are supported) and keys are kept
+// case-sensitive, in source order. Edits are applied to the raw frontmatter
+// lines so that untouched keys, comments and formatting survive a rewrite.
+package document
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Delimiter opens and closes the frontmatter block.
+const Delimiter = "---"
+
+var (
+	// ErrNoFrontmatter means the first line is not the '---' delimiter.
+	ErrNoFrontmatter = errors.New("missing frontmatter start '---' on first line")
+	// ErrUnterminated means the closing '---' delimiter was not found.
+	ErrUnterminated = errors.New("missing frontmatter end '---'")
+)
+
+// SyntaxError reports invalid frontmatter YAML at a file line.
+type SyntaxError struct {
+	Line int // 1-based file line, 0 when unknown
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("invalid frontmatter YAML (line %d): %s", e.Line, e.Msg)
+	}
+	return "invalid frontmatter YAML: " + e.Msg
+}
+
+// Pos is a 1-based line/column position in the source file.
+type Pos struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+// Document is a parsed file: frontmatter plus body.
+type Document struct {
+	// HasFrontmatter is true when both delimiters were found.
+	HasFrontmatter bool
+	// Body is everything after the closing delimiter line (or the whole file
+	// when there is no frontmatter).
+	Body string
+
+	lines []string   // raw frontmatter lines between the delimiters
+	nl    string     // newline style of the source
+	root  *yaml.Node // top-level mapping; nil when empty or invalid
+}
+
+var yamlLineRe = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// Parse splits b into frontmatter and body and parses the frontmatter.
+// It always returns a usable Document; the error is one of ErrNoFrontmatter,
+// ErrUnterminated or *SyntaxError.
+func Parse(b []byte) (*Document, error) {
+	s := strings.TrimPrefix(string(b), "\ufeff")
+	nl := "\n"
+	if strings.Contains(s, "\r\n") {
+		nl = "\r\n"
+	}
+	d := &Document{nl: nl}
+	first, rest, _ := strings.Cut(s, "\n")
+	if strings.TrimRight(first, "\r") != Delimiter {
+		d.Body = s
+		return d, ErrNoFrontmatter
+	}
+	var fm []string
+	for {
+		if rest == "" {
+			d.Body = s
+			return d, ErrUnterminated
+		}
+		var ln string
+		ln, rest, _ = strings.Cut(rest, "\n")
+		ln = strings.TrimRight(ln, "\r")
+		if ln == Delimiter {
+			break
+		}
+		fm = append(fm, ln)
+	}
+	d.HasFrontmatter = true
+	d.lines = fm
+	d.Body = rest
+	return d, d.reparse()
+}
+
+// New returns a document with an empty frontmatter block and the given body.
+func New(body string) *Document {
+	return &Document{HasFrontmatter: true, Body: body, nl: "\n"}
+}
+
+// reparse rebuilds the YAML node tree from the raw lines.
+func (d *Document) reparse() error {
+	d.root = nil
+	src := strings.Join(d.lines, "\n")
+	if strings.TrimSpace(src) == "" {
+		return nil
+	}
+	var n yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &n); err != nil {
+		msg := strings.TrimPrefix(err.Error(), "yaml: ")
+		line := 0
+		if m := yamlLineRe.FindStringSubmatch(err.Error()); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			line++ // account for the opening delimiter
+			msg = m[2]
+		}
+		return &SyntaxError{Line: line, Msg: msg}
+	}
+	if len(n.Content) == 0 {
+		return nil
+	}
+	if n.Content[0].Kind != yaml.MappingNode {
+		return &SyntaxError{Line: 2, Msg: "frontmatter must be a mapping of keys to values"}
+	}
+	d.root = n.Content[0]
+	return nil
+}
+
+// pair returns the key and value nodes for key (case-sensitive).
+func (d *Document) pair(key string) (*yaml.Node, *yaml.Node, int) {
+	if d.root == nil {
+		return nil, nil, -1
+	}
+	for i := 0; i+1 < len(d.root.Content); i += 2 {
+		if d.root.Content[i].Value == key {
+			return d.root.Content[i], d.root.Content[i+1], i
+		}
+	}
+	return nil, nil, -1
+}
+
+// Keys returns top-level frontmatter keys in source order.
+func (d *Document) Keys() []string {
+	if d.root == nil {
+		return nil
+	}
+	out := make([]string, 0, len(d.root.Content)/2)
+	for i := 0; i+1 < len(d.root.Content); i += 2 {
+		out = append(out, d.root.Content[i].Value)
+	}
+	return out
+}
+
+// Has reports whether key is present.
+func (d *Document) Has(key string) bool {
+	k, _, _ := d.pair(key)
+	return k != nil
+}
+
+// Node returns the YAML value node for key, or nil.
+func (d *Document) Node(key string) *yaml.Node {
+	_, v, _ := d.pair(key)
+	return v
+}
+
+// Lookup returns the value of key as a string. Scalars yield their value,
+// flow collections (such as the `{auto}` placeholder) their source text,
+// sequences of scalars a comma-separated list.
+func (d *Document) Lookup(key string) (string, bool) {
+	_, v, _ := d.pair(key)
+	if v == nil {
+		return "", false
+	}
+	return d.text(v), true
+}
+
+// String returns Lookup(key) without the presence flag.
+func (d *Document) String(key string) string {
+	s, _ := d.Lookup(key)
+	return s
+}
+
+// Strings returns a sequence value as a string slice. A scalar yields a
+// one-element slice; a missing or null key yields nil.
+func (d *Document) Strings(key string) []string {
+	_, v, _ := d.pair(key)
+	if v == nil {
+		return nil
+	}
+	switch v.Kind {
+	case yaml.ScalarNode:
+		if v.Tag == "!!null" || v.Value == "" {
+			return nil
+		}
+		return []string{v.Value}
+	case yaml.SequenceNode:
+		out := make([]string, 0, len(v.Content))
+		for _, c := range v.Content {
+			out = append(out, d.text(c))
+		}
+		return out
+	default:
+		return []string{d.text(v)}
+	}
+}
+
+// Bool returns a boolean value; ok is false when missing or not a bool.
+func (d *Document) Bool(key string) (val bool, ok bool) {
+	_, v, _ := d.pair(key)
+	if v == nil || v.Kind != yaml.ScalarNode {
+		return false, false
+	}
+	if err := v.Decode(&val); err != nil {
+		return false, false
+	}
+	return val, true
+}
+
+// Time parses a date (2006-01-02) or RFC 3339 timestamp value.
+func (d *Document) Time(key string) (time.Time, bool) {
+	s := strings.TrimSpace(d.String(key))
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Decode decodes the value of key into out using YAML rules.
+func (d *Document) Decode(key string, out any) error {
+	_, v, _ := d.pair(key)
+	if v == nil {
+		return fmt.Errorf("missing key %q", key)
+	}
+	return v.Decode(out)
+}
+
+// Fields flattens the frontmatter into a string map (see Lookup).
+func (d *Document) Fields() map[string]string {
+	out := map[string]string{}
+	for _, k := range d.Keys() {
+		out[k] = d.String(k)
+	}
+	return out
+}
+
+// KeyPos returns the file position of key; ok is false when missing.
+func (d *Document) KeyPos(key string) (Pos, bool) {
+	k, _, _ := d.pair(key)
+	if k == nil {
+		return Pos{}, false
+	}
+	return Pos{Line: k.Line + 1, Col: k.Column}, true
+}
+
+// ValuePos returns the file position of key's value.
+func (d *Document) ValuePos(key string) (Pos, bool) {
+	_, v, _ := d.pair(key)
+	if v == nil {
+		return Pos{}, false
+	}
+	return Pos{Line: v.Line + 1, Col: v.Column}, true
+}
+
+// BodyLine returns the 1-based file line where the body starts.
+func (d *Document) BodyLine() int {
+	if !d.HasFrontmatter {
+		return 1
+	}
+	return len(d.lines) + 3
+}
+
+// FrontmatterLines returns the number of raw lines between the delimiters.
+func (d *Document) FrontmatterLines() int { return len(d.lines) }
+
+// text renders a value node as a display string.
+func (d *Document) text(v *yaml.Node) string {
+	switch v.Kind {
+	case yaml.ScalarNode:
+		if v.Tag == "!!null" && (v.Value == "" || v.Value == "~" || v.Value == "null") {
+			return ""
+		}
+		return v.Value
+	case yaml.AliasNode:
+		if v.Alias != nil {
+			return d.text(v.Alias)
+		}
+		return ""
+	case yaml.SequenceNode, yaml.MappingNode:
+		if v.Style&yaml.FlowStyle != 0 {
+			if raw, ok := d.rawFlow(v); ok {
+				return raw
+			}
+		}
+		if v.Kind == yaml.SequenceNode {
+			parts := make([]string, 0, len(v.Content))
+			for _, c := range v.Content {
+				parts = append(parts, d.text(c))
+			}
+			return strings.Join(parts, ", ")
+		}
+		cp := *v
+		cp.Style = yaml.FlowStyle
+		b, err := yaml.Marshal(&cp)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(b))
+	}
+	return ""
+}
+
+// rawFlow returns the source text of a single-line flow collection.
+func (d *Document) rawFlow(v *yaml.Node) (string, bool) {
+	if v.Line < 1 || v.Line > len(d.lines) {
+		return "", false
+	}
+	ln := []rune(d.lines[v.Line-1])
+	if v.Column < 1 || v.Column > len(ln) {
+		return "", false
+	}
+	open := ln[v.Column-1]
+	closeR := '}'
+	if open == '[' {
+		closeR = ']'
+	}
+	depth := 0
+	for i := v.Column - 1; i < len(ln); i++ {
+		switch ln[i] {
+		case open:
+			depth++
+		case closeR:
+			depth--
+			if depth == 0 {
+				return string(ln[v.Column-1 : i+1]), true
+			}
+		}
+	}
+	return "", false
+}
+
+// span returns the raw line range [start, end) occupied by the pair at index i
+// (in root.Content), excluding trailing blank and comment-only lines.
+func (d *Document) span(i int) (int, int) {
+	start := d.root.Content[i].Line - 1
+	end := len(d.lines)
+	if i+2 < len(d.root.Content) {
+		end = d.root.Content[i+2].Line - 1
+	}
+	// blank lines and unindented comments before the next key are not part of the value
+	for end > start+1 {
+		t := strings.TrimSpace(d.lines[end-1])
+		if t != "" && !(strings.HasPrefix(t, "#") && !strings.HasPrefix(d.lines[end-1], " ")) {
+			break
+		}
+		end--
+	}
+	return start, end
+}
+
+// FormatString renders s as a YAML scalar, quoting only when required.
+func FormatString(s string) string {
+	b, err := yaml.Marshal(s)
+	if err != nil {
+		return strconv.Quote(s)
+	}
+	out := strings.TrimSuffix(string(b), "\n")
+	if strings.Contains(out, "\n") {
+		return strconv.Quote(s)
+	}
+	return out
+}
+
+// Set assigns a string value to key, quoting as needed.
+func (d *Document) Set(key, value string) error { return d.SetRaw(key, FormatString(value)) }
+
+// SetStrings assigns a block sequence of strings to key.
+func (d *Document) SetStrings(key string, values []string) error {
+	items := make([]string, 0, len(values))
+	for _, v := range values {
+		items = append(items, FormatString(v))
+	}
+	return d.SetRawList(key, items)
+}
+
+// SetRawList assigns a block sequence of raw YAML items (e.g. flow
+// mappings such as "{by: alice}") to key.
+func (d *Document) SetRawList(key string, items []string) error {
+	if len(items) == 0 {
+		return d.SetRaw(key, "[]")
+	}
+	lines := make([]string, 0, len(items))
+	for _, it := range items {
+		lines = append(lines, "  - "+it)
+	}
+	return d.setLines(key, "", lines)
+}
+
+// SetRaw assigns raw YAML text (a single line, e.g. "{auto}") to key.
+// An existing key keeps its position and inline comment; a new key is appended.
+func (d *Document) SetRaw(key, raw string) error {
+	return d.setLines(key, raw, nil)
+}
+
+func (d *Document) setLines(key, inline string, block []string) error {
+	if !d.HasFrontmatter {
+		d.HasFrontmatter = true
+	}
+	if d.root == nil && strings.TrimSpace(strings.Join(d.lines, "")) != "" {
+		if err := d.reparse(); err != nil {
+			return err
+		}
+	}
+	head := key + ":"
+	if inline != "" {
+		head += " " + inline
+	}
+	k, v, i := d.pair(key)
+	if k == nil {
+		d.lines = append(d.lines, head)
+		d.lines = append(d.lines, block...)
+		return d.reparse()
+	}
+	start, end := d.span(i)
+	comment := v.LineComment
+	if comment == "" {
+		comment = k.LineComment
+	}
+	if comment != "" && block == nil && v.Line == k.Line {
+		if j := strings.LastIndex(d.lines[start], comment); j > 0 {
+			head += " " + d.lines[start][j:]
+		}
+	}
+	repl := append([]string{d.lines[start][:k.Column-1] + head}, block...)
+	d.lines = append(d.lines[:start], append(repl, d.lines[end:]...)...)
+	return d.reparse()
+}
+
+// Rename changes a key name in place; it fails if newKey already exists.
+func (d *Document) Rename(oldKey, newKey string) error {
+	k, _, _ := d.pair(oldKey)
+	if k == nil {
+		return fmt.Errorf("missing key %q", oldKey)
+	}
+	if d.Has(newKey) {
+		return fmt.Errorf("key %q already exists", newKey)
+	}
+	ln := d.lines[k.Line-1]
+	col := k.Column - 1
+	if !strings.HasPrefix(ln[col:], oldKey) {
+		return fmt.Errorf("cannot rename quoted key %q", oldKey)
+	}
+	d.lines[k.Line-1] = ln[:col] + newKey + ln[col+len(oldKey):]
+	return d.reparse()
+}
+
+// Delete removes key and its value; it reports whether the key existed.
+func (d *Document) Delete(key string) bool {
+	k, _, i := d.pair(key)
+	if k == nil {
+		return false
+	}
+	start, end := d.span(i)
+	d.lines = append(d.lines[:start], d.lines[end:]...)
+	_ = d.reparse()
+	return true
+}
+
+// Bytes renders the document back to file content.
+func (d *Document) Bytes() []byte {
+	if !d.HasFrontmatter {
+		return []byte(d.Body)
+	}
+	var b strings.Builder
+	b.WriteString(Delimiter + d.nl)
+	for _, ln := range d.lines {
+		b.WriteString(ln + d.nl)
+	}
+	b.WriteString(Delimiter + d.nl)
+	b.WriteString(d.Body)
+	return []byte(b.String())
+}
@@ -0,0 +1,138 @@
+package document
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const sample = `---
+title: CODECTL — 规格  # display title
+specVersion: 0.1.0
+status: draft
+lastUpdated: {auto}
+owners:
+  - "@lucas"
+  - bob
+summary: |
+  line one
+  line two
+
+# review metadata
+reviewers: [alice, carol]
+---
+
+# Body
+`
+
+func TestParse_Accessors(t *testing.T) {
+	d, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	wantKeys := []string{"title", "specVersion", "status", "lastUpdated", "owners", "summary", "reviewers"}
+	if got := d.Keys(); strings.Join(got, ",") != strings.Join(wantKeys, ",") {
+		t.Fatalf("keys = %v", got)
+	}
+	if d.String("title") != "CODECTL — 规格" {
+		t.Fatalf("title = %q", d.String("title"))
+	}
+	if d.Has("specversion") {
+		t.Fatalf("keys must be case-sensitive")
+	}
+	if d.String("lastUpdated") != "{auto}" {
+		t.Fatalf("lastUpdated = %q", d.String("lastUpdated"))
+	}
+	if got := d.Strings("owners"); len(got) != 2 || got[0] != "@lucas" {
+		t.Fatalf("owners = %v", got)
+	}
+	if got := d.Strings("reviewers"); len(got) != 2 || got[1] != "carol" {
+		t.Fatalf("reviewers = %v", got)
+	}
+	if d.String("summary") != "line one\nline two\n" {
+		t.Fatalf("summary = %q", d.String("summary"))
+	}
+	if p, ok := d.KeyPos("status"); !ok || p.Line != 4 || p.Col != 1 {
+		t.Fatalf("status pos = %+v", p)
+	}
+	if d.BodyLine() != 16 || d.Body != "\n# Body\n" {
+		t.Fatalf("body line %d, body %q", d.BodyLine(), d.Body)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	if _, err := Parse([]byte("# no frontmatter\n")); !errors.Is(err, ErrNoFrontmatter) {
+		t.Fatalf("expected ErrNoFrontmatter, got %v", err)
+	}
+	if _, err := Parse([]byte("---\ntitle: x\n")); !errors.Is(err, ErrUnterminated) {
+		t.Fatalf("expected ErrUnterminated, got %v", err)
+	}
+	_, err := Parse([]byte("---\ntitle: x\n  bad: [\n---\n"))
+	var se *SyntaxError
+	if !errors.As(err, &se) || se.Line < 2 {
+		t.Fatalf("expected SyntaxError with line, got %v", err)
+	}
+}
+
+func TestEdit_PreservesLayout(t *testing.T) {
+	d, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set("title", "New: title"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set("status", "accepted"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetStrings("owners", []string{"dave"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Rename("lastUpdated", "updated"); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Delete("summary") {
+		t.Fatalf("Delete summary failed")
+	}
+	if err := d.Set("priority", "P1"); err != nil {
+		t.Fatal(err)
+	}
+	want := `---
+title: 'New: title' # display title
+specVersion: 0.1.0
+status: accepted
+updated: {auto}
+owners:
+  - dave
+
+# review metadata
+reviewers: [alice, carol]
+priority: P1
+---
+
+# Body
+`
+	if got := string(d.Bytes()); got != want {
+		t.Fatalf("unexpected rewrite:\n%s", got)
+	}
+	d2, err := Parse(d.Bytes())
+	if err != nil || d2.String("title") != "New: title" {
+		t.Fatalf("reparse: %v, %q", err, d2.String("title"))
+	}
+}
+
+func TestRoundTrip_Unchanged(t *testing.T) {
+	src := "---\r\ntitle: x\r\n---\r\nbody\r\n"
+	d, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(d.Bytes()) != src {
+		t.Fatalf("round trip changed content: %q", d.Bytes())
+	}
+	n := New("body\n")
+	_ = n.Set("title", "t")
+	if string(n.Bytes()) != "---\ntitle: t\n---\nbody\n" {
+		t.Fatalf("New rendered %q", n.Bytes())
+	}
+}
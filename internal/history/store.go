@@ -0,0 +1,354 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cfg "codectl/internal/config"
+	"codectl/internal/safefs"
+)
+
+// Local history keeps a copy of a file's previous content before it is
+// overwritten, renamed or deleted through the API, similar to an IDE's
+// local history. Snapshots live under ~/.codectl/history/<key>/ where key
+// is derived from the absolute file path.
+
+// Operations recorded with a snapshot.
+const (
+	OpWrite   = "write"
+	OpDelete  = "delete"
+	OpRename  = "rename"
+	OpRestore = "restore"
+)
+
+// Config controls retention (~/.codectl/history.json).
+type Config struct {
+	MaxVersions int `json:"maxVersions"` // per file; <= 0 means unlimited
+	MaxAgeDays  int `json:"maxAgeDays"`  // <= 0 means unlimited
+}
+
+// DefaultConfig keeps 50 versions per file for 30 days.
+func DefaultConfig() Config { return Config{MaxVersions: 50, MaxAgeDays: 30} }
+
+// Version describes one stored snapshot.
+type Version struct {
+	ID   string      `json:"id"`
+	Path string      `json:"path"` // absolute path of the file at snapshot time
+	Op   string      `json:"op"`
+	Size int64       `json:"size"`
+	Mode os.FileMode `json:"mode"`
+	Time time.Time   `json:"time"`
+}
+
+var mu sync.Mutex
+
+// ErrNotFound is returned when a version does not exist.
+var ErrNotFound = errors.New("history version not found")
+
+func baseDir() (string, error) {
+	dir, err := cfg.DotDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history"), nil
+}
+
+func configPath() (string, error) {
+	dir, err := cfg.DotDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.json"), nil
+}
+
+// LoadConfig reads history.json; a missing file yields DefaultConfig.
+func LoadConfig() (Config, error) {
+	p, err := configPath()
+	if err != nil {
+		return DefaultConfig(), err
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return DefaultConfig(), nil
+		}
+		return DefaultConfig(), err
+	}
+	c := DefaultConfig()
+	if err := json.Unmarshal(b, &c); err != nil {
+		return DefaultConfig(), err
+	}
+	return c, nil
+}
+
+// SaveConfig writes history.json.
+func SaveConfig(c Config) error {
+	p, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, b, 0o644)
+}
+
+// fileDir returns the snapshot directory for an absolute file path.
+func fileDir(full string) (string, error) {
+	base, err := baseDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(full)))
+	return filepath.Join(base, hex.EncodeToString(sum[:])[:24]), nil
+}
+
+// Snapshot stores the current content of rel (beneath root) before op modifies it.
+// It returns nil without error when the file does not exist or is not a regular
+// file, and prunes old versions according to the loaded Config.
+func Snapshot(root, rel, op string) (*Version, error) {
+	st, err := safefs.Lstat(root, rel)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !st.Mode().IsRegular() {
+		return nil, nil
+	}
+	b, err := safefs.ReadFile(root, rel)
+	if err != nil {
+		return nil, err
+	}
+	full := filepath.Join(root, filepath.Clean(rel))
+	mu.Lock()
+	defer mu.Unlock()
+	v, err := store(full, op, b, st.Mode().Perm())
+	if err != nil {
+		return nil, err
+	}
+	c, _ := LoadConfig()
+	if err := prune(full, c, time.Now()); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+func store(full, op string, data []byte, mode os.FileMode) (*Version, error) {
+	dir, err := fileDir(full)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	// IDs are nanosecond timestamps (fixed width) so they sort chronologically.
+	n := now.UnixNano()
+	var id string
+	for {
+		id = strconv.FormatInt(n, 10)
+		if _, err := os.Stat(filepath.Join(dir, id+".json")); os.IsNotExist(err) {
+			break
+		}
+		n++
+	}
+	v := &Version{ID: id, Path: filepath.Clean(full), Op: op, Size: int64(len(data)), Mode: mode, Time: now}
+	if err := os.WriteFile(filepath.Join(dir, id+".data"), data, 0o600); err != nil {
+		return nil, err
+	}
+	meta, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, id+".json"), meta, 0o600); err != nil {
+		_ = os.Remove(filepath.Join(dir, id+".data"))
+		return nil, err
+	}
+	return v, nil
+}
+
+// List returns the stored versions of full, newest first.
+func List(full string) ([]Version, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return list(full)
+}
+
+func list(full string) ([]Version, error) {
+	dir, err := fileDir(full)
+	if err != nil {
+		return nil, err
+	}
+	return listDir(dir)
+}
+
+// listDir returns the versions stored in the snapshot directory dir, newest
+// first.
+func listDir(dir string) ([]Version, error) {
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Version{}, nil
+		}
+		return nil, err
+	}
+	out := make([]Version, 0, len(ents))
+	for _, e := range ents {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var v Version
+		if json.Unmarshal(b, &v) != nil || v.ID == "" {
+			continue
+		}
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	return out, nil
+}
+
+// Read returns the content and metadata of a stored version.
+func Read(full, id string) ([]byte, Version, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return read(full, id)
+}
+
+func read(full, id string) ([]byte, Version, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, Version{}, ErrNotFound
+	}
+	dir, err := fileDir(full)
+	if err != nil {
+		return nil, Version{}, err
+	}
+	mb, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, Version{}, ErrNotFound
+		}
+		return nil, Version{}, err
+	}
+	var v Version
+	if err := json.Unmarshal(mb, &v); err != nil {
+		return nil, Version{}, err
+	}
+	b, err := os.ReadFile(filepath.Join(dir, id+".data"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, Version{}, ErrNotFound
+		}
+		return nil, Version{}, err
+	}
+	return b, v, nil
+}
+
+// Restore writes version id back to rel beneath root. The current content,
+// if any, is snapshotted first (op "restore") so a restore can itself be undone.
+func Restore(root, rel, id string) (Version, error) {
+	b, v, err := Read(filepath.Join(root, filepath.Clean(rel)), id)
+	if err != nil {
+		return Version{}, err
+	}
+	if _, err := Snapshot(root, rel, OpRestore); err != nil {
+		return Version{}, err
+	}
+	mode := v.Mode
+	if mode == 0 {
+		mode = 0o644
+	}
+	if err := safefs.WriteFile(root, rel, b, mode); err != nil {
+		return Version{}, err
+	}
+	return v, nil
+}
+
+// Prune applies retention limits to the versions of full.
+func Prune(full string, c Config) error {
+	mu.Lock()
+	defer mu.Unlock()
+	return prune(full, c, time.Now())
+}
+
+func prune(full string, c Config, now time.Time) error {
+	dir, err := fileDir(full)
+	if err != nil {
+		return err
+	}
+	return pruneDir(dir, c, now)
+}
+
+// Sweep applies retention limits to the versions of every file, so that
+// versions of files that are no longer edited expire too. A snapshot
+// directory left empty is removed.
+func Sweep(c Config) error {
+	mu.Lock()
+	defer mu.Unlock()
+	return sweep(c, time.Now())
+}
+
+func sweep(c Config, now time.Time) error {
+	base, err := baseDir()
+	if err != nil {
+		return err
+	}
+	ents, err := os.ReadDir(base)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var firstErr error
+	for _, e := range ents {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(base, e.Name())
+		if err := pruneDir(dir, c, now); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		_ = os.Remove(dir) // only succeeds when empty
+	}
+	return firstErr
+}
+
+func pruneDir(dir string, c Config, now time.Time) error {
+	vs, err := listDir(dir)
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for i, v := range vs {
+		expired := c.MaxAgeDays > 0 && now.Sub(v.Time) > time.Duration(c.MaxAgeDays)*24*time.Hour
+		excess := c.MaxVersions > 0 && i >= c.MaxVersions
+		if !expired && !excess {
+			continue
+		}
+		for _, ext := range []string{".json", ".data"} {
+			if err := os.Remove(filepath.Join(dir, v.ID+ext)); err != nil && !os.IsNotExist(err) && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
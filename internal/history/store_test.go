@@ -0,0 +1,127 @@
+package history
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tu "codectl/internal/testutil"
+)
+
+func TestSnapshot_ListRestore(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+
+	root := filepath.Join(tmp, "work")
+	f := filepath.Join(root, "a.txt")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// missing file: nothing to snapshot
+	v, err := Snapshot(root, "a.txt", OpWrite)
+	if err != nil || v != nil {
+		t.Fatalf("expected nil snapshot for missing file, got %v, %v", v, err)
+	}
+
+	if err := os.WriteFile(f, []byte("one"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Snapshot(root, "a.txt", OpWrite); err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	if err := os.WriteFile(f, []byte("two"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Snapshot(root, "a.txt", OpDelete); err != nil {
+		t.Fatalf("Snapshot error: %v", err)
+	}
+	if err := os.Remove(f); err != nil {
+		t.Fatal(err)
+	}
+
+	vs, err := List(f)
+	if err != nil {
+		t.Fatalf("List error: %v", err)
+	}
+	if len(vs) != 2 || vs[0].Op != OpDelete || vs[1].Op != OpWrite {
+		t.Fatalf("unexpected versions: %+v", vs)
+	}
+
+	// restore the first write snapshot onto the deleted path
+	if _, err := Restore(root, "a.txt", vs[1].ID); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	b, err := os.ReadFile(f)
+	if err != nil || string(b) != "one" {
+		t.Fatalf("unexpected restored content: %q, %v", b, err)
+	}
+	if _, _, err := Read(f, "../x"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound for bad id, got %v", err)
+	}
+}
+
+func TestPrune_CountAndAge(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+
+	f := filepath.Join(tmp, "b.txt")
+	for _, s := range []string{"1", "2", "3", "4"} {
+		if _, err := store(f, OpWrite, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Prune(f, Config{MaxVersions: 2}); err != nil {
+		t.Fatalf("Prune error: %v", err)
+	}
+	vs, _ := List(f)
+	if len(vs) != 2 {
+		t.Fatalf("expected 2 versions after count prune, got %d", len(vs))
+	}
+	if b, _, _ := Read(f, vs[0].ID); string(b) != "4" {
+		t.Fatalf("expected newest version kept, got %q", b)
+	}
+
+	if err := prune(f, Config{MaxAgeDays: 1}, time.Now().Add(48*time.Hour)); err != nil {
+		t.Fatalf("prune error: %v", err)
+	}
+	vs, _ = List(f)
+	if len(vs) != 0 {
+		t.Fatalf("expected all versions expired, got %d", len(vs))
+	}
+}
+
+func TestSweep(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+
+	old, recent := filepath.Join(tmp, "old.txt"), filepath.Join(tmp, "recent.txt")
+	for _, f := range []string{old, recent} {
+		if _, err := store(f, OpWrite, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir, _ := fileDir(old)
+	vs, _ := List(old)
+	meta := filepath.Join(dir, vs[0].ID+".json")
+	vs[0].Time = time.Now().Add(-72 * time.Hour)
+	b, _ := json.Marshal(vs[0])
+	if err := os.WriteFile(meta, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Sweep(Config{MaxAgeDays: 2}); err != nil {
+		t.Fatalf("Sweep error: %v", err)
+	}
+	if vs, _ := List(old); len(vs) != 0 {
+		t.Fatalf("expected expired version swept, got %d", len(vs))
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("expected empty snapshot dir removed: %v", err)
+	}
+	if vs, _ := List(recent); len(vs) != 1 {
+		t.Fatalf("expected recent version kept, got %d", len(vs))
+	}
+}
@@ -0,0 +1,190 @@
+package mdhtml
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	linkRe     = regexp.MustCompile(`^!?\[((?:[^\[\]]|\[[^\[\]]*\])*)\]\(\s*<?((?:[^()\s<>]|\([^()\s<>]*\))*)>?(?:\s+"([^"]*)")?\s*\)`)
+	autoRe     = regexp.MustCompile(`^<((?:https?|mailto):[^>\s]+)>`)
+	urlRe      = regexp.MustCompile(`^https?://[^\s<>()]*[^\s<>().,;:!?。，；：！？）」』】]`)
+	specRefRe  = regexp.MustCompile(`^spec:[A-Za-z0-9][\w.\-/]*[\w](?:#[^\s)\]` + "`" + `，。；]*[^\s)\]` + "`" + `，。；.,:;!?])?`)
+	wordCharRe = regexp.MustCompile(`[\p{L}\p{N}]`)
+	schemeRe   = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.\-]*):`)
+)
+
+// inline renders inline Markdown to HTML.
+func (r *renderer) inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		rest := s[i:]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_{}[]()#+-.!|<>~", s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+		case c == '\n':
+			b.WriteString("\n")
+			i++
+			continue
+		case c == '`':
+			n := len(rest) - len(strings.TrimLeft(rest, "`"))
+			ticks := rest[:n]
+			if end := strings.Index(rest[n:], ticks); end >= 0 {
+				code := strings.TrimSpace(rest[n : n+end])
+				b.WriteString("<code>" + r.codeRef(code) + "</code>")
+				i += n + end + n
+				continue
+			}
+			b.WriteString(ticks)
+			i += n
+			continue
+		case c == '!' || c == '[':
+			if m := linkRe.FindStringSubmatch(rest); m != nil {
+				dest := r.dest(m[2])
+				title := ""
+				if m[3] != "" {
+					title = ` title="` + html.EscapeString(m[3]) + `"`
+				}
+				if c == '!' {
+					b.WriteString(`<img src="` + html.EscapeString(dest) + `" alt="` + html.EscapeString(plain(m[1])) + `"` + title + ">")
+				} else {
+					b.WriteString(`<a href="` + html.EscapeString(dest) + `"` + title + ">" + r.inline(m[1]) + "</a>")
+				}
+				i += len(m[0])
+				continue
+			}
+		case c == '<':
+			if m := autoRe.FindStringSubmatch(rest); m != nil {
+				b.WriteString(`<a href="` + html.EscapeString(m[1]) + `">` + html.EscapeString(m[1]) + "</a>")
+				i += len(m[0])
+				continue
+			}
+		case c == 'h':
+			if m := urlRe.FindString(rest); m != "" && (i == 0 || !wordCharRe.MatchString(s[i-1:i])) {
+				b.WriteString(`<a href="` + html.EscapeString(m) + `">` + html.EscapeString(m) + "</a>")
+				i += len(m)
+				continue
+			}
+		case c == 's':
+			if m := specRefRe.FindString(rest); m != "" && (i == 0 || !wordCharRe.MatchString(s[i-1:i])) && r.opt.SpecRef != nil {
+				if href, ok := r.opt.SpecRef(m); ok {
+					b.WriteString(`<a href="` + html.EscapeString(href) + `">` + html.EscapeString(m) + "</a>")
+					i += len(m)
+					continue
+				}
+			}
+		case c == '*' || c == '_' || c == '~':
+			if out, n, ok := r.emphasis(s, i); ok {
+				b.WriteString(out)
+				i += n
+				continue
+			}
+		}
+		switch c {
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '&':
+			b.WriteString("&amp;")
+		case '"':
+			b.WriteString("&#34;")
+		case '\'':
+			b.WriteString("&#39;")
+		default:
+			b.WriteByte(c) // bytes of multi-byte runes are copied as is
+		}
+		i++
+	}
+	return b.String()
+}
+
+// emphasis renders **strong**, *em*, __strong__, _em_ and ~~del~~ at s[i].
+func (r *renderer) emphasis(s string, i int) (string, int, bool) {
+	for _, d := range []struct{ delim, tag string }{
+		{"**", "strong"}, {"__", "strong"}, {"~~", "del"}, {"*", "em"}, {"_", "em"},
+	} {
+		if !strings.HasPrefix(s[i:], d.delim) {
+			continue
+		}
+		open := i + len(d.delim)
+		if open >= len(s) || s[open] == ' ' || s[open] == '\n' {
+			continue
+		}
+		if d.delim[0] == '_' && i > 0 && wordCharRe.MatchString(s[i-1:i]) {
+			continue // intra-word underscore (snake_case)
+		}
+		end := strings.Index(s[open:], d.delim)
+		if end <= 0 {
+			continue
+		}
+		close := open + end
+		if s[close-1] == ' ' {
+			continue
+		}
+		if d.delim[0] == '_' && close+len(d.delim) < len(s) && wordCharRe.MatchString(s[close+len(d.delim):close+len(d.delim)+1]) {
+			continue
+		}
+		return "<" + d.tag + ">" + r.inline(s[open:close]) + "</" + d.tag + ">", close + len(d.delim) - i, true
+	}
+	return "", 0, false
+}
+
+// codeRef renders a code span, linking document paths and spec: references
+// that resolve.
+func (r *renderer) codeRef(code string) string {
+	esc := html.EscapeString(code)
+	if r.opt.SpecRef != nil && specRefRe.MatchString(code) && len(specRefRe.FindString(code)) == len(code) {
+		if href, ok := r.opt.SpecRef(code); ok {
+			return `<a href="` + html.EscapeString(SafeURL(href)) + `">` + esc + "</a>"
+		}
+	}
+	if r.opt.Link != nil && (strings.HasPrefix(code, "./") || strings.HasPrefix(code, "../")) &&
+		(strings.Contains(code, ".spec.mdx") || strings.Contains(code, ".task.mdx")) && !strings.ContainsAny(code, " \t") {
+		if href := r.opt.Link(code); href != code {
+			return `<a href="` + html.EscapeString(SafeURL(href)) + `">` + esc + "</a>"
+		}
+	}
+	return esc
+}
+
+func (r *renderer) dest(d string) string {
+	if r.opt.Link != nil {
+		d = r.opt.Link(d)
+	}
+	return SafeURL(d)
+}
+
+// SafeURL returns u if it is relative or uses the http, https or mailto
+// scheme, and "#" otherwise, so that javascript:, data: and similar
+// destinations never reach an href or src. Browsers ignore control
+// characters and spaces around and inside a scheme, so those are dropped
+// before looking at it.
+func SafeURL(u string) string {
+	s := strings.Map(func(c rune) rune {
+		if c <= ' ' || c == 0x7f {
+			return -1
+		}
+		return c
+	}, u)
+	m := schemeRe.FindStringSubmatch(s)
+	if m == nil {
+		return u
+	}
+	switch strings.ToLower(m[1]) {
+	case "http", "https", "mailto":
+		return u
+	}
+	return "#"
+}
+
+var plainRe = regexp.MustCompile("[`*_~]|\\[|\\]\\((?:[^()]|\\([^()]*\\))*\\)|\\]")
+
+// plain strips inline markup from heading or alt text.
+func plain(s string) string {
+	return strings.TrimSpace(plainRe.ReplaceAllString(s, ""))
+}
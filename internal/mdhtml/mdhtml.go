@@ -0,0 +1,341 @@
+// Package mdhtml renders the Markdown subset used by vibe-docs to HTML:
+// ATX headings with anchors, paragraphs, nested lists, task items, block
+// quotes, fenced code, GFM tables, rules, and inline code, emphasis, links,
+// images and autolinks. Raw HTML/JSX is escaped, never passed through.
+package mdhtml
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Options customize rendering.
+type Options struct {
+	// Slug returns the anchor of a heading text; headings get no id when nil.
+	// Repeated anchors are suffixed "-1", "-2", ... like GitHub.
+	Slug func(text string) string
+	// Link rewrites link and image destinations; nil keeps them.
+	Link func(dest string) string
+	// SpecRef resolves a "spec:name#anchor" reference in text to a URL;
+	// unresolved references stay plain text.
+	SpecRef func(ref string) (href string, ok bool)
+}
+
+// Heading is a rendered heading.
+type Heading struct {
+	Level int
+	Text  string // plain text
+	ID    string
+}
+
+// Render converts Markdown src to HTML and returns its headings.
+func Render(src string, opt Options) (string, []Heading) {
+	r := &renderer{opt: opt, seen: map[string]int{}}
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i, ln := range lines {
+		lines[i] = strings.ReplaceAll(ln, "\t", "    ")
+	}
+	r.blocks(lines, false)
+	return r.b.String(), r.heads
+}
+
+type renderer struct {
+	opt   Options
+	b     strings.Builder
+	seen  map[string]int
+	heads []Heading
+}
+
+var (
+	fenceRe    = regexp.MustCompile("^(\\s{0,3})(```+|~~~+)\\s*([^\\s`]*)")
+	headRe     = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	hrRe       = regexp.MustCompile(`^\s{0,3}([-*_])(?:\s*[-*_]){2,}\s*$`)
+	itemRe     = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])(\s+|$)(.*)$`)
+	taskRe     = regexp.MustCompile(`^\[([ xX])\]\s+`)
+	quoteRe    = regexp.MustCompile(`^\s{0,3}>\s?`)
+	tableSepRe = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+func indentOf(s string) int { return len(s) - len(strings.TrimLeft(s, " ")) }
+
+func blank(s string) bool { return strings.TrimSpace(s) == "" }
+
+// startsBlock reports whether ln interrupts a paragraph.
+func startsBlock(ln string) bool {
+	return fenceRe.MatchString(ln) || headRe.MatchString(ln) || hrRe.MatchString(ln) ||
+		quoteRe.MatchString(ln) || itemRe.MatchString(ln)
+}
+
+// blocks renders lines; tight renders bare paragraphs without <p> (list items).
+func (r *renderer) blocks(lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		ln := lines[i]
+		switch {
+		case blank(ln):
+			i++
+		case fenceRe.MatchString(ln):
+			i = r.fence(lines, i)
+		case headRe.MatchString(ln):
+			m := headRe.FindStringSubmatch(ln)
+			r.heading(len(m[1]), m[2])
+			i++
+		case hrRe.MatchString(ln):
+			r.b.WriteString("<hr>\n")
+			i++
+		case quoteRe.MatchString(ln):
+			var inner []string
+			for ; i < len(lines) && !blank(lines[i]); i++ {
+				inner = append(inner, quoteRe.ReplaceAllString(lines[i], ""))
+			}
+			r.b.WriteString("<blockquote>\n")
+			r.blocks(inner, false)
+			r.b.WriteString("</blockquote>\n")
+		case strings.Contains(ln, "|") && i+1 < len(lines) && tableSepRe.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			i = r.table(lines, i)
+		case itemRe.MatchString(ln):
+			i = r.list(lines, i)
+		default:
+			var para []string
+			for ; i < len(lines) && !blank(lines[i]) && (len(para) == 0 || !startsBlock(lines[i])); i++ {
+				para = append(para, strings.TrimSpace(lines[i]))
+			}
+			text := r.inline(strings.Join(para, "\n"))
+			if tight {
+				r.b.WriteString(text + "\n")
+			} else {
+				r.b.WriteString("<p>" + text + "</p>\n")
+			}
+		}
+	}
+}
+
+func (r *renderer) fence(lines []string, i int) int {
+	m := fenceRe.FindStringSubmatch(lines[i])
+	indent, marker, lang := len(m[1]), m[2], m[3]
+	var code []string
+	j := i + 1
+	for ; j < len(lines); j++ {
+		t := strings.TrimSpace(lines[j])
+		if strings.HasPrefix(t, marker) && strings.Trim(t, marker[:1]) == "" {
+			j++
+			break
+		}
+		ln := lines[j]
+		if n := min(indent, indentOf(ln)); n > 0 {
+			ln = ln[n:]
+		}
+		code = append(code, ln)
+	}
+	if lang != "" {
+		r.b.WriteString(`<pre><code class="language-` + html.EscapeString(lang) + `">`)
+	} else {
+		r.b.WriteString("<pre><code>")
+	}
+	r.b.WriteString(html.EscapeString(strings.Join(code, "\n")))
+	if len(code) > 0 {
+		r.b.WriteString("\n")
+	}
+	r.b.WriteString("</code></pre>\n")
+	return j
+}
+
+func (r *renderer) heading(level int, text string) {
+	h := Heading{Level: level, Text: plain(text)}
+	if r.opt.Slug != nil {
+		slug := r.opt.Slug(text)
+		h.ID = slug
+		if n := r.seen[slug]; n > 0 {
+			h.ID = slug + "-" + strconv.Itoa(n)
+		}
+		r.seen[slug]++
+	}
+	r.heads = append(r.heads, h)
+	tag := "h" + strconv.Itoa(level)
+	if h.ID != "" {
+		id := html.EscapeString(h.ID)
+		r.b.WriteString("<" + tag + ` id="` + id + `">` + r.inline(text) + ` <a class="anchor" href="#` + id + `">#</a></` + tag + ">\n")
+		return
+	}
+	r.b.WriteString("<" + tag + ">" + r.inline(text) + "</" + tag + ">\n")
+}
+
+func splitRow(ln string) []string {
+	ln = strings.TrimSpace(ln)
+	ln = strings.TrimPrefix(ln, "|")
+	ln = strings.TrimSuffix(ln, "|")
+	var cells []string
+	var cur strings.Builder
+	inCode := false
+	for i := 0; i < len(ln); i++ {
+		c := ln[i]
+		switch {
+		case c == '\\' && i+1 < len(ln) && ln[i+1] == '|':
+			cur.WriteByte('|')
+			i++
+		case c == '`':
+			inCode = !inCode
+			cur.WriteByte(c)
+		case c == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	return append(cells, strings.TrimSpace(cur.String()))
+}
+
+func (r *renderer) table(lines []string, i int) int {
+	head := splitRow(lines[i])
+	var align []string
+	for _, c := range splitRow(lines[i+1]) {
+		switch {
+		case strings.HasPrefix(c, ":") && strings.HasSuffix(c, ":"):
+			align = append(align, "center")
+		case strings.HasSuffix(c, ":"):
+			align = append(align, "right")
+		case strings.HasPrefix(c, ":"):
+			align = append(align, "left")
+		default:
+			align = append(align, "")
+		}
+	}
+	cell := func(tag string, k int, s string) {
+		if k < len(align) && align[k] != "" {
+			r.b.WriteString("<" + tag + ` style="text-align:` + align[k] + `">`)
+		} else {
+			r.b.WriteString("<" + tag + ">")
+		}
+		r.b.WriteString(r.inline(s) + "</" + tag + ">")
+	}
+	r.b.WriteString("<table>\n<thead><tr>")
+	for k, c := range head {
+		cell("th", k, c)
+	}
+	r.b.WriteString("</tr></thead>\n<tbody>\n")
+	j := i + 2
+	for ; j < len(lines) && !blank(lines[j]) && strings.Contains(lines[j], "|"); j++ {
+		r.b.WriteString("<tr>")
+		for k, c := range splitRow(lines[j]) {
+			if k >= len(head) {
+				break
+			}
+			cell("td", k, c)
+		}
+		r.b.WriteString("</tr>\n")
+	}
+	r.b.WriteString("</tbody>\n</table>\n")
+	return j
+}
+
+type item struct {
+	lines []string
+	task  string // "", " " or "x"
+}
+
+func (r *renderer) list(lines []string, i int) int {
+	m := itemRe.FindStringSubmatch(lines[i])
+	base := len(m[1])
+	ordered := m[2][0] >= '0' && m[2][0] <= '9'
+	start := 0
+	if ordered {
+		start, _ = strconv.Atoi(strings.TrimRight(m[2], ".)"))
+	}
+	var items []item
+	tight := true
+	j := i
+	for j < len(lines) {
+		m := itemRe.FindStringSubmatch(lines[j])
+		if m == nil || len(m[1]) != base || (m[2][0] >= '0' && m[2][0] <= '9') != ordered {
+			break
+		}
+		content := base + len(m[2]) + len(m[3])
+		if m[3] == "" || len(m[3]) > 4 {
+			content = base + len(m[2]) + 1
+		}
+		it := item{lines: []string{m[4]}}
+		if tm := taskRe.FindStringSubmatch(m[4]); tm != nil {
+			it.task = strings.ToLower(tm[1])
+			it.lines[0] = m[4][len(tm[0]):]
+		}
+		j++
+		for j < len(lines) {
+			ln := lines[j]
+			if blank(ln) {
+				// a blank line continues the item only when indented content follows
+				k := j
+				for k < len(lines) && blank(lines[k]) {
+					k++
+				}
+				if k < len(lines) && indentOf(lines[k]) >= content {
+					it.lines = append(it.lines, "")
+					tight = false
+					j = k
+					continue
+				}
+				if k < len(lines) {
+					if nm := itemRe.FindStringSubmatch(lines[k]); nm != nil && len(nm[1]) == base {
+						tight = false
+					}
+				}
+				break
+			}
+			if indentOf(ln) >= content {
+				it.lines = append(it.lines, ln[content:])
+				j++
+				continue
+			}
+			if nm := itemRe.FindStringSubmatch(ln); nm != nil && len(nm[1]) > base {
+				// a nested item indented less than the content column
+				it.lines = append(it.lines, ln[len(nm[1]):])
+				j++
+				continue
+			}
+			if startsBlock(ln) {
+				break
+			}
+			// lazy paragraph continuation
+			it.lines = append(it.lines, strings.TrimSpace(ln))
+			j++
+		}
+		items = append(items, it)
+		// skip blank lines between sibling items
+		k := j
+		for k < len(lines) && blank(lines[k]) {
+			k++
+		}
+		if k > j && k < len(lines) {
+			if nm := itemRe.FindStringSubmatch(lines[k]); nm != nil && len(nm[1]) == base {
+				j = k
+			}
+		}
+	}
+	switch {
+	case ordered && start != 1:
+		r.b.WriteString(`<ol start="` + strconv.Itoa(start) + `">` + "\n")
+	case ordered:
+		r.b.WriteString("<ol>\n")
+	default:
+		r.b.WriteString("<ul>\n")
+	}
+	for _, it := range items {
+		switch it.task {
+		case "x":
+			r.b.WriteString(`<li class="task"><input type="checkbox" checked disabled> `)
+		case " ":
+			r.b.WriteString(`<li class="task"><input type="checkbox" disabled> `)
+		default:
+			r.b.WriteString("<li>")
+		}
+		r.blocks(it.lines, tight)
+		r.b.WriteString("</li>\n")
+	}
+	if ordered {
+		r.b.WriteString("</ol>\n")
+	} else {
+		r.b.WriteString("</ul>\n")
+	}
+	return j
+}
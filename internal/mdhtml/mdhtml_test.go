@@ -0,0 +1,95 @@
+package mdhtml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	src := "# 标题 One\n\n段落 **粗体** 与 `code` 和 [链接](./a.spec.mdx#x)。\n\n" +
+		"- a\n- [x] done\n  - nested_item\n1. one\n\n" +
+		"> quote\n\n" +
+		"| A | B |\n|---|:-:|\n| 1 | x \\| y |\n\n" +
+		"```json\n{\"a\": \"<b>\"}\n```\n\n## 标题 One\n---\n<Component />\n"
+	out, heads := Render(src, Options{
+		Slug: func(s string) string { return strings.ToLower(strings.ReplaceAll(s, " ", "-")) },
+		Link: func(d string) string { return strings.Replace(d, ".spec.mdx", ".spec.html", 1) },
+	})
+	for _, want := range []string{
+		`<h1 id="标题-one">标题 One <a class="anchor" href="#标题-one">#</a></h1>`,
+		`<p>段落 <strong>粗体</strong> 与 <code>code</code> 和 <a href="./a.spec.html#x">链接</a>。</p>`,
+		"<ul>\n<li>a\n</li>\n<li class=\"task\"><input type=\"checkbox\" checked disabled> done\n<ul>\n<li>nested_item\n</li>\n</ul>\n</li>\n</ul>\n<ol>\n<li>one\n</li>\n</ol>",
+		"<blockquote>\n<p>quote</p>\n</blockquote>",
+		`<th style="text-align:center">B</th>`,
+		`<td style="text-align:center">x | y</td>`,
+		`<pre><code class="language-json">{&#34;a&#34;: &#34;&lt;b&gt;&#34;}` + "\n</code></pre>",
+		`<h2 id="标题-one-1">`,
+		"<hr>",
+		"<p>&lt;Component /&gt;</p>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if len(heads) != 2 || heads[1].ID != "标题-one-1" || heads[0].Text != "标题 One" {
+		t.Fatalf("headings = %+v", heads)
+	}
+}
+
+func TestInline_SpecRef(t *testing.T) {
+	out, _ := Render("see spec:400#7 and spec:nope, https://example.com/x.", Options{
+		SpecRef: func(ref string) (string, bool) {
+			if ref == "spec:400#7" {
+				return "400-spec.spec.html#7", true
+			}
+			return "", false
+		},
+	})
+	want := `<p>see <a href="400-spec.spec.html#7">spec:400#7</a> and spec:nope, <a href="https://example.com/x">https://example.com/x</a>.</p>` + "\n"
+	if out != want {
+		t.Fatalf("got  %q\nwant %q", out, want)
+	}
+}
+
+func TestInline_LinkDestinations(t *testing.T) {
+	cases := map[string]string{
+		"[x](javascript:alert(document.cookie))": `<a href="#">x</a>`,
+		"[x](JavaScript:alert(1))":               `<a href="#">x</a>`,
+		"![x](data:image/svg+xml,evil)":          `<img src="#" alt="x">`,
+		"[x](vbscript:msgbox)":                   `<a href="#">x</a>`,
+		"[x](https://e.com/a_(b))":               `<a href="https://e.com/a_(b)">x</a>`,
+		"[x](mailto:a@b.c)":                      `<a href="mailto:a@b.c">x</a>`,
+		"[x](./a(1).md \"t\")":                   `<a href="./a(1).md" title="t">x</a>`,
+		"[x](/abs/path?q=1#h)":                   `<a href="/abs/path?q=1#h">x</a>`,
+	}
+	for src, want := range cases {
+		out, _ := Render(src, Options{})
+		if got := strings.TrimSuffix(strings.TrimPrefix(out, "<p>"), "</p>\n"); got != want {
+			t.Errorf("%s:\ngot  %q\nwant %q", src, got, want)
+		}
+	}
+	// A Link callback cannot reintroduce an unsafe scheme either.
+	out, _ := Render("[x](a.md)", Options{Link: func(string) string { return "javascript:void(0)" }})
+	if !strings.Contains(out, `href="#"`) {
+		t.Errorf("unsafe Link result kept: %s", out)
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	for u, want := range map[string]string{
+		"https://x.org":       "https://x.org",
+		"HTTP://x.org":        "HTTP://x.org",
+		"a/b.md#c":            "a/b.md#c",
+		"#top":                "#top",
+		"":                    "",
+		"java\tscript:x":      "#",
+		" javascript:x":       "#",
+		"file:///etc/passwd":  "#",
+		"./javascript:x":      "./javascript:x",
+		"mailto:me@host.test": "mailto:me@host.test",
+	} {
+		if got := SafeURL(u); got != want {
+			t.Errorf("SafeURL(%q) = %q, want %q", u, got, want)
+		}
+	}
+}
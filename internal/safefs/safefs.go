@@ -0,0 +1,134 @@
+// Package safefs performs file operations confined to a root directory.
+//
+// Every call opens the root with os.OpenRoot and resolves the relative path
+// component by component beneath it (openat with O_NOFOLLOW on Unix), so
+// symlinks, including ones swapped in after a path was validated, cannot
+// lead outside the root. Symlinks that stay within the root are followed.
+package safefs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrEscape is returned for paths that are absolute or leave the root lexically.
+var ErrEscape = errors.New("path escapes base")
+
+// Clean validates rel and returns it in clean, OS-specific form.
+func Clean(rel string) (string, error) {
+	if filepath.IsAbs(rel) || strings.HasPrefix(rel, "/") || strings.HasPrefix(rel, `\`) {
+		return "", errors.New("absolute path not allowed")
+	}
+	c := filepath.Clean(filepath.FromSlash(rel))
+	if c == ".." || strings.HasPrefix(c, ".."+string(os.PathSeparator)) {
+		return "", ErrEscape
+	}
+	return c, nil
+}
+
+func withRoot(root string, fn func(r *os.Root) error) error {
+	r, err := os.OpenRoot(root)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return fn(r)
+}
+
+// Open opens rel for reading.
+func Open(root, rel string) (*os.File, error) {
+	c, err := Clean(rel)
+	if err != nil {
+		return nil, err
+	}
+	var f *os.File
+	err = withRoot(root, func(r *os.Root) error {
+		var oerr error
+		f, oerr = r.Open(c)
+		return oerr
+	})
+	return f, err
+}
+
+// ReadFile reads the whole of rel.
+func ReadFile(root, rel string) ([]byte, error) {
+	c, err := Clean(rel)
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	err = withRoot(root, func(r *os.Root) error {
+		var rerr error
+		b, rerr = r.ReadFile(c)
+		return rerr
+	})
+	return b, err
+}
+
+// Lstat returns file info for rel without following a final symlink.
+func Lstat(root, rel string) (os.FileInfo, error) {
+	c, err := Clean(rel)
+	if err != nil {
+		return nil, err
+	}
+	var st os.FileInfo
+	err = withRoot(root, func(r *os.Root) error {
+		var serr error
+		st, serr = r.Lstat(c)
+		return serr
+	})
+	return st, err
+}
+
+// WriteFile writes data to rel, creating parent directories as needed.
+func WriteFile(root, rel string, data []byte, perm os.FileMode) error {
+	c, err := Clean(rel)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return err
+	}
+	return withRoot(root, func(r *os.Root) error {
+		if d := filepath.Dir(c); d != "." {
+			if err := r.MkdirAll(d, 0o755); err != nil {
+				return err
+			}
+		}
+		return r.WriteFile(c, data, perm)
+	})
+}
+
+// Rename moves oldRel to newRel, creating the target's parent directories.
+func Rename(root, oldRel, newRel string) error {
+	oc, err := Clean(oldRel)
+	if err != nil {
+		return err
+	}
+	nc, err := Clean(newRel)
+	if err != nil {
+		return err
+	}
+	return withRoot(root, func(r *os.Root) error {
+		if d := filepath.Dir(nc); d != "." {
+			if err := r.MkdirAll(d, 0o755); err != nil {
+				return err
+			}
+		}
+		return r.Rename(oc, nc)
+	})
+}
+
+// Remove deletes rel (a file or an empty directory). A symlink is removed itself.
+func Remove(root, rel string) error {
+	c, err := Clean(rel)
+	if err != nil {
+		return err
+	}
+	if c == "." {
+		return errors.New("cannot remove base")
+	}
+	return withRoot(root, func(r *os.Root) error { return r.Remove(c) })
+}
@@ -0,0 +1,109 @@
+package safefs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// setup creates <tmp>/repo with a file, plus <tmp>/outside/secret.txt, and
+// symlinks inside repo that point outside it.
+func setup(t *testing.T) (repo, outside string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+	tmp := t.TempDir()
+	repo = filepath.Join(tmp, "repo")
+	outside = filepath.Join(tmp, "outside")
+	for _, d := range []string{filepath.Join(repo, "docs"), outside} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(repo, "docs", "a.md"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"outdir":      outside,
+		"secret-link": filepath.Join(outside, "secret.txt"),
+		"dangling":    filepath.Join(outside, "new.txt"),
+		"inner":       "docs",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(repo, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo, outside
+}
+
+func TestClean_RejectsEscapes(t *testing.T) {
+	for _, p := range []string{"/etc/passwd", "..", "../x", "a/../../x"} {
+		if _, err := Clean(p); err == nil {
+			t.Fatalf("expected error for %q", p)
+		}
+	}
+	if c, err := Clean("a/./b/../c"); err != nil || c != filepath.FromSlash("a/c") {
+		t.Fatalf("unexpected clean: %q, %v", c, err)
+	}
+}
+
+func TestSymlinksCannotEscape(t *testing.T) {
+	repo, outside := setup(t)
+
+	if _, err := ReadFile(repo, "secret-link"); err == nil {
+		t.Fatalf("read through file symlink escaped root")
+	}
+	if _, err := ReadFile(repo, "outdir/secret.txt"); err == nil {
+		t.Fatalf("read through dir symlink escaped root")
+	}
+	// not-yet-existing file below a symlinked directory
+	if err := WriteFile(repo, "outdir/new.txt", []byte("x"), 0o644); err == nil {
+		t.Fatalf("write below dir symlink escaped root")
+	}
+	// dangling symlink whose target is outside
+	if err := WriteFile(repo, "dangling", []byte("x"), 0o644); err == nil {
+		t.Fatalf("write through dangling symlink escaped root")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); !os.IsNotExist(err) {
+		t.Fatalf("file was created outside root")
+	}
+	if err := WriteFile(repo, "secret-link", []byte("pwned"), 0o644); err == nil {
+		t.Fatalf("overwrite through file symlink escaped root")
+	}
+	if err := Rename(repo, "docs/a.md", "outdir/a.md"); err == nil {
+		t.Fatalf("rename into dir symlink escaped root")
+	}
+	if err := Remove(repo, "outdir/secret.txt"); err == nil {
+		t.Fatalf("remove through dir symlink escaped root")
+	}
+	if b, err := os.ReadFile(filepath.Join(outside, "secret.txt")); err != nil || string(b) != "secret" {
+		t.Fatalf("outside file modified: %q, %v", b, err)
+	}
+}
+
+func TestSymlinksWithinRoot(t *testing.T) {
+	repo, _ := setup(t)
+
+	if b, err := ReadFile(repo, "inner/a.md"); err != nil || string(b) != "a" {
+		t.Fatalf("read via inner symlink: %q, %v", b, err)
+	}
+	if err := WriteFile(repo, "docs/sub/b.md", []byte("b"), 0o644); err != nil {
+		t.Fatalf("write with parent creation: %v", err)
+	}
+	if err := Rename(repo, "docs/sub/b.md", "docs/c/b.md"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	// removing a symlink removes the link, not its target
+	if err := Remove(repo, "secret-link"); err != nil {
+		t.Fatalf("remove link: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(repo, "secret-link")); !os.IsNotExist(err) {
+		t.Fatalf("link still present")
+	}
+}
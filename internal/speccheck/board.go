@@ -0,0 +1,90 @@
+package speccheck
+
+import "strings"
+
+// BoardRule configures the task board: columns mapped to status values, each
+// with an optional work-in-progress limit, and the statuses of closed tasks.
+type BoardRule struct {
+	Columns []BoardColumn `json:"columns,omitempty"`
+	// Closed are the statuses of finished tasks, which no longer block the
+	// tasks depending on them.
+	Closed []string `json:"closed,omitempty"`
+}
+
+// BoardColumn is one column of the task board.
+type BoardColumn struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+	// Statuses are the status values shown in the column; the first one is
+	// written to tasks moved into it (default: ID).
+	Statuses []string `json:"statuses,omitempty"`
+	// WIP limits the number of tasks in the column; 0 means no limit.
+	WIP int `json:"wip,omitempty"`
+}
+
+// DefaultStatuses are the board columns used when neither columns nor a
+// status enum are configured.
+var DefaultStatuses = []string{"todo", "in-progress", "done"}
+
+// DefaultClosed are the closed statuses used when neither closed statuses
+// nor columns are configured, as far as the status enum allows them.
+var DefaultClosed = []string{"done", "canceled"}
+
+// Effective fills unset parts of b: without columns there is one column per
+// value of statuses (the status enum), or per DefaultStatuses when that is
+// empty; columns without statuses show the status named by their ID.
+// Without closed statuses, configured columns close tasks in their last
+// column; otherwise the values of DefaultClosed in statuses, or its last
+// value, are closed.
+func (b BoardRule) Effective(statuses []string) BoardRule {
+	var cols []BoardColumn
+	if len(b.Columns) == 0 {
+		if len(statuses) == 0 {
+			statuses = DefaultStatuses
+		}
+		cols = make([]BoardColumn, 0, len(statuses))
+		for _, s := range statuses {
+			cols = append(cols, BoardColumn{ID: s, Title: s, Statuses: []string{s}})
+		}
+	} else {
+		cols = make([]BoardColumn, len(b.Columns))
+		for i, c := range b.Columns {
+			if len(c.Statuses) == 0 {
+				c.Statuses = []string{c.ID}
+			}
+			if c.Title == "" {
+				c.Title = c.ID
+			}
+			cols[i] = c
+		}
+	}
+	closed := b.Closed
+	switch {
+	case len(closed) > 0:
+	case len(b.Columns) > 0:
+		closed = cols[len(cols)-1].Statuses
+	default:
+		for _, d := range DefaultClosed {
+			for _, s := range statuses {
+				if strings.EqualFold(s, d) {
+					closed = append(closed, s)
+				}
+			}
+		}
+		if len(closed) == 0 {
+			closed = statuses[len(statuses)-1:]
+		}
+	}
+	return BoardRule{Columns: cols, Closed: closed}
+}
+
+// IsClosed reports whether status is one of the closed statuses of the
+// effective rule, ignoring case.
+func (b BoardRule) IsClosed(status string) bool {
+	for _, s := range b.Closed {
+		if strings.EqualFold(s, status) {
+			return true
+		}
+	}
+	return false
+}
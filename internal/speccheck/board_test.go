@@ -0,0 +1,30 @@
+package speccheck
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBoardEffectiveClosed(t *testing.T) {
+	cases := []struct {
+		name     string
+		board    BoardRule
+		statuses []string
+		want     []string
+	}{
+		{"default", BoardRule{}, nil, []string{"done"}},
+		{"enum", BoardRule{}, []string{"todo", "Done", "canceled"}, []string{"Done", "canceled"}},
+		{"enum without defaults", BoardRule{}, []string{"open", "closed"}, []string{"closed"}},
+		{"last column", BoardRule{Columns: []BoardColumn{{ID: "todo"}, {ID: "end", Statuses: []string{"shipped", "dropped"}}}}, nil, []string{"shipped", "dropped"}},
+		{"configured", BoardRule{Columns: []BoardColumn{{ID: "todo"}, {ID: "done"}}, Closed: []string{"done", "wontfix"}}, nil, []string{"done", "wontfix"}},
+	}
+	for _, c := range cases {
+		b := c.board.Effective(c.statuses)
+		if !reflect.DeepEqual(b.Closed, c.want) {
+			t.Errorf("%s: closed = %v, want %v", c.name, b.Closed, c.want)
+		}
+		if !b.IsClosed(c.want[0]) || b.IsClosed("todo") {
+			t.Errorf("%s: IsClosed wrong", c.name)
+		}
+	}
+}
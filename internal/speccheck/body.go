@@ -0,0 +1,209 @@
+package speccheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"codectl/internal/document"
+)
+
+// BodyRule configures structural lint of the Markdown body.
+type BodyRule struct {
+	// Sections lists required sections as case-insensitive regular
+	// expressions matched against heading text without its section number
+	// (e.g. "^(目标与非目标|goals)").
+	Sections []string `json:"sections,omitempty"`
+	// HeadingOrder reports headings that skip a level (## followed by ####).
+	HeadingOrder bool `json:"headingOrder,omitempty"`
+	// SingleH1 requires exactly one level-1 heading that starts with the
+	// frontmatter title, ignoring case (a suffix such as "（草案）" is allowed).
+	SingleH1 bool `json:"singleH1,omitempty"`
+	// NoEmptySections reports headings without content, counting template
+	// placeholders such as "- " as empty.
+	NoEmptySections bool `json:"noEmptySections,omitempty"`
+	// MaxLineLength limits prose lines in characters; 0 disables the check.
+	// Code blocks, tables, headings and link-only lines are exempt.
+	MaxLineLength int `json:"maxLineLength,omitempty"`
+	// CodeBlocks requires fenced blocks declared as json or yaml to parse.
+	CodeBlocks bool `json:"codeBlocks,omitempty"`
+	// Severity of body findings (default "warning").
+	Severity string `json:"severity,omitempty"`
+}
+
+func (b BodyRule) isZero() bool {
+	return len(b.Sections) == 0 && !b.HeadingOrder && !b.SingleH1 && !b.NoEmptySections &&
+		b.MaxLineLength == 0 && !b.CodeBlocks && b.Severity == ""
+}
+
+var (
+	bodyFenceRe   = regexp.MustCompile("^\\s{0,3}(```+|~~~+)\\s*([^\\s`]*)")
+	bodyHeadRe    = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	sectionNumRe  = regexp.MustCompile(`^\d+(?:\.\d+)*\.?\s*`)
+	placeholderRe = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])?\s*(?:\[[ xX]\])?\s*$`)
+	linkOnlyRe    = regexp.MustCompile(`^\s*(?:[-*+]\s+)?(?:\[[^\]]*\]\([^)]*\)|<?https?://\S+>?)\s*$`)
+)
+
+type heading struct {
+	level int
+	text  string
+	line  int // file line
+	idx   int // body line index
+}
+
+type fence struct {
+	lang  string
+	line  int // file line of the opening fence
+	start int // body line index of the first content line
+	end   int // body line index of the closing fence (len(lines) if open)
+}
+
+// scanBody splits the body into headings and fenced blocks.
+func scanBody(lines []string, first int) (heads []heading, fences []fence, inCode []bool) {
+	inCode = make([]bool, len(lines))
+	var open *fence
+	marker := ""
+	for i, ln := range lines {
+		if m := bodyFenceRe.FindStringSubmatch(ln); m != nil {
+			if open == nil {
+				open = &fence{lang: strings.ToLower(m[2]), line: first + i, start: i + 1}
+				marker = m[1]
+				inCode[i] = true
+				continue
+			}
+			if strings.HasPrefix(m[1], marker[:1]) && len(m[1]) >= len(marker) && m[2] == "" {
+				open.end = i
+				fences = append(fences, *open)
+				open = nil
+				inCode[i] = true
+				continue
+			}
+		}
+		if open != nil {
+			inCode[i] = true
+			continue
+		}
+		if m := bodyHeadRe.FindStringSubmatch(ln); m != nil {
+			heads = append(heads, heading{level: len(m[1]), text: m[2], line: first + i, idx: i})
+		}
+	}
+	if open != nil {
+		open.end = len(lines)
+		fences = append(fences, *open)
+	}
+	return heads, fences, inCode
+}
+
+func checkBody(eff *Rules, doc *document.Document, res *Result) {
+	br := eff.Body
+	if br.isZero() {
+		return
+	}
+	sev := severityOr(br.Severity, SeverityWarning)
+	first := doc.BodyLine()
+	lines := strings.Split(strings.ReplaceAll(doc.Body, "\r\n", "\n"), "\n")
+	heads, fences, inCode := scanBody(lines, first)
+	at := func(line int) document.Pos { return document.Pos{Line: line, Col: 1} }
+
+	for i, pat := range br.Sections {
+		re := eff.sections[i]
+		found := false
+		for _, h := range heads {
+			if h.level > 1 && re.MatchString(sectionNumRe.ReplaceAllString(h.text, "")) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			res.add(RuleRequiredSection, sev, "", top, fmt.Sprintf("missing required section matching '%s'", pat))
+		}
+	}
+
+	if br.SingleH1 {
+		var h1 []heading
+		for _, h := range heads {
+			if h.level == 1 {
+				h1 = append(h1, h)
+			}
+		}
+		switch {
+		case len(h1) == 0:
+			res.add(RuleH1, sev, "", at(first), "document has no level-1 heading")
+		default:
+			title := strings.TrimSpace(doc.String("title"))
+			if title != "" && !strings.HasPrefix(strings.ToLower(strings.TrimSpace(h1[0].text)), strings.ToLower(title)) {
+				res.add(RuleH1, sev, "", at(h1[0].line), fmt.Sprintf("level-1 heading '%s' does not match title '%s'", h1[0].text, title))
+			}
+			for _, h := range h1[1:] {
+				res.add(RuleH1, sev, "", at(h.line), fmt.Sprintf("extra level-1 heading '%s' (only one is allowed)", h.text))
+			}
+		}
+	}
+
+	if br.HeadingOrder {
+		prev := 0
+		for _, h := range heads {
+			if prev > 0 && h.level > prev+1 {
+				res.add(RuleHeadingOrder, sev, "", at(h.line), fmt.Sprintf("heading '%s' skips from level %d to %d", h.text, prev, h.level))
+			}
+			prev = h.level
+		}
+	}
+
+	if br.NoEmptySections {
+		for i, h := range heads {
+			end, next := len(lines), 0
+			if i+1 < len(heads) {
+				end, next = heads[i+1].idx, heads[i+1].level
+			}
+			if next > h.level {
+				continue // content lives in subsections
+			}
+			empty := true
+			for j := h.idx + 1; j < end; j++ {
+				if inCode[j] || !placeholderRe.MatchString(lines[j]) {
+					empty = false
+					break
+				}
+			}
+			if empty {
+				res.add(RuleEmptySection, sev, "", at(h.line), fmt.Sprintf("section '%s' is empty", h.text))
+			}
+		}
+	}
+
+	if max := br.MaxLineLength; max > 0 {
+		for i, ln := range lines {
+			if inCode[i] || bodyHeadRe.MatchString(ln) || strings.HasPrefix(strings.TrimSpace(ln), "|") || linkOnlyRe.MatchString(ln) {
+				continue
+			}
+			if n := utf8.RuneCountInString(ln); n > max {
+				res.add(RuleLineLength, sev, "", document.Pos{Line: first + i, Col: max + 1}, fmt.Sprintf("line is %d characters long (max %d)", n, max))
+			}
+		}
+	}
+
+	if br.CodeBlocks {
+		for _, f := range fences {
+			src := strings.Join(lines[f.start:min(f.end, len(lines))], "\n")
+			var err error
+			switch f.lang {
+			case "json":
+				var v any
+				err = json.Unmarshal([]byte(src), &v)
+			case "yaml", "yml":
+				var v any
+				err = yaml.Unmarshal([]byte(src), &v)
+			default:
+				continue
+			}
+			if err != nil {
+				res.add(RuleCodeBlock, sev, "", at(f.line), fmt.Sprintf("%s code block does not parse: %v", f.lang, err))
+			}
+		}
+	}
+}
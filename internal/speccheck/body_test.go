@@ -0,0 +1,88 @@
+package speccheck
+
+import (
+	"fmt"
+	"testing"
+)
+
+func bodyRules(t *testing.T, br BodyRule) *Rules {
+	t.Helper()
+	r := &Rules{Fields: map[string]FieldRule{"title": {Required: true}}, Body: br}
+	if err := r.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func findingLines(res Result) map[string][]int {
+	out := map[string][]int{}
+	for _, f := range res.Findings {
+		out[f.Rule] = append(out[f.Rule], f.Line)
+	}
+	return out
+}
+
+func TestCheckBody(t *testing.T) {
+	rules := bodyRules(t, BodyRule{
+		Sections:        []string{"^目标与非目标", "^变更记录"},
+		SingleH1:        true,
+		HeadingOrder:    true,
+		NoEmptySections: true,
+		MaxLineLength:   20,
+		CodeBlocks:      true,
+	})
+	src := "---\ntitle: Demo\n---\n" + // lines 1-3
+		"# Demo（草案）\n" + // 4
+		"\n" +
+		"## 1. 目标与非目标\n" + // 6
+		"### 1.1 目标\n" + // 7
+		"- \n" +
+		"### 1.2 非目标\n" + // 9
+		"##### deep\n" + // 10
+		"text\n" +
+		"## 2. 接口\n" + // 12
+		"```json\n{\"a\": 1,}\n```\n" + // 13-15
+		"```yaml\nkey: [unterminated\n```\n" + // 16-18
+		"```go\nthis line is far longer than twenty characters\n```\n" +
+		"[a link that is very long](https://example.com/very/long)\n" +
+		"a prose line that is definitely too long\n" + // 23
+		"# Second\n" + // 24
+		"body\n"
+	got := findingLines(Check(rules, "", []byte(src)))
+	want := map[string][]int{
+		RuleRequiredSection: {1},
+		RuleH1:              {24},
+		RuleHeadingOrder:    {10},
+		RuleEmptySection:    {7},
+		RuleCodeBlock:       {13, 16},
+		RuleLineLength:      {23},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("findings by rule = %v, want %v", got, want)
+	}
+}
+
+func TestCheckBody_TitleAndDirs(t *testing.T) {
+	rules := &Rules{
+		Fields: map[string]FieldRule{"title": {Required: true}},
+		Body:   BodyRule{SingleH1: true},
+		Dirs: map[string]Rules{
+			"vibe-docs/task": {Body: BodyRule{Sections: []string{"^验收标准"}}},
+		},
+	}
+	if err := rules.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	spec := "---\ntitle: Web UI\n---\n# web ui (MVP)\n"
+	if res := Check(rules, "vibe-docs/spec/a.spec.mdx", []byte(spec)); len(res.Findings) != 0 {
+		t.Fatalf("spec findings = %+v", res.Findings)
+	}
+	if res := Check(rules, "vibe-docs/spec/a.spec.mdx", []byte("---\ntitle: Other\n---\n# web ui\n")); len(res.Findings) != 1 || res.Findings[0].Rule != RuleH1 {
+		t.Fatalf("spec findings = %+v", res.Findings)
+	}
+	// the task directory replaces the body rule: sections required, no H1 check
+	task := "---\ntitle: T\n---\n# 任务说明\n\n## 验收标准\n- done\n"
+	if res := Check(rules, "vibe-docs/task/t.task.mdx", []byte(task)); len(res.Findings) != 0 {
+		t.Fatalf("task findings = %+v", res.Findings)
+	}
+}
@@ -0,0 +1,209 @@
+// Package speccheck validates spec/task documents against repository rules.
+// It is shared by `codectl check` and the Web UI spec API.
+package speccheck
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"codectl/internal/document"
+)
+
+// Rule identifiers attached to findings.
+const (
+	RuleRulesFile         = "rules-file"
+	RuleRead              = "read-error"
+	RuleFrontmatter       = "frontmatter"
+	RuleFrontmatterYAML   = "frontmatter-yaml"
+	RuleRequiredField     = "required-field"
+	RuleRecommendedField  = "recommended-field"
+	RuleEnum              = "enum"
+	RulePattern           = "pattern"
+	RuleKeyCase           = "key-case"
+	RulePathLocation      = "path-location"
+	RulePathSuffix        = "path-suffix"
+	RuleBrokenLink        = "broken-link"
+	RuleBrokenAnchor      = "broken-anchor"
+	RuleVersionBump       = "version-bump"
+	RuleChangelog         = "changelog"
+	RuleRequiredSection   = "required-section"
+	RuleH1                = "h1"
+	RuleHeadingOrder      = "heading-order"
+	RuleEmptySection      = "empty-section"
+	RuleLineLength        = "line-length"
+	RuleCodeBlock         = "code-block"
+	RuleDuplicateNumber   = "duplicate-number"
+	RuleLocked            = "locked"
+	RuleTransition        = "status-transition"
+	RuleMissingDependency = "missing-dependency"
+	RuleDependencyCycle   = "dependency-cycle"
+)
+
+// Finding is a single validation result.
+type Finding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Field    string `json:"field,omitempty"`
+	// Line and Column are 1-based file positions; zero when unknown.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+// Result is the outcome of checking one document.
+type Result struct {
+	Doc            *document.Document `json:"-"`
+	HasFrontmatter bool               `json:"hasFrontmatter"`
+	Fields         map[string]string  `json:"fields,omitempty"`
+	Findings       []Finding          `json:"findings,omitempty"`
+}
+
+// Errors returns the messages of error findings.
+func (r Result) Errors() []string { return r.messages(SeverityError) }
+
+// Warnings returns the messages of warning findings.
+func (r Result) Warnings() []string { return r.messages(SeverityWarning) }
+
+func (r Result) messages(sev string) []string {
+	out := []string{}
+	for _, f := range r.Findings {
+		if f.Severity == sev {
+			out = append(out, f.Message)
+		}
+	}
+	return out
+}
+
+func (r *Result) add(rule, sev, field string, pos document.Pos, msg string) {
+	r.Findings = append(r.Findings, Finding{Rule: rule, Severity: sev, Field: field, Message: msg, Line: pos.Line, Column: pos.Col})
+}
+
+// top is the position of document-level findings (the first line).
+var top = document.Pos{Line: 1, Col: 1}
+
+// ReadError returns a Result holding a single read failure.
+func ReadError(err error) Result {
+	var r Result
+	r.add(RuleRead, SeverityError, "", document.Pos{}, err.Error())
+	return r
+}
+
+// Check validates content b of the document at repository-relative path rel.
+// rel may be empty when the location is unknown (path rules are skipped).
+func Check(rules *Rules, rel string, b []byte) Result {
+	if rules == nil {
+		rules = Default()
+	}
+	eff := rules.For(rel)
+	var res Result
+	if rel != "" {
+		checkPath(eff, rel, &res)
+	}
+	doc, err := document.Parse(b)
+	res.Doc = doc
+	if errors.Is(err, document.ErrNoFrontmatter) || errors.Is(err, document.ErrUnterminated) {
+		res.add(RuleFrontmatter, SeverityError, "", top, err.Error())
+		return res
+	}
+	res.HasFrontmatter = true
+	if err != nil {
+		pos := top
+		var se *document.SyntaxError
+		if errors.As(err, &se) && se.Line > 0 {
+			pos.Line = se.Line
+		}
+		res.add(RuleFrontmatterYAML, SeverityError, "", pos, err.Error())
+		return res
+	}
+	res.Fields = doc.Fields()
+	checkFields(eff, doc, &res)
+	checkBody(eff, doc, &res)
+	return res
+}
+
+func checkPath(eff *Rules, rel string, res *Result) {
+	sev := severityOr(eff.Paths.Severity, SeverityWarning)
+	slash := filepath.ToSlash(filepath.Clean(rel))
+	if s := eff.Paths.Suffix; s != "" && !strings.HasSuffix(strings.ToLower(slash), strings.ToLower(s)) {
+		res.add(RulePathSuffix, sev, "", top, fmt.Sprintf("file name should end with '%s'", s))
+	}
+	if len(eff.Paths.Under) > 0 {
+		ok := false
+		for _, d := range eff.Paths.Under {
+			d = strings.Trim(filepath.ToSlash(d), "/")
+			if strings.HasPrefix(slash, d+"/") {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			res.add(RulePathLocation, sev, "", top, fmt.Sprintf("file should be located under %s", strings.Join(eff.Paths.Under, ", ")))
+		}
+	}
+}
+
+func checkFields(eff *Rules, doc *document.Document, res *Result) {
+	names := make([]string, 0, len(eff.Fields))
+	for n := range eff.Fields {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	if eff.CaseSensitiveKeys {
+		for _, k := range doc.Keys() {
+			if _, ok := eff.Fields[k]; ok {
+				continue
+			}
+			for _, n := range names {
+				if strings.EqualFold(k, n) {
+					pos, _ := doc.KeyPos(k)
+					res.add(RuleKeyCase, SeverityError, k, pos, fmt.Sprintf("field '%s' must be spelled '%s' (keys are case-sensitive)", k, n))
+					break
+				}
+			}
+		}
+	}
+
+	for _, n := range names {
+		f := eff.Fields[n]
+		val, present := doc.Lookup(n)
+		if !present || strings.TrimSpace(val) == "" {
+			switch {
+			case f.Required:
+				res.add(RuleRequiredField, SeverityError, n, missingPos(doc, n), fmt.Sprintf("missing required field '%s'", n))
+			case f.Recommended:
+				res.add(RuleRecommendedField, SeverityWarning, n, missingPos(doc, n), fmt.Sprintf("recommended field '%s' is missing", n))
+			}
+			continue
+		}
+		sev := severityOr(f.Severity, SeverityError)
+		pos, _ := doc.ValuePos(n)
+		if len(f.Enum) > 0 && !contains(f.Enum, val) {
+			res.add(RuleEnum, sev, n, pos, fmt.Sprintf("field '%s' has value '%s', expected one of %s", n, val, strings.Join(f.Enum, "|")))
+		}
+		if re := eff.patterns[n]; re != nil && !re.MatchString(val) {
+			res.add(RulePattern, sev, n, pos, fmt.Sprintf("field '%s' value '%s' does not match %s", n, val, f.Pattern))
+		}
+	}
+}
+
+// missingPos points at an empty key when present, else at the opening
+// delimiter.
+func missingPos(doc *document.Document, key string) document.Pos {
+	if p, ok := doc.KeyPos(key); ok {
+		return p
+	}
+	return top
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
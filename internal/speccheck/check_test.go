@@ -0,0 +1,127 @@
+package speccheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func rulesOf(findings []Finding) map[string]string {
+	out := map[string]string{}
+	for _, f := range findings {
+		out[f.Rule] = f.Severity
+	}
+	return out
+}
+
+func TestCheck_Default(t *testing.T) {
+	res := Check(nil, "", []byte("---\nstatus: draft\n---\n"))
+	got := rulesOf(res.Findings)
+	if got[RuleRequiredField] != SeverityError || got[RuleRecommendedField] != SeverityWarning {
+		t.Fatalf("findings = %+v", res.Findings)
+	}
+	if len(res.Errors()) != 1 || res.Errors()[0] != "missing required field 'title'" {
+		t.Fatalf("errors = %v", res.Errors())
+	}
+	res = Check(nil, "", []byte("# no frontmatter\n"))
+	if res.HasFrontmatter || rulesOf(res.Findings)[RuleFrontmatter] != SeverityError {
+		t.Fatalf("findings = %+v", res.Findings)
+	}
+}
+
+func TestLoad_RulesFile(t *testing.T) {
+	root := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(root, "vibe-docs"), 0o755)
+	cfg := `{
+  "fields": {
+    "title": {"required": true},
+    "specVersion": {"required": true, "pattern": "^\\d+\\.\\d+\\.\\d+$"},
+    "status": {"required": true, "enum": ["draft", "accepted"], "severity": "warning"}
+  },
+  "caseSensitiveKeys": true,
+  "paths": {"under": ["vibe-docs/spec"], "suffix": ".spec.mdx"},
+  "dirs": {
+    "vibe-docs/spec/legacy": {"fields": {"status": {"enum": ["draft", "accepted", "archived"]}}}
+  }
+}`
+	if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(RulesFile)), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	res := Check(rules, "docs/a.mdx", []byte("---\ntitle: A\nspecversion: 1.0\nstatus: wip\n---\n"))
+	got := rulesOf(res.Findings)
+	want := map[string]string{
+		RulePathSuffix:    SeverityWarning,
+		RulePathLocation:  SeverityWarning,
+		RuleKeyCase:       SeverityError,
+		RuleRequiredField: SeverityError,
+		RuleEnum:          SeverityWarning,
+	}
+	for rule, sev := range want {
+		if got[rule] != sev {
+			t.Fatalf("rule %s: got %q, findings %+v", rule, got[rule], res.Findings)
+		}
+	}
+
+	res = Check(rules, "vibe-docs/spec/a.spec.mdx", []byte("---\ntitle: A\nspecVersion: 1.0\nstatus: draft\n---\n"))
+	if got := rulesOf(res.Findings); len(got) != 1 || got[RulePattern] != SeverityError {
+		t.Fatalf("findings = %+v", res.Findings)
+	}
+
+	doc := []byte("---\ntitle: A\nspecVersion: 1.0.0\nstatus: archived\n---\n")
+	if res := Check(rules, "vibe-docs/spec/a.spec.mdx", doc); len(res.Findings) != 1 {
+		t.Fatalf("archived outside legacy should be flagged: %+v", res.Findings)
+	}
+	if res := Check(rules, "vibe-docs/spec/legacy/a.spec.mdx", doc); len(res.Findings) != 0 {
+		t.Fatalf("legacy dir override not applied: %+v", res.Findings)
+	}
+}
+
+func TestLoad_InvalidPattern(t *testing.T) {
+	root := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(root, "vibe-docs"), 0o755)
+	_ = os.WriteFile(filepath.Join(root, filepath.FromSlash(RulesFile)), []byte(`{"fields":{"x":{"pattern":"("}}}`), 0o644)
+	rules, err := Load(root)
+	if err == nil {
+		t.Fatalf("expected error for invalid pattern")
+	}
+	if rules == nil || !rules.Fields["title"].Required {
+		t.Fatalf("expected default rules on error")
+	}
+}
+
+func TestCheck_Positions(t *testing.T) {
+	rules := &Rules{
+		Fields: map[string]FieldRule{
+			"title":       {Required: true},
+			"specVersion": {Required: true},
+			"status":      {Enum: []string{"draft"}},
+		},
+		CaseSensitiveKeys: true,
+	}
+	if err := rules.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	res := Check(rules, "", []byte("---\ntitle: A\nstatus:   wip\nspecversion: 1.0.0\n---\n"))
+	pos := map[string][2]int{}
+	for _, f := range res.Findings {
+		pos[f.Rule] = [2]int{f.Line, f.Column}
+	}
+	if pos[RuleEnum] != [2]int{3, 11} {
+		t.Fatalf("enum at %v", pos[RuleEnum])
+	}
+	if pos[RuleKeyCase] != [2]int{4, 1} {
+		t.Fatalf("key-case at %v", pos[RuleKeyCase])
+	}
+	if pos[RuleRequiredField] != [2]int{1, 1} {
+		t.Fatalf("required-field at %v", pos[RuleRequiredField])
+	}
+	res = Check(rules, "", []byte("---\ntitle: A\nbad: [\n---\n"))
+	if len(res.Findings) != 1 || res.Findings[0].Rule != RuleFrontmatterYAML || res.Findings[0].Line < 3 {
+		t.Fatalf("findings = %+v", res.Findings)
+	}
+}
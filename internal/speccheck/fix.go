@@ -0,0 +1,183 @@
+package speccheck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"codectl/internal/document"
+)
+
+// Default values written by Fix for missing fields.
+const (
+	DefaultSpecVersion = "0.1.0"
+	DefaultStatus      = "draft"
+	// AutoPlaceholder is the lastUpdated value filled in by tooling.
+	AutoPlaceholder = "{auto}"
+)
+
+var (
+	headingRe  = regexp.MustCompile(`(?m)^#\s+(.+?)\s*#*\s*$`)
+	numberedRe = regexp.MustCompile(`^\d+[-_]`)
+)
+
+// Fix applies the mechanical fixes for the findings of the document at
+// repository-relative path rel and returns the new content together with the
+// fixes applied, as findings whose Message describes the change. The body and
+// the order of existing keys are preserved; new keys are appended. Content
+// that cannot be fixed safely (unterminated or invalid frontmatter) is
+// returned unchanged.
+func Fix(rules *Rules, rel string, b []byte) ([]byte, []Finding) {
+	if rules == nil {
+		rules = Default()
+	}
+	eff := rules.For(rel)
+	var fixed Result
+	doc, err := document.Parse(b)
+	switch {
+	case errors.Is(err, document.ErrNoFrontmatter):
+		doc = document.New(doc.Body)
+		fixed.add(RuleFrontmatter, SeverityError, "", document.Pos{}, "added frontmatter")
+	case err != nil:
+		return b, nil
+	}
+
+	// key-case: rename miscased keys unless the canonical key exists too
+	if eff.CaseSensitiveKeys {
+		for _, k := range doc.Keys() {
+			if _, ok := eff.Fields[k]; ok {
+				continue
+			}
+			for n := range eff.Fields {
+				if strings.EqualFold(k, n) && !doc.Has(n) {
+					if doc.Rename(k, n) == nil {
+						fixed.add(RuleKeyCase, SeverityError, n, document.Pos{}, fmt.Sprintf("renamed '%s' to '%s'", k, n))
+					}
+					break
+				}
+			}
+		}
+	}
+
+	for _, n := range sortedFields(eff) {
+		f := eff.Fields[n]
+		val, present := doc.Lookup(n)
+		if !present || strings.TrimSpace(val) == "" {
+			if !f.Required && !f.Recommended {
+				continue
+			}
+			rule, sev := RuleRequiredField, SeverityError
+			if !f.Required {
+				rule, sev = RuleRecommendedField, SeverityWarning
+			}
+			v, raw, ok := defaultValue(n, f, doc, rel)
+			if !ok {
+				continue
+			}
+			if err := setValue(doc, n, v, raw); err == nil {
+				fixed.add(rule, sev, n, document.Pos{}, fmt.Sprintf("set '%s' to '%s'", n, v))
+			}
+			continue
+		}
+		sev := severityOr(f.Severity, SeverityError)
+		// enum: normalize values that only differ in case
+		if len(f.Enum) > 0 && !contains(f.Enum, val) {
+			for _, e := range f.Enum {
+				if strings.EqualFold(strings.TrimSpace(val), e) {
+					if doc.Set(n, e) == nil {
+						fixed.add(RuleEnum, sev, n, document.Pos{}, fmt.Sprintf("changed '%s' from '%s' to '%s'", n, val, e))
+					}
+					break
+				}
+			}
+			continue
+		}
+		// lastUpdated: replace values the pattern rejects by the placeholder
+		if n == "lastUpdated" {
+			if re := eff.patterns[n]; re != nil && !re.MatchString(val) && re.MatchString(AutoPlaceholder) {
+				if doc.SetRaw(n, AutoPlaceholder) == nil {
+					fixed.add(RulePattern, sev, n, document.Pos{}, fmt.Sprintf("set '%s' to '%s'", n, AutoPlaceholder))
+				}
+			}
+		}
+	}
+
+	// an empty frontmatter block on its own is not worth a rewrite
+	if len(fixed.Findings) == 0 || (len(fixed.Findings) == 1 && fixed.Findings[0].Rule == RuleFrontmatter) {
+		return b, nil
+	}
+	out := doc.Bytes()
+	if bytes.Equal(out, b) {
+		return b, nil
+	}
+	return out, fixed.Findings
+}
+
+// defaultValue returns the value Fix writes for a missing field; raw values
+// are written verbatim instead of being quoted.
+func defaultValue(name string, f FieldRule, doc *document.Document, rel string) (val string, raw bool, ok bool) {
+	switch name {
+	case "title":
+		if m := headingRe.FindStringSubmatch(doc.Body); m != nil {
+			return strings.TrimSpace(m[1]), false, true
+		}
+		if rel == "" {
+			return "", false, false
+		}
+		base := path.Base(rel)
+		if i := strings.Index(base, "."); i > 0 {
+			base = base[:i]
+		}
+		base = numberedRe.ReplaceAllString(base, "")
+		if base == "" {
+			return "", false, false
+		}
+		return base, false, true
+	case "specVersion":
+		return DefaultSpecVersion, false, true
+	case "status":
+		if len(f.Enum) > 0 {
+			if contains(f.Enum, DefaultStatus) {
+				return DefaultStatus, false, true
+			}
+			return f.Enum[0], false, true
+		}
+		return DefaultStatus, false, true
+	case "lastUpdated":
+		return AutoPlaceholder, true, true
+	}
+	if len(f.Enum) > 0 {
+		return f.Enum[0], false, true
+	}
+	return "", false, false
+}
+
+func setValue(doc *document.Document, key, val string, raw bool) error {
+	if raw {
+		return doc.SetRaw(key, val)
+	}
+	return doc.Set(key, val)
+}
+
+func sortedFields(eff *Rules) []string {
+	// keep a stable, conventional order for appended keys
+	order := []string{"title", "specVersion", "status", "lastUpdated"}
+	out := make([]string, 0, len(eff.Fields))
+	for _, n := range order {
+		if _, ok := eff.Fields[n]; ok {
+			out = append(out, n)
+		}
+	}
+	rest := make([]string, 0, len(eff.Fields))
+	for n := range eff.Fields {
+		if !contains(order, n) {
+			rest = append(rest, n)
+		}
+	}
+	sort.Strings(rest)
+	return append(out, rest...)
+}
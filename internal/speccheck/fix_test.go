@@ -0,0 +1,63 @@
+package speccheck
+
+import (
+	"strings"
+	"testing"
+)
+
+func repoRules(t *testing.T) *Rules {
+	t.Helper()
+	r := &Rules{
+		Fields: map[string]FieldRule{
+			"title":       {Required: true},
+			"specVersion": {Required: true, Pattern: `^\d+\.\d+\.\d+$`},
+			"status":      {Required: true, Enum: []string{"draft", "accepted"}},
+			"lastUpdated": {Pattern: `^(\{auto\}|\d{4}-\d{2}-\d{2})$`},
+		},
+		CaseSensitiveKeys: true,
+	}
+	if err := r.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestFix_Frontmatter(t *testing.T) {
+	rules := repoRules(t)
+	src := "---\ntitle: Keep me # comment\nspecversion: 1.2.3\nStatus: Draft\nlastUpdated: someday\nowners: [a]\n---\n# Body\n"
+	out, fixed := Fix(rules, "vibe-docs/spec/x.spec.mdx", []byte(src))
+	want := "---\ntitle: Keep me # comment\nspecVersion: 1.2.3\nstatus: draft\nlastUpdated: {auto}\nowners: [a]\n---\n# Body\n"
+	if string(out) != want {
+		t.Fatalf("unexpected fix:\n%s", out)
+	}
+	if len(fixed) != 4 {
+		t.Fatalf("fixed = %+v", fixed)
+	}
+	if res := Check(rules, "vibe-docs/spec/x.spec.mdx", out); len(res.Findings) != 0 {
+		t.Fatalf("findings after fix: %+v", res.Findings)
+	}
+	// idempotent
+	if again, fx := Fix(rules, "vibe-docs/spec/x.spec.mdx", out); fx != nil || string(again) != string(out) {
+		t.Fatalf("second fix changed content: %+v", fx)
+	}
+}
+
+func TestFix_MissingFrontmatter(t *testing.T) {
+	rules := repoRules(t)
+	out, fixed := Fix(rules, "vibe-docs/spec/120-cool-thing.spec.mdx", []byte("Some notes.\n"))
+	if !strings.HasPrefix(string(out), "---\ntitle: cool-thing\nspecVersion: 0.1.0\nstatus: draft\n---\nSome notes.\n") {
+		t.Fatalf("unexpected fix:\n%s", out)
+	}
+	if len(fixed) == 0 || fixed[0].Rule != RuleFrontmatter {
+		t.Fatalf("fixed = %+v", fixed)
+	}
+	out, _ = Fix(rules, "", []byte("# Heading Title\n\ntext\n"))
+	if !strings.Contains(string(out), "title: Heading Title\n") {
+		t.Fatalf("title not taken from heading:\n%s", out)
+	}
+	// unterminated frontmatter is left alone
+	src := []byte("---\ntitle: x\n")
+	if out, fixed := Fix(rules, "", src); string(out) != string(src) || fixed != nil {
+		t.Fatalf("unterminated frontmatter should not be fixed")
+	}
+}
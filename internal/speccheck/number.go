@@ -0,0 +1,154 @@
+package speccheck
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NumberRule configures numeric file name prefixes such as
+// "300-mcp.spec.mdx": the number width, the category ranges numbers are
+// assigned from and the severity of duplicate numbers.
+type NumberRule struct {
+	// Width is the number of digits (default 3).
+	Width int `json:"width,omitempty"`
+	// Ranges lists the categories in assignment order.
+	Ranges []NumberRange `json:"ranges,omitempty"`
+	// Severity of duplicate numbers (default "error").
+	Severity string `json:"severity,omitempty"`
+}
+
+// NumberRange is the inclusive range of numbers of one category.
+type NumberRange struct {
+	Category string `json:"category"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+}
+
+// Enabled reports whether numbering is configured.
+func (n NumberRule) Enabled() bool { return n.Width > 0 || len(n.Ranges) > 0 }
+
+func (n NumberRule) width() int {
+	if n.Width > 0 {
+		return n.Width
+	}
+	return 3
+}
+
+// Format pads num to the configured width.
+func (n NumberRule) Format(num int) string {
+	return fmt.Sprintf("%0*d", n.width(), num)
+}
+
+// Range returns the range of category; an empty category spans every
+// number of the configured width.
+func (n NumberRule) Range(category string) (NumberRange, error) {
+	if category == "" {
+		max := 1
+		for range n.width() {
+			max *= 10
+		}
+		return NumberRange{From: 0, To: max - 1}, nil
+	}
+	names := make([]string, 0, len(n.Ranges))
+	for _, r := range n.Ranges {
+		if strings.EqualFold(r.Category, category) {
+			return r, nil
+		}
+		names = append(names, r.Category)
+	}
+	return NumberRange{}, fmt.Errorf("unknown category %q (configured: %s)", category, strings.Join(names, ", "))
+}
+
+// Next returns the number following the highest used number in the range
+// of category, or the lowest gap once the end of the range is taken.
+func (n NumberRule) Next(used []int, category string) (int, error) {
+	r, err := n.Range(category)
+	if err != nil {
+		return 0, err
+	}
+	taken := map[int]bool{}
+	high := r.From - 1
+	for _, u := range used {
+		if u >= r.From && u <= r.To {
+			taken[u] = true
+			high = max(high, u)
+		}
+	}
+	if high < r.To {
+		return high + 1, nil
+	}
+	for i := r.From; i <= r.To; i++ {
+		if !taken[i] {
+			return i, nil
+		}
+	}
+	label := category
+	if label == "" {
+		label = "numbering"
+	}
+	return 0, fmt.Errorf("%s: no free number in %d-%d", label, r.From, r.To)
+}
+
+var numberRe = regexp.MustCompile(`^(\d+)-`)
+
+// FileNumber returns the numeric prefix of a file name ("201" in
+// "201-tui-dash.spec.mdx").
+func FileNumber(name string) (num int, digits string, ok bool) {
+	m := numberRe.FindStringSubmatch(path.Base(name))
+	if m == nil {
+		return 0, "", false
+	}
+	num, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, "", false
+	}
+	return num, m[1], true
+}
+
+// Duplicates reports documents that share a number with another document
+// of the same directory, keyed by repository-relative path. Directories
+// without numbering configured are skipped.
+func Duplicates(r *Rules, rels []string) map[string][]Finding {
+	type key struct {
+		dir string
+		num int
+	}
+	groups := map[key][]string{}
+	for _, rel := range rels {
+		if !r.For(rel).Numbering.Enabled() {
+			continue
+		}
+		if num, _, ok := FileNumber(rel); ok {
+			k := key{path.Dir(rel), num}
+			groups[k] = append(groups[k], rel)
+		}
+	}
+	out := map[string][]Finding{}
+	for _, g := range groups {
+		if len(g) < 2 {
+			continue
+		}
+		sort.Strings(g)
+		for _, rel := range g {
+			var others []string
+			for _, o := range g {
+				if o != rel {
+					others = append(others, path.Base(o))
+				}
+			}
+			_, digits, _ := FileNumber(rel)
+			out[rel] = append(out[rel], Finding{
+				Rule:     RuleDuplicateNumber,
+				Severity: severityOr(r.For(rel).Numbering.Severity, SeverityError),
+				Message:  fmt.Sprintf("number %s is also used by %s", digits, strings.Join(others, ", ")),
+				Line:     1,
+				Column:   1,
+			})
+		}
+	}
+	return out
+}
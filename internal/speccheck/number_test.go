@@ -0,0 +1,59 @@
+package speccheck
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNumberNext(t *testing.T) {
+	n := NumberRule{Ranges: []NumberRange{{Category: "tui", From: 200, To: 203}, {Category: "mcp", From: 300, To: 399}}}
+	cases := []struct {
+		used     []int
+		category string
+		want     int
+		err      string
+	}{
+		{[]int{0, 100, 200, 201, 300}, "tui", 202, ""},
+		{nil, "MCP", 300, ""},
+		{[]int{200, 201, 203}, "tui", 202, ""},
+		{[]int{200, 201, 202, 203}, "tui", 0, "no free number"},
+		{[]int{0, 410}, "", 411, ""},
+		{nil, "web", 0, "unknown category"},
+	}
+	for _, c := range cases {
+		got, err := n.Next(c.used, c.category)
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("Next(%v, %q) err = %v, want %q", c.used, c.category, err, c.err)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("Next(%v, %q) = %d, %v; want %d", c.used, c.category, got, err, c.want)
+		}
+	}
+	if got := n.Format(7); got != "007" {
+		t.Errorf("Format(7) = %q", got)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	r := &Rules{Dirs: map[string]Rules{"vibe-docs/spec": {Numbering: NumberRule{Width: 3}}}}
+	if err := r.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	got := Duplicates(r, []string{
+		"vibe-docs/spec/201-a.spec.mdx",
+		"vibe-docs/spec/201-b.spec.mdx",
+		"vibe-docs/spec/202-c.spec.mdx",
+		"vibe-docs/task/250913-x.task.mdx",
+		"vibe-docs/task/250913-y.task.mdx",
+	})
+	if len(got) != 2 {
+		t.Fatalf("duplicates = %+v", got)
+	}
+	f := got["vibe-docs/spec/201-a.spec.mdx"]
+	if len(f) != 1 || f[0].Rule != RuleDuplicateNumber || f[0].Severity != SeverityError || !strings.Contains(f[0].Message, "201-b.spec.mdx") {
+		t.Fatalf("finding = %+v", f)
+	}
+}
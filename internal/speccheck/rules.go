@@ -0,0 +1,208 @@
+package speccheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RulesFile is the repository-relative location of the rules file.
+const RulesFile = "vibe-docs/.specrules.json"
+
+// Severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// FieldRule constrains one frontmatter field.
+type FieldRule struct {
+	Required    bool     `json:"required,omitempty"`
+	Recommended bool     `json:"recommended,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	// Severity of enum/pattern violations (default "error").
+	Severity string `json:"severity,omitempty"`
+}
+
+// PathRule constrains where documents live and how they are named.
+type PathRule struct {
+	// Under lists repository-relative directories documents must live in.
+	Under []string `json:"under,omitempty"`
+	// Suffix is the required file name suffix (e.g. ".spec.mdx").
+	Suffix string `json:"suffix,omitempty"`
+	// Severity of path violations (default "warning").
+	Severity string `json:"severity,omitempty"`
+}
+
+// VersionRule configures the check of a spec against its git base: a body
+// change needs a specVersion bump, and a new version needs a changelog entry.
+type VersionRule struct {
+	Disabled bool `json:"disabled,omitempty"`
+	// Severity of findings (default "warning").
+	Severity string `json:"severity,omitempty"`
+}
+
+// Rules is the shape of vibe-docs/.specrules.json.
+type Rules struct {
+	Fields map[string]FieldRule `json:"fields,omitempty"`
+	// CaseSensitiveKeys reports keys that match a configured field only when
+	// ignoring case (e.g. "specversion" for "specVersion").
+	CaseSensitiveKeys bool     `json:"caseSensitiveKeys,omitempty"`
+	Paths             PathRule `json:"paths,omitempty"`
+	// Versioning applies to documents that declare specVersion.
+	Versioning VersionRule `json:"versioning,omitempty"`
+	// Body configures structural lint of the document body.
+	Body BodyRule `json:"body,omitempty"`
+	// Numbering configures numeric file name prefixes.
+	Numbering NumberRule `json:"numbering,omitempty"`
+	// Workflow is the review state machine of the status field.
+	Workflow WorkflowRule `json:"workflow,omitempty"`
+	// Board configures the task board columns.
+	Board BoardRule `json:"board,omitempty"`
+	// Dirs overrides rules for documents below a repository-relative
+	// directory; deeper directories are applied last. A field rule replaces
+	// the parent's rule for that field; path, body, numbering, workflow and
+	// board rules replace the parent's when set.
+	Dirs map[string]Rules `json:"dirs,omitempty"`
+
+	patterns map[string]*regexp.Regexp
+	sections []*regexp.Regexp // compiled Body.Sections
+}
+
+// Default returns the rules used when a repository has no rules file:
+// a required title and a recommended specVersion.
+func Default() *Rules {
+	r := &Rules{Fields: map[string]FieldRule{
+		"title":       {Required: true},
+		"specVersion": {Recommended: true},
+	}}
+	_ = r.Compile()
+	return r
+}
+
+// Load reads RulesFile under root; a missing file yields Default().
+func Load(root string) (*Rules, error) {
+	b, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(RulesFile)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Default(), nil
+		}
+		return Default(), err
+	}
+	var r Rules
+	if err := json.Unmarshal(b, &r); err != nil {
+		return Default(), fmt.Errorf("%s: %w", RulesFile, err)
+	}
+	if err := r.Compile(); err != nil {
+		return Default(), fmt.Errorf("%s: %w", RulesFile, err)
+	}
+	return &r, nil
+}
+
+// Compile compiles the patterns of r; Load does this for rules files.
+func (r *Rules) Compile() error {
+	r.patterns = map[string]*regexp.Regexp{}
+	for name, f := range r.Fields {
+		if f.Pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			return fmt.Errorf("field %q: invalid pattern: %w", name, err)
+		}
+		r.patterns[name] = re
+	}
+	r.sections = nil
+	for _, pat := range r.Body.Sections {
+		re, err := regexp.Compile("(?i)" + pat)
+		if err != nil {
+			return fmt.Errorf("body section %q: invalid pattern: %w", pat, err)
+		}
+		r.sections = append(r.sections, re)
+	}
+	for dir, sub := range r.Dirs {
+		if err := sub.Compile(); err != nil {
+			return fmt.Errorf("dir %q: %w", dir, err)
+		}
+		r.Dirs[dir] = sub
+	}
+	return nil
+}
+
+// For returns the effective rules for a repository-relative path.
+func (r *Rules) For(rel string) *Rules {
+	rel = filepath.ToSlash(filepath.Clean(rel))
+	dirs := make([]string, 0, len(r.Dirs))
+	for d := range r.Dirs {
+		dirs = append(dirs, d)
+	}
+	// shortest first so deeper directories override
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) < len(dirs[j]) })
+	eff := &Rules{
+		Fields:            map[string]FieldRule{},
+		CaseSensitiveKeys: r.CaseSensitiveKeys,
+		Paths:             r.Paths,
+		Versioning:        r.Versioning,
+		Body:              r.Body,
+		Numbering:         r.Numbering,
+		Workflow:          r.Workflow,
+		Board:             r.Board,
+		patterns:          map[string]*regexp.Regexp{},
+		sections:          r.sections,
+	}
+	merge := func(src Rules) {
+		for k, v := range src.Fields {
+			eff.Fields[k] = v
+			delete(eff.patterns, k)
+			if re := src.patterns[k]; re != nil {
+				eff.patterns[k] = re
+			}
+		}
+		if src.CaseSensitiveKeys {
+			eff.CaseSensitiveKeys = true
+		}
+		if len(src.Paths.Under) > 0 || src.Paths.Suffix != "" {
+			eff.Paths = src.Paths
+		}
+		if src.Versioning.Disabled || src.Versioning.Severity != "" {
+			eff.Versioning = src.Versioning
+		}
+		if !src.Body.isZero() {
+			eff.Body, eff.sections = src.Body, src.sections
+		}
+		if src.Numbering.Enabled() {
+			eff.Numbering = src.Numbering
+		}
+		if len(src.Workflow.Transitions) > 0 || src.Workflow.Field != "" {
+			eff.Workflow = src.Workflow
+		}
+		if len(src.Board.Columns) > 0 {
+			eff.Board.Columns = src.Board.Columns
+		}
+		if len(src.Board.Closed) > 0 {
+			eff.Board.Closed = src.Board.Closed
+		}
+	}
+	merge(*r)
+	for _, d := range dirs {
+		prefix := strings.Trim(filepath.ToSlash(d), "/")
+		if rel == prefix || strings.HasPrefix(rel, prefix+"/") {
+			merge(r.Dirs[d])
+		}
+	}
+	return eff
+}
+
+func severityOr(s, def string) string {
+	switch s {
+	case SeverityError, SeverityWarning:
+		return s
+	default:
+		return def
+	}
+}
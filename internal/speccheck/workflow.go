@@ -0,0 +1,90 @@
+package speccheck
+
+import "slices"
+
+// WorkflowRule is the review state machine of the status field. Without
+// transitions the default workflow applies: draft → review → accepted →
+// deprecated, where review may return to draft and accepted or deprecated
+// specs are reopened to draft.
+type WorkflowRule struct {
+	// Field holds the state (default "status").
+	Field string `json:"field,omitempty"`
+	// Initial is the state of documents without a known state (default
+	// "draft").
+	Initial string `json:"initial,omitempty"`
+	// Transitions maps a state to the states it may move to.
+	Transitions map[string][]string `json:"transitions,omitempty"`
+	// Locked lists states whose documents may not be edited until they are
+	// moved to an unlocked state; entering one records an approval.
+	Locked []string `json:"locked,omitempty"`
+	// Severity of edits to locked documents (default "error").
+	Severity string `json:"severity,omitempty"`
+}
+
+// DefaultWorkflow returns the workflow used when none is configured.
+func DefaultWorkflow() WorkflowRule {
+	return WorkflowRule{
+		Field:   "status",
+		Initial: "draft",
+		Transitions: map[string][]string{
+			"draft":      {"review"},
+			"review":     {"accepted", "draft"},
+			"accepted":   {"deprecated", "draft"},
+			"deprecated": {"draft"},
+		},
+		Locked: []string{"accepted"},
+	}
+}
+
+// Effective fills unset parts of w from DefaultWorkflow.
+func (w WorkflowRule) Effective() WorkflowRule {
+	def := DefaultWorkflow()
+	if w.Field == "" {
+		w.Field = def.Field
+	}
+	if w.Initial == "" {
+		w.Initial = def.Initial
+	}
+	if len(w.Transitions) == 0 {
+		w.Transitions = def.Transitions
+		if w.Locked == nil {
+			w.Locked = def.Locked
+		}
+	}
+	w.Severity = severityOr(w.Severity, SeverityError)
+	return w
+}
+
+// Known reports whether state is part of the workflow.
+func (w WorkflowRule) Known(state string) bool {
+	w = w.Effective()
+	if _, ok := w.Transitions[state]; ok || state == w.Initial {
+		return true
+	}
+	for _, tos := range w.Transitions {
+		if slices.Contains(tos, state) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the states reachable from state; a state outside the
+// workflow may only move to the initial state.
+func (w WorkflowRule) Next(state string) []string {
+	w = w.Effective()
+	if !w.Known(state) {
+		return []string{w.Initial}
+	}
+	return w.Transitions[state]
+}
+
+// Allowed reports whether from may move to to.
+func (w WorkflowRule) Allowed(from, to string) bool {
+	return slices.Contains(w.Next(from), to)
+}
+
+// IsLocked reports whether documents in state are locked against edits.
+func (w WorkflowRule) IsLocked(state string) bool {
+	return slices.Contains(w.Effective().Locked, state)
+}
@@ -0,0 +1,340 @@
+// Package specgraph builds the cross-reference graph of spec and task
+// documents and detects broken links and heading anchors.
+package specgraph
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"codectl/internal/document"
+	"codectl/internal/speccheck"
+)
+
+// Document kinds.
+const (
+	NodeSpec = "spec"
+	NodeTask = "task"
+)
+
+// Dirs are the repository-relative directories scanned for documents.
+var Dirs = []string{"vibe-docs/spec", "vibe-docs/task"}
+
+// Node is a spec or task document.
+type Node struct {
+	ID     string `json:"id"` // repository-relative path
+	Kind   string `json:"kind"`
+	Title  string `json:"title,omitempty"`
+	Status string `json:"status,omitempty"`
+	In     int    `json:"in"`
+	Out    int    `json:"out"`
+	// Orphan is true when no other document links to or from this one.
+	Orphan bool `json:"orphan"`
+
+	anchors  map[string]bool
+	headings []Heading
+}
+
+// Headings returns the headings of the document.
+func (n Node) Headings() []Heading { return n.headings }
+
+// Edge is a reference from one document to another path.
+type Edge struct {
+	From   string `json:"from"`
+	To     string `json:"to"` // repository-relative target path
+	Kind   string `json:"kind"`
+	Target string `json:"target"` // as written in the source
+	Anchor string `json:"anchor,omitempty"`
+	Line   int    `json:"line"`
+	Col    int    `json:"col"`
+	// Broken is set when the target path or anchor does not resolve.
+	Broken bool   `json:"broken,omitempty"`
+	Reason string `json:"reason,omitempty"` // "missing" or "anchor"
+}
+
+// Broken reasons.
+const (
+	ReasonMissing = "missing"
+	ReasonAnchor  = "anchor"
+)
+
+// Graph is the set of documents and their references.
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+
+	root  string
+	index map[string]*Node
+}
+
+type source struct {
+	rel  string
+	refs []Ref
+}
+
+// Build scans Dirs under root and returns the document graph. References to
+// existing non-document files are checked but not kept as edges.
+func Build(root string) (*Graph, error) {
+	var srcs []source
+	nodes := map[string]*Node{}
+	for _, d := range Dirs {
+		dir := filepath.Join(root, filepath.FromSlash(d))
+		if st, err := os.Stat(dir); err != nil || !st.IsDir() {
+			continue
+		}
+		err := filepath.WalkDir(dir, func(p string, de os.DirEntry, err error) error {
+			if err != nil || de.IsDir() {
+				return nil
+			}
+			kind := docKind(de.Name())
+			if kind == "" {
+				return nil
+			}
+			b, err := os.ReadFile(p)
+			if err != nil {
+				return nil
+			}
+			rel := relSlash(root, p)
+			doc, perr := document.Parse(b)
+			n := &Node{ID: rel, Kind: kind}
+			var refs []Ref
+			if perr == nil {
+				n.Title = doc.String("title")
+				n.Status = doc.String("status")
+				refs = frontmatterRefs(doc)
+			}
+			body, anchors := ParseBody(doc.Body, doc.BodyLine())
+			n.anchors = anchors
+			n.headings = Headings(doc.Body, doc.BodyLine())
+			nodes[rel] = n
+			srcs = append(srcs, source{rel: rel, refs: append(refs, body...)})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	g := &Graph{Nodes: []Node{}, Edges: []Edge{}, root: root}
+	for _, s := range srcs {
+		for _, r := range s.refs {
+			e, keep := resolve(root, s.rel, r, nodes)
+			if !keep {
+				continue
+			}
+			g.Edges = append(g.Edges, e)
+			if e.Reason == ReasonMissing || e.To == e.From {
+				continue
+			}
+			if n := nodes[e.From]; n != nil {
+				n.Out++
+			}
+			if n := nodes[e.To]; n != nil {
+				n.In++
+			}
+		}
+	}
+	for _, n := range nodes {
+		n.Orphan = n.In == 0 && n.Out == 0
+		g.Nodes = append(g.Nodes, *n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	g.index = make(map[string]*Node, len(g.Nodes))
+	for i := range g.Nodes {
+		g.index[g.Nodes[i].ID] = &g.Nodes[i]
+	}
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].Line < g.Edges[j].Line
+	})
+	return g, nil
+}
+
+// Node returns the document with the repository-relative path id.
+func (g *Graph) Node(id string) (Node, bool) {
+	if n := g.index[id]; n != nil {
+		return *n, true
+	}
+	return Node{}, false
+}
+
+// Resolve resolves a link or spec: reference written in the document from,
+// as a Markdown link would be. ok is false for external links and links to
+// files that are not documents.
+func (g *Graph) Resolve(from, target string) (e Edge, ok bool) {
+	kind := KindLink
+	if strings.HasPrefix(target, "spec:") {
+		kind = KindRef
+	}
+	return resolve(g.root, from, Ref{Kind: kind, Target: target}, g.index)
+}
+
+// HeadingAnchor returns the heading anchor of document id that anchor
+// (a slug, a section number or heading text) refers to.
+func (g *Graph) HeadingAnchor(id, anchor string) (string, bool) {
+	n := g.index[id]
+	if n == nil {
+		return "", false
+	}
+	slug := Slug(anchor)
+	for _, h := range n.headings {
+		if h.Anchor == anchor || h.Anchor == slug || (h.Number != "" && h.Number == anchor) {
+			return h.Anchor, true
+		}
+	}
+	return "", false
+}
+
+// BrokenFrom returns the broken edges of the document rel.
+func (g *Graph) BrokenFrom(rel string) []Edge {
+	var out []Edge
+	for _, e := range g.Edges {
+		if e.Broken && e.From == rel {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func frontmatterRefs(doc *document.Document) []Ref {
+	var refs []Ref
+	for key, kind := range map[string]string{"depends": KindDepends, "spec": KindSpec, "relatedSpec": KindSpec} {
+		if !doc.Has(key) {
+			continue
+		}
+		pos, _ := doc.KeyPos(key)
+		for _, v := range doc.Strings(key) {
+			if v = strings.TrimSpace(v); v != "" {
+				refs = append(refs, Ref{Kind: kind, Target: v, Line: pos.Line, Col: pos.Col})
+			}
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].Line < refs[j].Line })
+	return refs
+}
+
+// resolve turns a reference into an edge; keep is false for external links
+// and valid links to non-document files.
+func resolve(root, from string, r Ref, nodes map[string]*Node) (Edge, bool) {
+	e := Edge{From: from, Kind: r.Kind, Target: r.Target, Line: r.Line, Col: r.Col}
+	target := r.Target
+	if IsExternal(target) {
+		return e, false
+	}
+	var p string
+	if strings.HasPrefix(target, "spec:") {
+		name, anchor, _ := strings.Cut(strings.TrimPrefix(target, "spec:"), "#")
+		e.Anchor = anchor
+		p = lookupSpec(name, nodes)
+		if p == "" {
+			e.To = name
+			e.Broken, e.Reason = true, ReasonMissing
+			return e, true
+		}
+	} else {
+		pathPart, anchor, _ := strings.Cut(target, "#")
+		e.Anchor = anchor
+		if u, err := url.PathUnescape(pathPart); err == nil {
+			pathPart = u
+		}
+		switch {
+		case pathPart == "":
+			p = from
+		case strings.HasPrefix(pathPart, "/"):
+			p = path.Clean(strings.TrimPrefix(pathPart, "/"))
+		case (r.Kind == KindDepends || r.Kind == KindSpec) && strings.HasPrefix(pathPart, "vibe-docs/"):
+			p = path.Clean(pathPart)
+		default:
+			p = path.Join(path.Dir(from), pathPart)
+		}
+	}
+	e.To = p
+	n := nodes[p]
+	if n == nil {
+		if strings.HasPrefix(p, "../") || p == ".." {
+			e.Broken, e.Reason = true, ReasonMissing
+			return e, true
+		}
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(p))); err != nil {
+			e.Broken, e.Reason = true, ReasonMissing
+			return e, true
+		}
+		// an existing file that is not a spec/task document
+		return e, false
+	}
+	if e.Anchor != "" && !n.anchors[e.Anchor] && !n.anchors[Slug(e.Anchor)] {
+		e.Broken, e.Reason = true, ReasonAnchor
+	}
+	return e, true
+}
+
+// lookupSpec resolves a spec: name: a repository-relative path, a file name
+// in vibe-docs/spec with or without the .spec.mdx suffix, or a unique number
+// prefix such as "300".
+func lookupSpec(name string, nodes map[string]*Node) string {
+	name = strings.TrimSuffix(name, "/")
+	if nodes[name] != nil {
+		return name
+	}
+	dir := Dirs[0]
+	for _, c := range []string{name, name + ".spec.mdx", name + ".mdx"} {
+		if p := path.Join(dir, c); nodes[p] != nil {
+			return p
+		}
+	}
+	found := ""
+	for id, n := range nodes {
+		if n.Kind != NodeSpec || path.Dir(id) != dir {
+			continue
+		}
+		if strings.HasPrefix(path.Base(id), name+"-") {
+			if found != "" {
+				return "" // ambiguous
+			}
+			found = id
+		}
+	}
+	return found
+}
+
+func docKind(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".spec.mdx"):
+		return NodeSpec
+	case strings.HasSuffix(name, ".task.mdx"):
+		return NodeTask
+	}
+	return ""
+}
+
+func relSlash(root, p string) string {
+	if r, err := filepath.Rel(root, p); err == nil {
+		return filepath.ToSlash(r)
+	}
+	return filepath.ToSlash(p)
+}
+
+// Findings reports the broken references of the document rel: missing
+// targets as errors, unknown heading anchors as warnings.
+func (g *Graph) Findings(rel string) []speccheck.Finding {
+	var out []speccheck.Finding
+	for _, e := range g.BrokenFrom(rel) {
+		f := speccheck.Finding{Line: e.Line, Column: e.Col}
+		if e.Reason == ReasonAnchor {
+			f.Rule, f.Severity = speccheck.RuleBrokenAnchor, speccheck.SeverityWarning
+			f.Message = fmt.Sprintf("link '%s' points to a missing heading '#%s' in %s", e.Target, e.Anchor, e.To)
+		} else {
+			f.Rule, f.Severity = speccheck.RuleBrokenLink, speccheck.SeverityError
+			f.Message = fmt.Sprintf("link '%s' points to a missing document", e.Target)
+		}
+		out = append(out, f)
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package specgraph
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func write(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "vibe-docs/spec/000-overall.spec.mdx", "---\ntitle: Overall\n---\n# Overall\n\n## 4. 工作流 Details\n\n"+
+		"- see `./100-a.spec.mdx` and [a](100-a.spec.mdx#intro)\n"+
+		"- [bad anchor](./100-a.spec.mdx#nope) [missing](./missing.spec.mdx)\n"+
+		"- [self](#4-工作流-details) [num](#4) [web](https://example.com) [readme](../../README.md)\n"+
+		"```\n[ignored](./nothing.md)\n```\n")
+	write(t, root, "vibe-docs/spec/100-a.spec.mdx", "---\ntitle: A\ndepends:\n  - 000-overall.spec.mdx\n---\n## Intro\n")
+	write(t, root, "vibe-docs/spec/200-lonely.spec.mdx", "---\ntitle: Lonely\n---\nno links\n")
+	write(t, root, "vibe-docs/task/t1.task.mdx", "---\ntitle: T\nspec: spec:100\n---\nSee spec:200-lonely#nope.\n")
+	write(t, root, "README.md", "readme\n")
+
+	g, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := map[string]Node{}
+	for _, n := range g.Nodes {
+		nodes[n.ID] = n
+	}
+	if len(nodes) != 4 {
+		t.Fatalf("nodes = %+v", g.Nodes)
+	}
+	a := nodes["vibe-docs/spec/100-a.spec.mdx"]
+	if a.In != 4 || a.Out != 1 || a.Orphan {
+		t.Fatalf("100-a = %+v", a)
+	}
+	if nodes["vibe-docs/spec/200-lonely.spec.mdx"].Orphan {
+		t.Fatalf("200-lonely is linked from a task")
+	}
+
+	broken := g.BrokenFrom("vibe-docs/spec/000-overall.spec.mdx")
+	if len(broken) != 2 {
+		t.Fatalf("broken = %+v", broken)
+	}
+	if broken[0].Reason != ReasonAnchor || broken[0].Line != 9 || broken[1].Reason != ReasonMissing {
+		t.Fatalf("broken = %+v", broken)
+	}
+	if got := g.BrokenFrom("vibe-docs/task/t1.task.mdx"); len(got) != 1 || got[0].Reason != ReasonAnchor {
+		t.Fatalf("task broken = %+v", got)
+	}
+	f := g.Findings("vibe-docs/spec/000-overall.spec.mdx")
+	if len(f) != 2 || f[1].Rule != "broken-link" || f[1].Line != 9 || f[1].Column == 0 {
+		t.Fatalf("findings = %+v", f)
+	}
+}
+
+func TestSlug(t *testing.T) {
+	cases := map[string]string{
+		"7.2 校验报告 JSON（`codectl check --json` 输出）": "72-校验报告-jsoncodectl-check---json-输出",
+		"Hello, World!": "hello-world",
+	}
+	for in, want := range cases {
+		if got := Slug(in); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package specgraph
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Reference kinds.
+const (
+	KindLink    = "link"    // Markdown link [text](target)
+	KindRef     = "ref"     // spec:<name> reference or a `./x.spec.mdx` code span
+	KindDepends = "depends" // frontmatter depends: entry
+	KindSpec    = "spec"    // frontmatter spec:/relatedSpec: entry (tasks pointing at their spec)
+)
+
+// Ref is a reference found in a document.
+type Ref struct {
+	Kind   string
+	Target string // raw target as written
+	Line   int    // 1-based file line
+	Col    int    // 1-based column (bytes)
+}
+
+var (
+	fenceRe    = regexp.MustCompile("^\\s{0,3}(```|~~~)")
+	headRe     = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	sectionRe  = regexp.MustCompile(`^(\d+(?:\.\d+)*)\.?(?:\s|$)`)
+	codeRe     = regexp.MustCompile("`([^`]+)`")
+	mdLinkRe   = regexp.MustCompile(`!?\[[^\]]*\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	refDefRe   = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s*<?(\S+?)>?(?:\s+"[^"]*")?\s*$`)
+	specRefRe  = regexp.MustCompile(`(?:^|[^\w/])spec:([A-Za-z0-9][\w.\-/]*[\w])(#[^\s)\]` + "`" + `]+)?`)
+	docPathRe  = regexp.MustCompile(`^\.{1,2}/\S+\.(?:spec|task)\.mdx(?:#\S*)?$`)
+	externalRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.\-]*:`)
+)
+
+// ParseBody returns the references and heading anchors of a Markdown body
+// starting at file line first. Fenced code blocks are skipped.
+func ParseBody(body string, first int) (refs []Ref, anchors map[string]bool) {
+	anchors = map[string]bool{}
+	seen := map[string]int{}
+	inFence := ""
+	for i, ln := range strings.Split(body, "\n") {
+		ln = strings.TrimRight(ln, "\r")
+		lineNo := first + i
+		if m := fenceRe.FindStringSubmatch(ln); m != nil {
+			switch {
+			case inFence == "":
+				inFence = m[1]
+			case inFence == m[1]:
+				inFence = ""
+			}
+			continue
+		}
+		if inFence != "" {
+			continue
+		}
+		if m := headRe.FindStringSubmatch(ln); m != nil {
+			h := heading(m, seen, lineNo)
+			anchors[h.Anchor] = true
+			if h.Number != "" {
+				anchors[h.Number] = true
+			}
+		}
+		if m := refDefRe.FindStringSubmatchIndex(ln); m != nil {
+			refs = append(refs, Ref{Kind: KindLink, Target: ln[m[2]:m[3]], Line: lineNo, Col: m[2] + 1})
+			continue
+		}
+		// code spans: keep doc paths and spec: refs, then blank them out
+		masked := []byte(ln)
+		for _, m := range codeRe.FindAllStringSubmatchIndex(ln, -1) {
+			code := strings.TrimSpace(ln[m[2]:m[3]])
+			if docPathRe.MatchString(code) {
+				refs = append(refs, Ref{Kind: KindRef, Target: code, Line: lineNo, Col: m[2] + 1})
+			}
+			for j := m[0]; j < m[1]; j++ {
+				masked[j] = ' '
+			}
+			for _, sm := range specRefRe.FindAllStringSubmatchIndex(ln[m[2]:m[3]], -1) {
+				refs = append(refs, Ref{Kind: KindRef, Target: specTarget(ln[m[2]:m[3]], sm), Line: lineNo, Col: m[2] + sm[2] - len("spec:") + 1})
+			}
+		}
+		rest := string(masked)
+		for _, m := range mdLinkRe.FindAllStringSubmatchIndex(rest, -1) {
+			refs = append(refs, Ref{Kind: KindLink, Target: rest[m[2]:m[3]], Line: lineNo, Col: m[2] + 1})
+		}
+		for _, m := range specRefRe.FindAllStringSubmatchIndex(rest, -1) {
+			refs = append(refs, Ref{Kind: KindRef, Target: specTarget(rest, m), Line: lineNo, Col: m[2] - len("spec:") + 1})
+		}
+	}
+	return refs, anchors
+}
+
+// Heading is a Markdown heading of a document.
+type Heading struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor"`           // slug, "-N" suffixed for repeats
+	Number string `json:"number,omitempty"` // leading section number such as "4.1"
+	Line   int    `json:"line"`
+}
+
+// Headings returns the headings of a Markdown body starting at file line
+// first, outside fenced code blocks.
+func Headings(body string, first int) []Heading {
+	var out []Heading
+	seen := map[string]int{}
+	inFence := ""
+	for i, ln := range strings.Split(body, "\n") {
+		ln = strings.TrimRight(ln, "\r")
+		if m := fenceRe.FindStringSubmatch(ln); m != nil {
+			switch {
+			case inFence == "":
+				inFence = m[1]
+			case inFence == m[1]:
+				inFence = ""
+			}
+			continue
+		}
+		if inFence != "" {
+			continue
+		}
+		if m := headRe.FindStringSubmatch(ln); m != nil {
+			out = append(out, heading(m, seen, first+i))
+		}
+	}
+	return out
+}
+
+// heading builds a Heading from a headRe match; seen counts slugs so far.
+func heading(m []string, seen map[string]int, line int) Heading {
+	h := Heading{Level: len(m[1]), Text: m[2], Line: line}
+	slug := Slug(h.Text)
+	h.Anchor = slug
+	if n := seen[slug]; n > 0 {
+		h.Anchor = slug + "-" + strconv.Itoa(n)
+	}
+	seen[slug]++
+	if sm := sectionRe.FindStringSubmatch(h.Text); sm != nil {
+		h.Number = sm[1]
+	}
+	return h
+}
+
+// specTarget rebuilds "spec:name#anchor" from a specRefRe match.
+func specTarget(s string, m []int) string {
+	t := "spec:" + s[m[2]:m[3]]
+	if m[4] >= 0 {
+		t += s[m[4]:m[5]]
+	}
+	return t
+}
+
+// Slug returns the GitHub-style heading anchor for text: lower-cased,
+// punctuation removed and spaces turned into hyphens. Letters of any script
+// are kept.
+func Slug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// IsExternal reports whether target is a URL (http:, mailto:, ...) rather
+// than a repository path. spec: references are not external.
+func IsExternal(target string) bool {
+	return externalRe.MatchString(target) && !strings.HasPrefix(target, "spec:")
+}
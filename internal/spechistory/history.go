@@ -0,0 +1,260 @@
+// Package spechistory reads the git history of spec documents: the last
+// modification per file (used to render `lastUpdated: {auto}`) and the
+// commits that touched a document with their frontmatter field changes.
+package spechistory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"codectl/internal/document"
+)
+
+// AutoPlaceholder is the frontmatter value resolved from history.
+const AutoPlaceholder = "{auto}"
+
+// DateLayout is the format of resolved lastUpdated values.
+const DateLayout = "2006-01-02"
+
+// Commit is one commit that touched a document.
+type Commit struct {
+	SHA     string    `json:"sha"`
+	Short   string    `json:"short"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email,omitempty"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+	// Path is the repository-relative path of the document in this commit
+	// (it differs from the current path across renames).
+	Path string `json:"path"`
+	// Changes lists the frontmatter fields changed by the commit.
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange is a frontmatter field changed by a commit. From is empty for
+// added fields and To for removed ones.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// ErrNoGit is returned when git is unavailable.
+var ErrNoGit = errors.New("git not available")
+
+const (
+	recSep   = "\x1e"
+	fieldSep = "\x1f"
+	logFmt   = "--format=" + recSep + "%H" + fieldSep + "%h" + fieldSep + "%an" + fieldSep + "%ae" + fieldSep + "%aI" + fieldSep + "%s"
+)
+
+func git(ctx context.Context, root string, args ...string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", ErrNoGit
+	}
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", root}, args...)...)
+	var out, errb bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errb
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(errb.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	return out.String(), nil
+}
+
+type entry struct {
+	Commit
+	files []string
+}
+
+// parseLog parses `git log logFmt --name-only` output.
+func parseLog(out string) []entry {
+	var entries []entry
+	for _, rec := range strings.Split(out, recSep) {
+		rec = strings.TrimSpace(rec)
+		if rec == "" {
+			continue
+		}
+		head, files, _ := strings.Cut(rec, "\n")
+		f := strings.Split(head, fieldSep)
+		if len(f) < 6 {
+			continue
+		}
+		e := entry{Commit: Commit{SHA: f[0], Short: f[1], Author: f[2], Email: f[3], Subject: f[5]}}
+		e.Date, _ = time.Parse(time.RFC3339, f[4])
+		for _, ln := range strings.Split(files, "\n") {
+			if ln = strings.TrimSpace(ln); ln != "" {
+				e.files = append(e.files, ln)
+			}
+		}
+		if len(e.files) > 0 {
+			e.Path = e.files[0]
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// LastModified returns the latest commit touching each file below dir
+// (repository-relative paths, forward slashes), in a single git call.
+func LastModified(ctx context.Context, root, dir string) (map[string]Commit, error) {
+	out, err := git(ctx, root, "log", logFmt, "--name-only", "--", filepath.ToSlash(dir))
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]Commit{}
+	for _, e := range parseLog(out) {
+		for _, f := range e.files {
+			if _, seen := res[f]; !seen {
+				c := e.Commit
+				c.Path = f
+				res[f] = c
+			}
+		}
+	}
+	return res, nil
+}
+
+// Last returns the latest commit touching rel; ok is false when the file has
+// no history.
+func Last(ctx context.Context, root, rel string) (c Commit, ok bool, err error) {
+	out, err := git(ctx, root, "log", "-1", logFmt, "--name-only", "--", filepath.ToSlash(rel))
+	if err != nil {
+		return Commit{}, false, err
+	}
+	es := parseLog(out)
+	if len(es) == 0 {
+		return Commit{}, false, nil
+	}
+	return es[0].Commit, true, nil
+}
+
+// Log limits. Each returned commit costs one `git show`, so the number of
+// commits is always bounded.
+const (
+	DefaultLogLimit = 50
+	MaxLogLimit     = 500
+)
+
+// LogLimit returns limit clamped to MaxLogLimit; limit <= 0 means
+// DefaultLogLimit.
+func LogLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultLogLimit
+	}
+	return min(limit, MaxLogLimit)
+}
+
+// Log returns up to limit commits touching rel (following renames), newest
+// first, with the frontmatter fields each commit changed. The limit is
+// clamped by LogLimit.
+func Log(ctx context.Context, root, rel string, limit int) ([]Commit, error) {
+	limit = LogLimit(limit)
+	// one extra commit to diff the oldest returned one against
+	args := []string{"log", "--follow", logFmt, "--name-only", "-n", strconv.Itoa(limit + 1)}
+	out, err := git(ctx, root, append(args, "--", filepath.ToSlash(rel))...)
+	if err != nil {
+		return nil, err
+	}
+	entries := parseLog(out)
+	commits := make([]Commit, len(entries))
+	for i, e := range entries {
+		commits[i] = e.Commit
+	}
+	fields := make([]map[string]string, len(commits))
+	for i, c := range commits {
+		fields[i] = fieldsAt(ctx, root, c.SHA, c.Path)
+	}
+	for i := range commits {
+		var prev map[string]string
+		if i+1 < len(fields) {
+			prev = fields[i+1]
+		} else if len(commits) <= limit {
+			prev = map[string]string{} // first commit of the file
+		} else {
+			continue
+		}
+		commits[i].Changes = diffFields(prev, fields[i])
+	}
+	if len(commits) > limit {
+		commits = commits[:limit]
+	}
+	return commits, nil
+}
+
+// fieldsAt returns the frontmatter fields of path at commit sha.
+func fieldsAt(ctx context.Context, root, sha, path string) map[string]string {
+	out, err := git(ctx, root, "show", sha+":"+path)
+	if err != nil {
+		return map[string]string{}
+	}
+	doc, err := document.Parse([]byte(out))
+	if err != nil {
+		return map[string]string{}
+	}
+	return doc.Fields()
+}
+
+func diffFields(prev, cur map[string]string) []FieldChange {
+	var out []FieldChange
+	for k, v := range cur {
+		if pv, ok := prev[k]; !ok || pv != v {
+			out = append(out, FieldChange{Field: k, From: prev[k], To: v})
+		}
+	}
+	for k, v := range prev {
+		if _, ok := cur[k]; !ok {
+			out = append(out, FieldChange{Field: k, From: v})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	return out
+}
+
+// ResolveAuto replaces an `{auto}` lastUpdated value in fields with the date
+// of c. It reports whether a value was replaced.
+func ResolveAuto(fields map[string]string, c Commit) bool {
+	if fields == nil || strings.TrimSpace(fields["lastUpdated"]) != AutoPlaceholder || c.Date.IsZero() {
+		return false
+	}
+	fields["lastUpdated"] = c.Date.Format(DateLayout)
+	return true
+}
+
+// ErrNotInRevision is returned by Show when path does not exist at rev.
+var ErrNotInRevision = errors.New("path not in revision")
+
+// ErrUnknownRevision is returned when rev names no commit.
+var ErrUnknownRevision = errors.New("unknown revision")
+
+// Verify returns ErrUnknownRevision unless rev names a commit of the
+// repository at root.
+func Verify(ctx context.Context, root, rev string) error {
+	if _, err := git(ctx, root, "rev-parse", "--verify", "--quiet", rev+"^{commit}"); err != nil {
+		return fmt.Errorf("%w %q", ErrUnknownRevision, rev)
+	}
+	return nil
+}
+
+// Show returns the content of the repository-relative path at rev.
+func Show(ctx context.Context, root, rev, path string) ([]byte, error) {
+	if err := Verify(ctx, root, rev); err != nil {
+		return nil, err
+	}
+	out, err := git(ctx, root, "show", rev+":"+filepath.ToSlash(path))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotInRevision, err)
+	}
+	return []byte(out), nil
+}
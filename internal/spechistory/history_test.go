@@ -0,0 +1,80 @@
+package spechistory
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func run(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Ada", "GIT_AUTHOR_EMAIL=ada@example.com",
+		"GIT_COMMITTER_NAME=Ada", "GIT_COMMITTER_EMAIL=ada@example.com",
+		"GIT_AUTHOR_DATE=2025-09-20T10:00:00+08:00", "GIT_COMMITTER_DATE=2025-09-20T10:00:00+08:00",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestLog(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	root := t.TempDir()
+	run(t, root, "init", "-q")
+	spec := filepath.Join(root, "vibe-docs", "spec")
+	_ = os.MkdirAll(spec, 0o755)
+	doc := filepath.Join(spec, "a.spec.mdx")
+	_ = os.WriteFile(doc, []byte("---\ntitle: A\nstatus: draft\nspecVersion: 0.1.0\nlastUpdated: {auto}\n---\nbody\n"), 0o644)
+	run(t, root, "add", "-A")
+	run(t, root, "commit", "-q", "-m", "add spec")
+	_ = os.WriteFile(doc, []byte("---\ntitle: A\nstatus: accepted\nspecVersion: 0.2.0\nlastUpdated: {auto}\n---\nbody\n"), 0o644)
+	run(t, root, "commit", "-q", "-am", "accept spec")
+
+	ctx := context.Background()
+	commits, err := Log(ctx, root, "vibe-docs/spec/a.spec.mdx", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 || commits[0].Subject != "accept spec" || commits[0].Author != "Ada" {
+		t.Fatalf("commits = %+v", commits)
+	}
+	ch := commits[0].Changes
+	if len(ch) != 2 || ch[0].Field != "specVersion" || ch[0].To != "0.2.0" || ch[1].Field != "status" || ch[1].From != "draft" {
+		t.Fatalf("changes = %+v", ch)
+	}
+	if len(commits[1].Changes) != 4 {
+		t.Fatalf("first commit should add all fields: %+v", commits[1].Changes)
+	}
+	// a limit keeps field changes of the oldest returned commit
+	limited, err := Log(ctx, root, "vibe-docs/spec/a.spec.mdx", 1)
+	if err != nil || len(limited) != 1 || len(limited[0].Changes) != 2 {
+		t.Fatalf("limited = %+v, %v", limited, err)
+	}
+
+	last, err := LastModified(ctx, root, "vibe-docs/spec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := last["vibe-docs/spec/a.spec.mdx"]
+	if !ok || c.Subject != "accept spec" {
+		t.Fatalf("last = %+v", last)
+	}
+	fields := map[string]string{"lastUpdated": "{auto}"}
+	if !ResolveAuto(fields, c) || fields["lastUpdated"] != "2025-09-20" {
+		t.Fatalf("resolved = %q", fields["lastUpdated"])
+	}
+}
+
+func TestLogLimit(t *testing.T) {
+	for in, want := range map[int]int{0: DefaultLogLimit, -1: DefaultLogLimit, 7: 7, MaxLogLimit + 1: MaxLogLimit} {
+		if got := LogLimit(in); got != want {
+			t.Errorf("LogLimit(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// Package specmove renames spec and task documents and rewrites the links,
+// spec: references and frontmatter entries that point at them.
+package specmove
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"codectl/internal/safefs"
+	"codectl/internal/specgraph"
+)
+
+// Edit is one rewritten reference.
+type Edit struct {
+	Path string `json:"path"` // repository-relative, after the move
+	Line int    `json:"line"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// Plan is a rename and the reference rewrites it needs.
+type Plan struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Edits []Edit `json:"edits"`
+
+	root    string
+	content map[string][]byte // rewritten files keyed by their path before the move
+}
+
+// Find resolves name to a document: a repository-relative path, a spec
+// file name with or without its suffix, or a unique number prefix.
+func Find(g *specgraph.Graph, name string) (string, error) {
+	name = filepath.ToSlash(strings.TrimSpace(name))
+	if _, ok := g.Node(path.Clean(name)); ok {
+		return path.Clean(name), nil
+	}
+	if e, ok := g.Resolve("", "spec:"+name); ok && !e.Broken {
+		return e.To, nil
+	}
+	var matches []string
+	for _, n := range g.Nodes {
+		if strings.HasPrefix(path.Base(n.ID), name+"-") {
+			matches = append(matches, n.ID)
+		}
+	}
+	if len(matches) > 1 {
+		return "", fmt.Errorf("%q is ambiguous: %s", name, strings.Join(matches, ", "))
+	}
+	return "", fmt.Errorf("no spec or task document matches %q", name)
+}
+
+var numRe = regexp.MustCompile(`^(\d+)-`)
+
+// Target returns the new path of from for dest: a repository-relative path
+// when dest contains a slash, a bare number that replaces the number prefix
+// of from, or a file name in the directory of from. The document suffix is
+// added when missing.
+func Target(from, dest string) (string, error) {
+	dest = filepath.ToSlash(strings.TrimSpace(dest))
+	if dest == "" {
+		return "", errors.New("empty destination")
+	}
+	suffix := docSuffix(from)
+	dir, base := path.Dir(from), path.Base(from)
+	var to string
+	switch {
+	case strings.Contains(dest, "/"):
+		to = path.Clean(dest)
+	case isDigits(dest):
+		rest := strings.TrimPrefix(base, numRe.FindString(base))
+		to = path.Join(dir, dest+"-"+rest)
+	default:
+		to = path.Join(dir, dest)
+	}
+	if !strings.HasSuffix(to, suffix) {
+		to = strings.TrimSuffix(to, ".mdx") + suffix
+	}
+	if strings.HasPrefix(to, "../") || path.IsAbs(to) {
+		return "", fmt.Errorf("destination %q is outside the repository", dest)
+	}
+	return to, nil
+}
+
+func docSuffix(p string) string {
+	if strings.HasSuffix(strings.ToLower(p), ".task.mdx") {
+		return ".task.mdx"
+	}
+	return ".spec.mdx"
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// New plans moving the document from to the repository-relative path to.
+func New(root, from, to string) (*Plan, error) {
+	g, err := specgraph.Build(root)
+	if err != nil {
+		return nil, err
+	}
+	return plan(root, g, from, to)
+}
+
+func plan(root string, g *specgraph.Graph, from, to string) (*Plan, error) {
+	if _, ok := g.Node(from); !ok {
+		return nil, fmt.Errorf("%s is not a spec or task document", from)
+	}
+	if from == to {
+		return nil, fmt.Errorf("%s: source and destination are the same", from)
+	}
+	if _, err := safefs.Lstat(root, to); err == nil {
+		return nil, fmt.Errorf("%s already exists", to)
+	}
+	p := &Plan{From: from, To: to, root: root, content: map[string][]byte{}}
+	bySource := map[string][]specgraph.Edge{}
+	for _, e := range g.Edges {
+		if e.Reason == specgraph.ReasonMissing {
+			continue
+		}
+		if e.To == from || (e.From == from && path.Dir(from) != path.Dir(to)) {
+			bySource[e.From] = append(bySource[e.From], e)
+		}
+	}
+	srcs := make([]string, 0, len(bySource))
+	for s := range bySource {
+		srcs = append(srcs, s)
+	}
+	sort.Strings(srcs)
+	for _, src := range srcs {
+		if err := p.rewrite(src, bySource[src]); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// rewrite replaces the references of edges in the file src. References are
+// located by searching their text from the recorded position on, so several
+// references on one line or frontmatter list entries below their key are
+// handled in order.
+func (p *Plan) rewrite(src string, edges []specgraph.Edge) error {
+	b, err := safefs.ReadFile(p.root, src)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(b), "\n")
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].Line != edges[j].Line {
+			return edges[i].Line < edges[j].Line
+		}
+		return edges[i].Col < edges[j].Col
+	})
+	newSrc := src
+	if src == p.From {
+		newSrc = p.To
+	}
+	curLine, curCol := 0, 0
+	changed := false
+	for _, e := range edges {
+		repl := p.retarget(e, newSrc)
+		if repl == e.Target {
+			continue
+		}
+		li, col := e.Line-1, max(e.Col-1, 0)
+		if li < curLine || (li == curLine && col < curCol) {
+			li, col = curLine, curCol
+		}
+		found := false
+		for ; li < len(lines) && li < e.Line+100; li, col = li+1, 0 {
+			if col > len(lines[li]) {
+				continue
+			}
+			if k := strings.Index(lines[li][col:], e.Target); k >= 0 {
+				at := col + k
+				lines[li] = lines[li][:at] + repl + lines[li][at+len(e.Target):]
+				curLine, curCol = li, at+len(repl)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s:%d: reference %q not found", src, e.Line, e.Target)
+		}
+		p.Edits = append(p.Edits, Edit{Path: newSrc, Line: curLine + 1, Old: e.Target, New: repl})
+		changed = true
+	}
+	if changed {
+		p.content[src] = []byte(strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+// retarget returns the reference text of e after the move, written in the
+// same style: spec: names stay names or numbers, repository paths stay
+// repository paths and relative links are recomputed from newSrc.
+func (p *Plan) retarget(e specgraph.Edge, newSrc string) string {
+	dest := e.To
+	if dest == p.From {
+		dest = p.To
+	}
+	anchor := ""
+	if e.Anchor != "" {
+		anchor = "#" + e.Anchor
+	}
+	if name, ok := strings.CutPrefix(e.Target, "spec:"); ok {
+		name, _, _ = strings.Cut(name, "#")
+		return "spec:" + specName(name, dest) + anchor
+	}
+	pathPart, _, _ := strings.Cut(e.Target, "#")
+	switch {
+	case pathPart == "":
+		return e.Target
+	case strings.HasPrefix(pathPart, "/"):
+		return "/" + dest + anchor
+	case (e.Kind == specgraph.KindDepends || e.Kind == specgraph.KindSpec) && strings.HasPrefix(pathPart, "vibe-docs/"):
+		return dest + anchor
+	}
+	rel := relPath(path.Dir(newSrc), dest)
+	if strings.HasPrefix(pathPart, "./") && !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel + anchor
+}
+
+// specName writes dest in the style of the spec: name old.
+func specName(old, dest string) string {
+	base := path.Base(dest)
+	switch {
+	case path.Dir(dest) != specgraph.Dirs[0] || strings.Contains(old, "/"):
+		return dest
+	case isDigits(old):
+		if m := numRe.FindStringSubmatch(base); m != nil {
+			return m[1]
+		}
+	case strings.HasSuffix(old, ".spec.mdx"):
+		return base
+	case strings.HasSuffix(old, ".mdx"):
+		return strings.TrimSuffix(base, ".spec.mdx") + ".mdx"
+	}
+	return strings.TrimSuffix(base, ".spec.mdx")
+}
+
+// relPath returns the slash path of target relative to dir.
+func relPath(dir, target string) string {
+	r, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(target))
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(r)
+}
+
+// Apply renames the document and writes the rewritten references. Files
+// are only touched through safefs, so no path, symlink included, leads
+// outside the repository.
+func (p *Plan) Apply() error {
+	if err := safefs.Rename(p.root, p.From, p.To); err != nil {
+		return err
+	}
+	for src, b := range p.content {
+		dst := src
+		if src == p.From {
+			dst = p.To
+		}
+		mode := os.FileMode(0o644)
+		if st, err := safefs.Lstat(p.root, dst); err == nil {
+			mode = st.Mode().Perm()
+		}
+		if err := safefs.WriteFile(p.root, dst, b, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package specmove
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"codectl/internal/specgraph"
+)
+
+func write(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, root, rel string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestTarget(t *testing.T) {
+	cases := map[string]string{
+		"203":                  "vibe-docs/spec/203-tui.spec.mdx",
+		"dash":                 "vibe-docs/spec/dash.spec.mdx",
+		"dash.spec.mdx":        "vibe-docs/spec/dash.spec.mdx",
+		"vibe-docs/spec/old/x": "vibe-docs/spec/old/x.spec.mdx",
+	}
+	for dest, want := range cases {
+		if got, err := Target("vibe-docs/spec/201-tui.spec.mdx", dest); err != nil || got != want {
+			t.Errorf("Target(%q) = %q, %v; want %q", dest, got, err, want)
+		}
+	}
+	if got, _ := Target("vibe-docs/spec/webui.spec.mdx", "600"); got != "vibe-docs/spec/600-webui.spec.mdx" {
+		t.Errorf("numbering an unnumbered doc = %q", got)
+	}
+	if _, err := Target("vibe-docs/spec/a.spec.mdx", "../../../x"); err == nil {
+		t.Error("want error for a destination outside the repository")
+	}
+}
+
+func TestMove(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "vibe-docs/spec/201-tui.spec.mdx", "---\ntitle: TUI\n---\n# TUI\n\n## 概述\n\nSee [self](201-tui.spec.mdx#概述) and [overall](000-overall.spec.mdx).\n")
+	write(t, root, "vibe-docs/spec/000-overall.spec.mdx", "---\ntitle: Overall\ndepends:\n  - 201-tui.spec.mdx\n---\n# Overall\n\n- [TUI](./201-tui.spec.mdx#概述) or [again](./201-tui.spec.mdx), see `./201-tui.spec.mdx`\n- spec:201-tui#概述 and spec:201\n")
+	write(t, root, "vibe-docs/task/t.task.mdx", "---\ntitle: T\nrelatedSpec:\n  - vibe-docs/spec/201-tui.spec.mdx\n---\n# T\n\n[spec](../spec/201-tui.spec.mdx)\n")
+
+	g, err := specgraph.Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := Find(g, "201")
+	if err != nil || from != "vibe-docs/spec/201-tui.spec.mdx" {
+		t.Fatalf("Find = %q, %v", from, err)
+	}
+	p, err := New(root, from, "vibe-docs/spec/203-tui.spec.mdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Edits) != 9 {
+		t.Fatalf("edits = %+v", p.Edits)
+	}
+	if err := p.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "vibe-docs/spec/201-tui.spec.mdx")); !os.IsNotExist(err) {
+		t.Fatal("old file still exists")
+	}
+	overall := read(t, root, "vibe-docs/spec/000-overall.spec.mdx")
+	for _, want := range []string{"  - 203-tui.spec.mdx\n", "[TUI](./203-tui.spec.mdx#概述) or [again](./203-tui.spec.mdx)", "`./203-tui.spec.mdx`", "spec:203-tui#概述 and spec:203\n"} {
+		if !strings.Contains(overall, want) {
+			t.Errorf("overall misses %q:\n%s", want, overall)
+		}
+	}
+	if got := read(t, root, "vibe-docs/task/t.task.mdx"); !strings.Contains(got, "  - vibe-docs/spec/203-tui.spec.mdx\n") || !strings.Contains(got, "(../spec/203-tui.spec.mdx)") {
+		t.Errorf("task = %s", got)
+	}
+	if got := read(t, root, "vibe-docs/spec/203-tui.spec.mdx"); !strings.Contains(got, "[self](203-tui.spec.mdx#概述)") {
+		t.Errorf("moved = %s", got)
+	}
+
+	g, err = specgraph.Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range g.Edges {
+		if e.Broken {
+			t.Errorf("broken after move: %+v", e)
+		}
+	}
+}
+
+func TestMoveToOtherDir(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "vibe-docs/spec/a.spec.mdx", "---\ntitle: A\n---\n# A\n\n[b](b.spec.mdx) [self](#a)\n")
+	write(t, root, "vibe-docs/spec/b.spec.mdx", "---\ntitle: B\n---\n# B\n")
+	p, err := New(root, "vibe-docs/spec/a.spec.mdx", "vibe-docs/spec/archive/a.spec.mdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, root, "vibe-docs/spec/archive/a.spec.mdx"); !strings.Contains(got, "[b](../b.spec.mdx) [self](#a)") {
+		t.Errorf("moved = %s", got)
+	}
+	if _, err := New(root, "vibe-docs/spec/b.spec.mdx", "vibe-docs/spec/archive/a.spec.mdx"); err == nil {
+		t.Error("want error when the destination exists")
+	}
+}
+
+func TestMoveStaysInRepo(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+	root := t.TempDir()
+	outside := t.TempDir()
+	write(t, root, "vibe-docs/spec/a.spec.mdx", "---\ntitle: A\n---\n# A\n")
+	if err := os.Symlink(outside, filepath.Join(root, "vibe-docs", "spec", "out")); err != nil {
+		t.Fatal(err)
+	}
+	p, err := New(root, "vibe-docs/spec/a.spec.mdx", "vibe-docs/spec/out/a.spec.mdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Apply(); err == nil {
+		t.Fatal("move through a symlink out of the repository succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "a.spec.mdx")); !os.IsNotExist(err) {
+		t.Fatalf("document written outside the repository: %v", err)
+	}
+	if got := read(t, root, "vibe-docs/spec/a.spec.mdx"); !strings.Contains(got, "# A") {
+		t.Errorf("source changed: %s", got)
+	}
+}
@@ -0,0 +1,55 @@
+// Client-side search over window.SEARCH_INDEX (written by codectl spec export).
+(function () {
+  var input = document.getElementById('search');
+  var list = document.getElementById('results');
+  var root = document.body.getAttribute('data-root') || '';
+  var index = window.SEARCH_INDEX || [];
+  if (!input || !list) return;
+
+  function esc(s) {
+    return String(s).replace(/[&<>"']/g, function (c) {
+      return { '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' }[c];
+    });
+  }
+
+  function snippet(text, term) {
+    var i = text.toLowerCase().indexOf(term);
+    if (i < 0) return '';
+    var start = Math.max(0, i - 30);
+    return (start > 0 ? '…' : '') + text.slice(start, i + term.length + 60) + '…';
+  }
+
+  function search(q) {
+    var terms = q.toLowerCase().split(/\s+/).filter(Boolean);
+    if (!terms.length) return [];
+    var out = [];
+    index.forEach(function (d) {
+      var title = d.title.toLowerCase();
+      var heads = d.headings.join(' ').toLowerCase();
+      var text = d.text.toLowerCase();
+      var score = 0;
+      for (var k = 0; k < terms.length; k++) {
+        var t = terms[k];
+        var s = (title.indexOf(t) >= 0 ? 10 : 0) + (heads.indexOf(t) >= 0 ? 4 : 0) + (text.indexOf(t) >= 0 ? 1 : 0);
+        if (!s) return;
+        score += s;
+      }
+      out.push({ doc: d, score: score });
+    });
+    out.sort(function (a, b) { return b.score - a.score; });
+    return out.slice(0, 20).map(function (r) { return r.doc; });
+  }
+
+  input.addEventListener('input', function () {
+    var q = input.value.trim();
+    var hits = search(q);
+    list.hidden = !q;
+    if (!q) { list.innerHTML = ''; return; }
+    if (!hits.length) { list.innerHTML = '<li><small>无结果 / No results</small></li>'; return; }
+    var first = q.toLowerCase().split(/\s+/)[0];
+    list.innerHTML = hits.map(function (d) {
+      return '<li><a href="' + esc(root + d.url) + '">' + esc(d.title) + '</a><small>' +
+        esc(d.path) + '</small><small>' + esc(snippet(d.text, first)) + '</small></li>';
+    }).join('');
+  });
+})();
@@ -0,0 +1,379 @@
+// Package specsite exports spec and task documents to a static HTML site:
+// one page per document with a file-tree sidebar, frontmatter badges and
+// resolved cross-links, an overview, a combined changelog and a client-side
+// search index. Only Go and the embedded assets are needed.
+package specsite
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"codectl/internal/document"
+	"codectl/internal/mdhtml"
+	"codectl/internal/specgraph"
+	"codectl/internal/spechistory"
+	"codectl/internal/specversion"
+)
+
+//go:embed assets
+var assets embed.FS
+
+var pageTmpl = template.Must(template.ParseFS(assets, "assets/page.html"))
+
+// doc is an exported document.
+type doc struct {
+	Path   string // repository-relative source path
+	URL    string // site-relative page path
+	Kind   string
+	Title  string
+	Fields map[string]string
+	Body   string
+}
+
+// Result summarizes an export.
+type Result struct {
+	Out   string   `json:"out"`
+	Docs  int      `json:"docs"`
+	Files []string `json:"files"` // site-relative, sorted
+}
+
+// docsPrefix is stripped from document paths to form page paths.
+const docsPrefix = "vibe-docs/"
+
+// PageURL returns the site-relative page of a repository-relative document
+// path: "vibe-docs/spec/a.spec.mdx" becomes "spec/a.spec.html".
+func PageURL(rel string) string {
+	rel = strings.TrimPrefix(rel, docsPrefix)
+	return strings.TrimSuffix(rel, path.Ext(rel)) + ".html"
+}
+
+// Export renders the documents of the repository at root into out.
+// Existing files in out are overwritten; nothing is deleted.
+func Export(ctx context.Context, root, out string) (*Result, error) {
+	g, err := specgraph.Build(root)
+	if err != nil {
+		return nil, err
+	}
+	// {auto} dates from git; the export works without history
+	history, _ := spechistory.LastModified(ctx, root, "vibe-docs")
+
+	docs := make([]*doc, 0, len(g.Nodes))
+	for _, n := range g.Nodes {
+		b, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(n.ID)))
+		if err != nil {
+			return nil, err
+		}
+		d := &doc{Path: n.ID, URL: PageURL(n.ID), Kind: n.Kind, Title: n.Title, Fields: map[string]string{}, Body: string(b)}
+		if pd, err := document.Parse(b); err == nil {
+			d.Fields = pd.Fields()
+			d.Body = pd.Body
+		}
+		if c, ok := history[n.ID]; ok {
+			spechistory.ResolveAuto(d.Fields, c)
+		}
+		if d.Title == "" {
+			d.Title = strings.TrimSuffix(path.Base(n.ID), ".mdx")
+		}
+		docs = append(docs, d)
+	}
+
+	s := &site{g: g, docs: docs, out: out, res: &Result{Out: out, Docs: len(docs)}}
+	for _, d := range docs {
+		if err := s.docPage(d); err != nil {
+			return nil, err
+		}
+	}
+	for _, step := range []func() error{s.indexPage, s.changelogPage, s.searchIndex, s.copyAssets} {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(s.res.Files)
+	return s.res, nil
+}
+
+type site struct {
+	g    *specgraph.Graph
+	docs []*doc
+	out  string
+	res  *Result
+}
+
+type badge struct{ Class, Label string }
+
+type page struct {
+	Title   string
+	Root    string
+	Sidebar template.HTML
+	Meta    []badge
+	Source  string
+	Content template.HTML
+}
+
+func (s *site) write(rel string, b []byte) error {
+	full := filepath.Join(s.out, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(full, b, 0o644); err != nil {
+		return err
+	}
+	s.res.Files = append(s.res.Files, rel)
+	return nil
+}
+
+func (s *site) render(url string, p page) error {
+	p.Root = rootOf(url)
+	p.Sidebar = s.sidebar(url)
+	var buf bytes.Buffer
+	if err := pageTmpl.Execute(&buf, p); err != nil {
+		return err
+	}
+	return s.write(url, buf.Bytes())
+}
+
+// rootOf returns the relative prefix from page url to the site root.
+func rootOf(url string) string {
+	return strings.Repeat("../", strings.Count(url, "/"))
+}
+
+// relURL returns the link from page from to page to.
+func relURL(from, to string) string {
+	return rootOf(from) + to
+}
+
+func (s *site) docPage(d *doc) error {
+	content := s.markdown(d, d.Body)
+	var meta []badge
+	for _, k := range []string{"status", "specVersion", "priority", "owner", "due", "lastUpdated"} {
+		v := strings.TrimSpace(d.Fields[k])
+		if v == "" || v == spechistory.AutoPlaceholder {
+			continue
+		}
+		b := badge{Label: v}
+		switch k {
+		case "status":
+			b.Class = "status-" + classOf(v)
+		case "specVersion":
+			b.Label = "v" + strings.TrimPrefix(v, "v")
+		default:
+			b.Label = k + ": " + v
+		}
+		meta = append(meta, b)
+	}
+	return s.render(d.URL, page{Title: d.Title, Meta: meta, Source: d.Path, Content: template.HTML(content)})
+}
+
+var classRe = regexp.MustCompile(`[^a-z0-9-]+`)
+
+func classOf(v string) string {
+	return classRe.ReplaceAllString(strings.ToLower(strings.TrimSpace(v)), "-")
+}
+
+// markdown renders body of document d with links rewritten to pages.
+func (s *site) markdown(d *doc, body string) string {
+	out, _ := mdhtml.Render(body, mdhtml.Options{
+		Slug: specgraph.Slug,
+		Link: func(dest string) string { return s.link(d, dest) },
+		SpecRef: func(ref string) (string, bool) {
+			href := s.link(d, ref)
+			return href, href != ref
+		},
+	})
+	return out
+}
+
+// link rewrites a link in d to the page of the document it resolves to;
+// other links are kept as written, except external ones with a scheme
+// mdhtml.SafeURL does not allow.
+func (s *site) link(d *doc, dest string) string {
+	if specgraph.IsExternal(dest) {
+		return mdhtml.SafeURL(dest)
+	}
+	if dest == "" || strings.HasPrefix(dest, "#") {
+		return dest
+	}
+	e, ok := s.g.Resolve(d.Path, dest)
+	if !ok || e.Reason == specgraph.ReasonMissing {
+		return dest
+	}
+	href := relURL(d.URL, PageURL(e.To))
+	if e.Anchor != "" {
+		if a, ok := s.g.HeadingAnchor(e.To, e.Anchor); ok {
+			href += "#" + a
+		} else {
+			href += "#" + e.Anchor
+		}
+	}
+	return href
+}
+
+// sidebar renders the document tree, marking the page current.
+func (s *site) sidebar(current string) template.HTML {
+	type dir struct {
+		name string
+		docs []*doc
+		subs map[string]*dir
+	}
+	top := &dir{subs: map[string]*dir{}}
+	for _, d := range s.docs {
+		parts := strings.Split(strings.TrimPrefix(d.Path, docsPrefix), "/")
+		cur := top
+		for _, p := range parts[:len(parts)-1] {
+			if cur.subs[p] == nil {
+				cur.subs[p] = &dir{name: p, subs: map[string]*dir{}}
+			}
+			cur = cur.subs[p]
+		}
+		cur.docs = append(cur.docs, d)
+	}
+	var b strings.Builder
+	var walk func(*dir)
+	walk = func(t *dir) {
+		b.WriteString("<ul>")
+		names := make([]string, 0, len(t.subs))
+		for n := range t.subs {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			b.WriteString(`<li><div class="dir">` + html.EscapeString(n) + "</div>")
+			walk(t.subs[n])
+			b.WriteString("</li>")
+		}
+		for _, d := range t.docs {
+			cls := ""
+			if d.URL == current {
+				cls = ` class="active"`
+			}
+			fmt.Fprintf(&b, `<li><a%s href="%s" title="%s"><span class="dot status-%s"></span>%s</a></li>`,
+				cls, html.EscapeString(relURL(current, d.URL)), html.EscapeString(d.Path),
+				html.EscapeString(classOf(d.Fields["status"])), html.EscapeString(d.Title))
+		}
+		b.WriteString("</ul>")
+	}
+	walk(top)
+	return template.HTML(b.String())
+}
+
+func (s *site) indexPage() error {
+	var b strings.Builder
+	b.WriteString("<h1>vibe-docs</h1>\n")
+	for _, kind := range []struct{ kind, title string }{{specgraph.NodeSpec, "规范 · Specs"}, {specgraph.NodeTask, "任务 · Tasks"}} {
+		var rows []*doc
+		for _, d := range s.docs {
+			if d.Kind == kind.kind {
+				rows = append(rows, d)
+			}
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "<h2>%s</h2>\n<table class=\"docs\">\n", html.EscapeString(kind.title))
+		cols := []string{"status", "specVersion", "lastUpdated"}
+		if kind.kind == specgraph.NodeTask {
+			cols = []string{"status", "priority", "owner", "due"}
+		}
+		b.WriteString("<thead><tr><th>title</th>")
+		for _, c := range cols {
+			b.WriteString("<th>" + c + "</th>")
+		}
+		b.WriteString("</tr></thead>\n<tbody>\n")
+		for _, d := range rows {
+			fmt.Fprintf(&b, `<tr><td class="title"><a href="%s">%s</a></td>`, html.EscapeString(d.URL), html.EscapeString(d.Title))
+			for _, c := range cols {
+				v := d.Fields[c]
+				if v == spechistory.AutoPlaceholder {
+					v = ""
+				}
+				if c == "status" && v != "" {
+					fmt.Fprintf(&b, `<td><span class="badge status-%s">%s</span></td>`, html.EscapeString(classOf(v)), html.EscapeString(v))
+					continue
+				}
+				b.WriteString("<td>" + html.EscapeString(v) + "</td>")
+			}
+			b.WriteString("</tr>\n")
+		}
+		b.WriteString("</tbody>\n</table>\n")
+	}
+	return s.render("index.html", page{Title: "vibe-docs", Content: template.HTML(b.String())})
+}
+
+func (s *site) changelogPage() error {
+	const url = "changelog.html"
+	var b strings.Builder
+	b.WriteString("<h1>变更记录 · Changelog</h1>\n")
+	for _, d := range s.docs {
+		log, ok := specversion.Changelog(d.Body)
+		if !ok || log == "" {
+			continue
+		}
+		// render as if the section lived in a page at the site root
+		rooted := *d
+		rooted.URL = url
+		fmt.Fprintf(&b, "<section>\n<h2><a href=\"%s\">%s</a></h2>\n", html.EscapeString(d.URL), html.EscapeString(d.Title))
+		b.WriteString(s.markdown(&rooted, log))
+		b.WriteString("</section>\n")
+	}
+	return s.render(url, page{Title: "变更记录", Content: template.HTML(b.String())})
+}
+
+type indexEntry struct {
+	URL      string   `json:"url"`
+	Path     string   `json:"path"`
+	Title    string   `json:"title"`
+	Status   string   `json:"status,omitempty"`
+	Headings []string `json:"headings"`
+	Text     string   `json:"text"`
+}
+
+var (
+	fenceBlockRe = regexp.MustCompile("(?s)```.*?```|~~~.*?~~~")
+	markupRe     = regexp.MustCompile("[#>*_`|]+|\\]\\([^)]*\\)|\\[")
+	spaceRe      = regexp.MustCompile(`\s+`)
+)
+
+// searchIndex writes search-index.js; a script rather than JSON so the site
+// also works from file:// URLs.
+func (s *site) searchIndex() error {
+	entries := make([]indexEntry, 0, len(s.docs))
+	for _, d := range s.docs {
+		e := indexEntry{URL: d.URL, Path: d.Path, Title: d.Title, Status: d.Fields["status"], Headings: []string{}}
+		for _, h := range specgraph.Headings(d.Body, 1) {
+			e.Headings = append(e.Headings, h.Text)
+		}
+		text := fenceBlockRe.ReplaceAllString(d.Body, " ")
+		e.Text = strings.TrimSpace(spaceRe.ReplaceAllString(markupRe.ReplaceAllString(text, " "), " "))
+		entries = append(entries, e)
+	}
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return s.write("search-index.js", []byte("window.SEARCH_INDEX = "+string(b)+";\n"))
+}
+
+func (s *site) copyAssets() error {
+	for _, name := range []string{"style.css", "site.js"} {
+		b, err := fs.ReadFile(assets, "assets/"+name)
+		if err != nil {
+			return err
+		}
+		if err := s.write("assets/"+name, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
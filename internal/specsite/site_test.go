@@ -0,0 +1,91 @@
+package specsite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func write(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, p string) string {
+	t.Helper()
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestExport(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "vibe-docs/spec/100-a.spec.mdx", "---\ntitle: A <spec>\nstatus: accepted\nspecVersion: 1.2.0\n---\n# A\n\n## 4. 接口\n\nSee [task](../task/t1.task.mdx) and spec:100#4.\n\n## 变更记录\n- 1.2.0（2025-01-01）：接口。\n")
+	write(t, root, "vibe-docs/task/t1.task.mdx", "---\ntitle: T1\nstatus: todo\nspec: vibe-docs/spec/100-a.spec.mdx#4\n---\n# T1\n\nImplements [接口](../spec/100-a.spec.mdx#4).\n")
+	out := filepath.Join(t.TempDir(), "site")
+
+	res, err := Export(context.Background(), root, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Docs != 2 {
+		t.Fatalf("result = %+v", res)
+	}
+	spec := read(t, filepath.Join(out, "spec", "100-a.spec.html"))
+	for _, want := range []string{
+		`<title>A &lt;spec&gt; · vibe-docs</title>`,
+		`<span class="badge status-accepted">accepted</span>`,
+		`<span class="badge">v1.2.0</span>`,
+		`<a href="../task/t1.task.html">task</a>`,
+		`<a href="../spec/100-a.spec.html#4-接口">spec:100#4</a>`,
+		`<a class="active" href="../spec/100-a.spec.html"`,
+		`<h2 id="4-接口">`,
+	} {
+		if !strings.Contains(spec, want) {
+			t.Errorf("spec page misses %q", want)
+		}
+	}
+	task := read(t, filepath.Join(out, "task", "t1.task.html"))
+	if !strings.Contains(task, `<a href="../spec/100-a.spec.html#4-接口">接口</a>`) {
+		t.Errorf("task page misses the resolved link:\n%s", task)
+	}
+	if log := read(t, filepath.Join(out, "changelog.html")); !strings.Contains(log, "1.2.0（2025-01-01）：接口。") || !strings.Contains(log, `href="spec/100-a.spec.html"`) {
+		t.Errorf("changelog page:\n%s", log)
+	}
+	if idx := read(t, filepath.Join(out, "search-index.js")); !strings.HasPrefix(idx, "window.SEARCH_INDEX = [") || !strings.Contains(idx, `"url":"task/t1.task.html"`) {
+		t.Errorf("search index:\n%s", idx)
+	}
+	for _, f := range []string{"index.html", "assets/style.css", "assets/site.js"} {
+		if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(f))); err != nil {
+			t.Errorf("missing %s", f)
+		}
+	}
+}
+
+func TestExport_UnsafeLinks(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "vibe-docs/spec/100-a.spec.mdx", "---\ntitle: A\n---\n# A\n\n[x](javascript:alert(document.cookie)) and [wiki](https://en.wikipedia.org/wiki/Go_(language)).\n")
+	out := filepath.Join(t.TempDir(), "site")
+	if _, err := Export(context.Background(), root, out); err != nil {
+		t.Fatal(err)
+	}
+	page := read(t, filepath.Join(out, "spec", "100-a.spec.html"))
+	if strings.Contains(page, "javascript:") {
+		t.Errorf("unsafe link exported:\n%s", page)
+	}
+	for _, want := range []string{`<a href="#">x</a> and`, `<a href="https://en.wikipedia.org/wiki/Go_(language)">wiki</a>.`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page misses %q", want)
+		}
+	}
+}
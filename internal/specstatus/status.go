@@ -0,0 +1,233 @@
+// Package specstatus implements the review workflow of spec documents:
+// status transitions checked against the configured state machine,
+// reviewers and approvals recorded in frontmatter, and the lock that keeps
+// accepted specs from being edited until they are reopened.
+package specstatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"codectl/internal/document"
+	"codectl/internal/speccheck"
+	"codectl/internal/spechistory"
+)
+
+// Frontmatter keys written by transitions.
+const (
+	KeyReviewers = "reviewers"
+	KeyApprovals = "approvals"
+)
+
+// Approval records who moved a spec into a locked state and when.
+type Approval struct {
+	By string `yaml:"by" json:"by"`
+	At string `yaml:"at" json:"at"`
+}
+
+// Info is the review state of one document.
+type Info struct {
+	Path      string     `json:"path"`
+	Title     string     `json:"title,omitempty"`
+	Status    string     `json:"status"`
+	Reviewers []string   `json:"reviewers,omitempty"`
+	Approvals []Approval `json:"approvals,omitempty"`
+	Locked    bool       `json:"locked"`
+	Next      []string   `json:"next"`
+}
+
+// Errors returned by Transition and CheckEdit.
+var (
+	ErrTransition = errors.New("transition not allowed")
+	ErrLocked     = errors.New("document is locked")
+)
+
+// Read returns the review state of document content b.
+func Read(wf speccheck.WorkflowRule, b []byte) (Info, error) {
+	wf = wf.Effective()
+	doc, err := document.Parse(b)
+	if err != nil {
+		return Info{}, err
+	}
+	in := Info{Title: doc.String("title"), Status: doc.String(wf.Field)}
+	in.Reviewers = doc.Strings(KeyReviewers)
+	if doc.Has(KeyApprovals) {
+		_ = doc.Decode(KeyApprovals, &in.Approvals)
+	}
+	in.Locked = wf.IsLocked(in.Status)
+	in.Next = append([]string{}, wf.Next(in.Status)...)
+	return in, nil
+}
+
+// Request is a status change.
+type Request struct {
+	To string
+	// By is recorded with the approval when To is a locked state.
+	By string
+	// Reviewers, when set, replace the reviewers list.
+	Reviewers []string
+	Now       time.Time
+}
+
+// Transition moves document content b to req.To. Entering a locked state
+// appends an approval; leaving one for an unlocked state (reopening) drops
+// the approvals, as they applied to the reopened text.
+func Transition(wf speccheck.WorkflowRule, b []byte, req Request) ([]byte, Info, error) {
+	wf = wf.Effective()
+	doc, err := document.Parse(b)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	from := doc.String(wf.Field)
+	to := strings.TrimSpace(req.To)
+	if !wf.Allowed(from, to) {
+		return nil, Info{}, fmt.Errorf("%w: %s → %s (allowed: %s)", ErrTransition, stateName(from), stateName(to), strings.Join(wf.Next(from), ", "))
+	}
+	if err := doc.Set(wf.Field, to); err != nil {
+		return nil, Info{}, err
+	}
+	if len(req.Reviewers) > 0 {
+		if err := doc.SetStrings(KeyReviewers, req.Reviewers); err != nil {
+			return nil, Info{}, err
+		}
+	}
+	switch {
+	case wf.IsLocked(to):
+		by := strings.TrimSpace(req.By)
+		if by == "" {
+			return nil, Info{}, fmt.Errorf("%s: approver required", to)
+		}
+		var approvals []Approval
+		if doc.Has(KeyApprovals) {
+			_ = doc.Decode(KeyApprovals, &approvals)
+		}
+		approvals = append(approvals, Approval{By: by, At: req.Now.Format(time.RFC3339)})
+		items := make([]string, len(approvals))
+		for i, a := range approvals {
+			items[i] = fmt.Sprintf("{by: %s, at: %s}", document.FormatString(a.By), document.FormatString(a.At))
+		}
+		if err := doc.SetRawList(KeyApprovals, items); err != nil {
+			return nil, Info{}, err
+		}
+	case wf.IsLocked(from):
+		doc.Delete(KeyApprovals)
+	}
+	out := doc.Bytes()
+	in, err := Read(wf, out)
+	return out, in, err
+}
+
+func stateName(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// CheckEdit rejects replacing content old with cur when old is locked and
+// cur changes the body or moves the status; status changes go through
+// Transition.
+func CheckEdit(wf speccheck.WorkflowRule, old, cur []byte) error {
+	wf = wf.Effective()
+	prev, err := document.Parse(old)
+	if err != nil {
+		return nil
+	}
+	state := prev.String(wf.Field)
+	if !wf.IsLocked(state) {
+		return nil
+	}
+	next, err := document.Parse(cur)
+	if err != nil || next.String(wf.Field) != state || normalize(next.Body) != normalize(prev.Body) {
+		return fmt.Errorf("%w: %s specs cannot be edited; reopen it first (allowed: %s)", ErrLocked, state, strings.Join(wf.Next(state), ", "))
+	}
+	return nil
+}
+
+func normalize(s string) string {
+	return strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+}
+
+// CheckAgainst compares content cur of the document at repository-relative
+// path rel with its version at git revision base: a body edited while the
+// status stayed locked, and a status change the workflow does not allow,
+// are reported. Documents absent at base are skipped.
+func CheckAgainst(ctx context.Context, root, rel, base string, cur []byte, wf speccheck.WorkflowRule) ([]speccheck.Finding, error) {
+	wf = wf.Effective()
+	old, err := spechistory.Show(ctx, root, base, rel)
+	if errors.Is(err, spechistory.ErrNotInRevision) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prev, err1 := document.Parse(old)
+	doc, err2 := document.Parse(cur)
+	if err1 != nil || err2 != nil {
+		return nil, nil
+	}
+	from, to := prev.String(wf.Field), doc.String(wf.Field)
+	line := 1
+	if pos, ok := doc.KeyPos(wf.Field); ok {
+		line = pos.Line
+	}
+	var out []speccheck.Finding
+	switch {
+	case from == to && wf.IsLocked(to) && normalize(prev.Body) != normalize(doc.Body):
+		out = append(out, speccheck.Finding{
+			Rule: speccheck.RuleLocked, Severity: wf.Severity, Line: doc.BodyLine(), Column: 1,
+			Message: fmt.Sprintf("%s spec edited since %s; reopen it with `codectl spec status %s %s` before editing", to, base, filepath.Base(rel), wf.Initial),
+		})
+	case from != to && wf.Known(from) && !wf.Allowed(from, to):
+		out = append(out, speccheck.Finding{
+			Rule: speccheck.RuleTransition, Severity: speccheck.SeverityWarning, Line: line, Column: 1,
+			Message: fmt.Sprintf("status changed %s → %s since %s, which the workflow does not allow (allowed: %s)", stateName(from), stateName(to), base, strings.Join(wf.Next(from), ", ")),
+		})
+	}
+	return out, nil
+}
+
+// List returns the review state of the spec documents under
+// vibe-docs/spec, sorted by path.
+func List(root string, rules *speccheck.Rules) ([]Info, error) {
+	dir := filepath.Join(root, "vibe-docs", "spec")
+	var out []Info
+	err := filepath.WalkDir(dir, func(p string, de os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if de.IsDir() || !strings.HasSuffix(de.Name(), ".spec.mdx") {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		rel := filepath.ToSlash(relOr(root, p))
+		in, err := Read(rules.For(rel).Workflow, b)
+		if err != nil {
+			in = Info{Next: []string{}}
+		}
+		in.Path = rel
+		out = append(out, in)
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out, err
+}
+
+func relOr(root, p string) string {
+	if r, err := filepath.Rel(root, p); err == nil {
+		return r
+	}
+	return p
+}
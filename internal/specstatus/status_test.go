@@ -0,0 +1,77 @@
+package specstatus
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"codectl/internal/speccheck"
+)
+
+const draft = "---\ntitle: A\nstatus: draft\n---\n# A\n\nbody\n"
+
+func TestTransition(t *testing.T) {
+	wf := speccheck.WorkflowRule{}
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+
+	if _, _, err := Transition(wf, []byte(draft), Request{To: "accepted", By: "ann", Now: now}); !errors.Is(err, ErrTransition) {
+		t.Fatalf("draft → accepted: err = %v", err)
+	}
+	b, in, err := Transition(wf, []byte(draft), Request{To: "review", Reviewers: []string{"ann", "bo"}, Now: now})
+	if err != nil || in.Status != "review" || strings.Join(in.Reviewers, ",") != "ann,bo" || in.Locked {
+		t.Fatalf("review: %+v, %v", in, err)
+	}
+	if _, _, err := Transition(wf, b, Request{To: "accepted", Now: now}); err == nil {
+		t.Fatal("accepting without an approver should fail")
+	}
+	b, in, err = Transition(wf, b, Request{To: "accepted", By: "ann", Now: now})
+	if err != nil || !in.Locked || len(in.Approvals) != 1 || in.Approvals[0] != (Approval{By: "ann", At: "2026-10-18T09:30:00Z"}) {
+		t.Fatalf("accepted: %+v, %v", in, err)
+	}
+	if !strings.Contains(string(b), "approvals:\n  - {by: ann, at: \"2026-10-18T09:30:00Z\"}\n") {
+		t.Fatalf("frontmatter:\n%s", b)
+	}
+	if strings.Join(in.Next, ",") != "deprecated,draft" {
+		t.Fatalf("next = %v", in.Next)
+	}
+	b, in, err = Transition(wf, b, Request{To: "draft", Now: now})
+	if err != nil || in.Locked || len(in.Approvals) != 0 || strings.Contains(string(b), "approvals") {
+		t.Fatalf("reopen: %+v, %v\n%s", in, err, b)
+	}
+}
+
+func TestTransitionConfigured(t *testing.T) {
+	wf := speccheck.WorkflowRule{
+		Field:       "state",
+		Initial:     "proposed",
+		Transitions: map[string][]string{"proposed": {"final"}, "final": {"proposed"}},
+		Locked:      []string{"final"},
+	}
+	// documents outside the workflow may only enter the initial state
+	in, _ := Read(wf, []byte("---\ntitle: A\nstate: wip\n---\n"))
+	if strings.Join(in.Next, ",") != "proposed" {
+		t.Fatalf("next = %v", in.Next)
+	}
+	b, in, err := Transition(wf, []byte("---\ntitle: A\nstate: proposed\n---\n"), Request{To: "final", By: "cy", Now: time.Now()})
+	if err != nil || !in.Locked || !strings.Contains(string(b), "state: final\n") {
+		t.Fatalf("final: %+v, %v", in, err)
+	}
+}
+
+func TestCheckEdit(t *testing.T) {
+	wf := speccheck.WorkflowRule{}
+	accepted := "---\ntitle: A\nstatus: accepted\n---\n# A\n\nbody\n"
+	if err := CheckEdit(wf, []byte(draft), []byte(draft+"more\n")); err != nil {
+		t.Fatalf("draft edit: %v", err)
+	}
+	if err := CheckEdit(wf, []byte(accepted), []byte(accepted+"more\n")); !errors.Is(err, ErrLocked) {
+		t.Fatalf("body edit: %v", err)
+	}
+	if err := CheckEdit(wf, []byte(accepted), []byte(strings.Replace(accepted, "accepted", "draft", 1))); !errors.Is(err, ErrLocked) {
+		t.Fatalf("status edit: %v", err)
+	}
+	if err := CheckEdit(wf, []byte(accepted), []byte(strings.Replace(accepted, "title: A", "title: B", 1))); err != nil {
+		t.Fatalf("frontmatter edit: %v", err)
+	}
+}
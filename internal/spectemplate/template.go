@@ -0,0 +1,203 @@
+// Package spectemplate provides named spec templates for `codectl spec new`:
+// built-in ones plus user templates in ~/.codectl/templates and repository
+// templates in vibe-docs/templates. Templates are Go text/template MDX files;
+// their level-2 headings are the sections a generated spec must keep.
+package spectemplate
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"codectl/internal/config"
+	"codectl/internal/speccheck"
+)
+
+//go:embed builtin/*.mdx
+var builtin embed.FS
+
+// Template sources, in increasing precedence.
+const (
+	SourceBuiltin = "builtin"
+	SourceUser    = "user"
+	SourceRepo    = "repo"
+)
+
+// RepoDir is the repository-relative directory of repository templates.
+const RepoDir = "vibe-docs/templates"
+
+// Ext is the file extension of templates.
+const Ext = ".mdx"
+
+// Template is a named spec template.
+type Template struct {
+	Name        string `json:"name"`
+	Source      string `json:"source"`
+	Path        string `json:"path,omitempty"` // empty for built-ins
+	Description string `json:"description,omitempty"`
+	text        string
+}
+
+// ErrNotFound is returned for unknown template names.
+var ErrNotFound = errors.New("template not found")
+
+// UserDir returns ~/.codectl/templates.
+func UserDir() (string, error) {
+	dir, err := config.DotDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "templates"), nil
+}
+
+// List returns the templates visible from the repository at root, one per
+// name, repository templates shadowing user ones shadowing built-ins.
+func List(root string) ([]Template, error) {
+	byName := map[string]Template{}
+	entries, _ := fs.ReadDir(builtin, "builtin")
+	for _, e := range entries {
+		b, err := fs.ReadFile(builtin, "builtin/"+e.Name())
+		if err != nil {
+			return nil, err
+		}
+		t := newTemplate(strings.TrimSuffix(e.Name(), Ext), SourceBuiltin, "", string(b))
+		byName[t.Name] = t
+	}
+	dirs := []struct{ dir, source string }{}
+	if d, err := UserDir(); err == nil {
+		dirs = append(dirs, struct{ dir, source string }{d, SourceUser})
+	}
+	if root != "" {
+		dirs = append(dirs, struct{ dir, source string }{filepath.Join(root, filepath.FromSlash(RepoDir)), SourceRepo})
+	}
+	for _, d := range dirs {
+		entries, err := os.ReadDir(d.dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), Ext) {
+				continue
+			}
+			p := filepath.Join(d.dir, e.Name())
+			b, err := os.ReadFile(p)
+			if err != nil {
+				return nil, err
+			}
+			name := strings.TrimSuffix(strings.TrimSuffix(e.Name(), Ext), ".spec")
+			byName[name] = newTemplate(name, d.source, p, string(b))
+		}
+	}
+	out := make([]Template, 0, len(byName))
+	for _, t := range byName {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// Find returns the template called name.
+func Find(root, name string) (Template, error) {
+	ts, err := List(root)
+	if err != nil {
+		return Template{}, err
+	}
+	for _, t := range ts {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	names := make([]string, 0, len(ts))
+	for _, t := range ts {
+		names = append(names, t.Name)
+	}
+	return Template{}, fmt.Errorf("%w: %q (available: %s)", ErrNotFound, name, strings.Join(names, ", "))
+}
+
+var descRe = regexp.MustCompile(`^\s*\{\{-?\s*/\*\s*(.*?)\s*\*/\s*-?\}\}`)
+
+func newTemplate(name, source, path, text string) Template {
+	t := Template{Name: name, Source: source, Path: path, text: text}
+	if m := descRe.FindStringSubmatch(text); m != nil {
+		t.Description = m[1]
+	}
+	return t
+}
+
+var (
+	optionalRe = regexp.MustCompile(`\s*<!--\s*optional\s*-->`)
+	h2Re       = regexp.MustCompile(`(?m)^##\s+(.*?)\s*$`)
+	numRe      = regexp.MustCompile(`^\d+(?:\.\d+)*\.?\s*`)
+	parenRe    = regexp.MustCompile(`\s*(（[^）]*）|\([^)]*\))\s*$`)
+)
+
+// Render executes the template with vars. Referencing an unset variable is
+// an error.
+func (t Template) Render(vars map[string]string) (string, error) {
+	tpl, err := template.New(t.Name).Option("missingkey=error").Parse(t.text)
+	if err != nil {
+		return "", fmt.Errorf("template %s: %w", t.Name, err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("template %s: %w", t.Name, err)
+	}
+	return optionalRe.ReplaceAllString(buf.String(), ""), nil
+}
+
+// Sections returns the required section titles of the template: its
+// level-2 headings without section numbers, except those marked
+// "<!-- optional -->".
+func (t Template) Sections() []string {
+	var out []string
+	for _, m := range h2Re.FindAllStringSubmatch(t.text, -1) {
+		if optionalRe.MatchString(m[1]) {
+			continue
+		}
+		out = append(out, numRe.ReplaceAllString(m[1], ""))
+	}
+	return out
+}
+
+// Validate checks content against the template's required sections and
+// returns the findings (missing sections are errors).
+func (t Template) Validate(content []byte) []speccheck.Finding {
+	if !bytes.HasPrefix(bytes.TrimLeft(content, " \t\r\n"), []byte("---")) {
+		// body rules only run on documents with frontmatter
+		content = append([]byte("---\n---\n"), content...)
+	}
+	sections := t.Sections()
+	rules := &speccheck.Rules{Body: speccheck.BodyRule{Severity: speccheck.SeverityError}}
+	for _, s := range sections {
+		// a parenthesized suffix such as "（用例片段）" may vary
+		base := strings.TrimSpace(parenRe.ReplaceAllString(s, ""))
+		if base == "" {
+			base = s
+		}
+		rules.Body.Sections = append(rules.Body.Sections, "^"+regexp.QuoteMeta(base))
+	}
+	if err := rules.Compile(); err != nil {
+		return []speccheck.Finding{{Rule: speccheck.RuleRulesFile, Severity: speccheck.SeverityError, Message: err.Error()}}
+	}
+	var out []speccheck.Finding
+	for _, f := range speccheck.Check(rules, "", content).Findings {
+		if f.Rule != speccheck.RuleRequiredSection {
+			continue
+		}
+		for i, pat := range rules.Body.Sections {
+			if strings.Contains(f.Message, "'"+pat+"'") {
+				f.Message = fmt.Sprintf("missing section '%s' required by template %s", sections[i], t.Name)
+			}
+		}
+		out = append(out, f)
+	}
+	return out
+}
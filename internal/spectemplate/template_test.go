@@ -0,0 +1,78 @@
+package spectemplate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tu "codectl/internal/testutil"
+)
+
+func TestList_Precedence(t *testing.T) {
+	home := t.TempDir()
+	defer tu.WithEnv(t, "HOME", home)()
+	root := t.TempDir()
+	userDir := filepath.Join(home, ".codectl", "templates")
+	repoDir := filepath.Join(root, filepath.FromSlash(RepoDir))
+	for _, d := range []string{userDir, repoDir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = os.WriteFile(filepath.Join(userDir, "api.mdx"), []byte("{{/* user api */}}\n## A\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(userDir, "rfc.mdx"), []byte("## R\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(repoDir, "rfc.spec.mdx"), []byte("{{/* repo rfc */}}\n## R\n"), 0o644)
+
+	ts, err := List(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]Template{}
+	for _, tp := range ts {
+		got[tp.Name] = tp
+	}
+	if got["feature"].Source != SourceBuiltin || got["adr"].Source != SourceBuiltin || got["tui-screen"].Source != SourceBuiltin {
+		t.Fatalf("built-ins = %+v", ts)
+	}
+	if got["api"].Source != SourceUser || got["api"].Description != "user api" {
+		t.Fatalf("api = %+v", got["api"])
+	}
+	if got["rfc"].Source != SourceRepo || got["rfc"].Description != "repo rfc" {
+		t.Fatalf("rfc = %+v", got["rfc"])
+	}
+	if _, err := Find(root, "nope"); err == nil || !strings.Contains(err.Error(), "available:") {
+		t.Fatalf("Find(nope) = %v", err)
+	}
+}
+
+func TestRenderAndValidate(t *testing.T) {
+	defer tu.WithEnv(t, "HOME", t.TempDir())()
+	tp, err := Find("", "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]string{"title": "登录", "description": "支持密码登录", "date": "2025-01-02", "author": "alice"}
+	out, err := tp.Render(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "---\ntitle: 登录\n") || !strings.Contains(out, "## 6. 错误处理与边界\n") || strings.Contains(out, "optional") {
+		t.Fatalf("rendered:\n%s", out)
+	}
+	if f := tp.Validate([]byte(out)); len(f) != 0 {
+		t.Fatalf("skeleton findings = %+v", f)
+	}
+	delete(vars, "author")
+	if _, err := tp.Render(vars); err == nil {
+		t.Fatal("expected missing variable error")
+	}
+
+	// the agent renamed a parenthesized suffix and dropped a section
+	filled := strings.Replace(out, "## 8. Conformance（用例片段）", "## 8. Conformance", 1)
+	filled = strings.Replace(filled, "## 3. 用户与场景", "## 3. 场景", 1)
+	f := tp.Validate([]byte(filled))
+	if len(f) != 1 || !strings.Contains(f[0].Message, "'用户与场景'") {
+		t.Fatalf("findings = %+v", f)
+	}
+}
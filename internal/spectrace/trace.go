@@ -0,0 +1,220 @@
+// Package spectrace reports spec-to-task traceability: for every section of
+// every spec, the tasks that declare it in their `spec:` / `relatedSpec:`
+// frontmatter, plus uncovered sections and tasks pointing at missing specs.
+package spectrace
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"codectl/internal/specgraph"
+)
+
+// TaskRef is a task referencing a spec or one of its sections.
+type TaskRef struct {
+	Path   string `json:"path"`
+	Title  string `json:"title,omitempty"`
+	Status string `json:"status,omitempty"`
+	Anchor string `json:"anchor,omitempty"` // as written in the task
+	Line   int    `json:"line"`
+}
+
+// Section is a heading of a spec with the tasks implementing it.
+type Section struct {
+	Anchor string    `json:"anchor"`
+	Number string    `json:"number,omitempty"`
+	Title  string    `json:"title"`
+	Level  int       `json:"level"`
+	Line   int       `json:"line"`
+	Tasks  []TaskRef `json:"tasks"`
+	// Covered is set when this section or an enclosing one has tasks.
+	Covered bool `json:"covered"`
+	// Whole is set on a section that is not covered while tasks reference
+	// the spec as a whole; those tasks may implement it.
+	Whole bool `json:"whole,omitempty"`
+}
+
+// Spec is the traceability of one spec document.
+type Spec struct {
+	Path   string `json:"path"`
+	Title  string `json:"title,omitempty"`
+	Status string `json:"status,omitempty"`
+	// Tasks reference the spec as a whole (no anchor).
+	Tasks    []TaskRef `json:"tasks"`
+	Sections []Section `json:"sections"`
+	Covered  int       `json:"covered"`
+	// Whole counts the sections left to whole-spec tasks (see Section.Whole);
+	// they are not included in Covered.
+	Whole int `json:"whole"`
+	Total int `json:"total"`
+}
+
+// Uncovered is a spec section no task implements.
+type Uncovered struct {
+	Spec   string `json:"spec"`
+	Anchor string `json:"anchor"`
+	Title  string `json:"title"`
+	Line   int    `json:"line"`
+	// Whole reports that tasks reference the spec as a whole.
+	Whole bool `json:"whole,omitempty"`
+}
+
+// Dangling is a task reference whose spec or section does not exist.
+type Dangling struct {
+	Task   string `json:"task"`
+	Title  string `json:"title,omitempty"`
+	Target string `json:"target"`
+	Reason string `json:"reason"` // specgraph.ReasonMissing or ReasonAnchor
+	Line   int    `json:"line"`
+}
+
+// Report is the traceability report of a repository.
+type Report struct {
+	Specs     []Spec      `json:"specs"`
+	Uncovered []Uncovered `json:"uncovered"`
+	Dangling  []Dangling  `json:"dangling"`
+}
+
+// MinLevel and MaxLevel bound the heading levels reported as sections; the
+// level-1 heading is the document title.
+const (
+	MinLevel = 2
+	MaxLevel = 3
+)
+
+// skipRe matches sections that are never implemented by tasks.
+var skipRe = regexp.MustCompile(`(?i)(变更记录|变更日志|changelog|change log|非目标|non-goals?)\s*$`)
+
+// Build computes the report for the repository at root.
+func Build(root string) (*Report, error) {
+	g, err := specgraph.Build(root)
+	if err != nil {
+		return nil, err
+	}
+	return FromGraph(g), nil
+}
+
+// FromGraph computes the report from a document graph. Task references are
+// the frontmatter spec edges of task documents.
+func FromGraph(g *specgraph.Graph) *Report {
+	rep := &Report{Specs: []Spec{}, Uncovered: []Uncovered{}, Dangling: []Dangling{}}
+	nodes := map[string]specgraph.Node{}
+	specs := map[string]*Spec{}
+	var order []string
+	for _, n := range g.Nodes {
+		nodes[n.ID] = n
+		if n.Kind != specgraph.NodeSpec {
+			continue
+		}
+		s := &Spec{Path: n.ID, Title: n.Title, Status: n.Status, Tasks: []TaskRef{}, Sections: []Section{}}
+		for _, h := range n.Headings() {
+			if h.Level < MinLevel || h.Level > MaxLevel || skipRe.MatchString(h.Text) {
+				continue
+			}
+			s.Sections = append(s.Sections, Section{Anchor: h.Anchor, Number: h.Number, Title: h.Text, Level: h.Level, Line: h.Line, Tasks: []TaskRef{}})
+		}
+		specs[n.ID] = s
+		order = append(order, n.ID)
+	}
+	for _, e := range g.Edges {
+		from, ok := nodes[e.From]
+		if !ok || from.Kind != specgraph.NodeTask || e.Kind != specgraph.KindSpec {
+			continue
+		}
+		if e.Broken {
+			rep.Dangling = append(rep.Dangling, Dangling{Task: e.From, Title: from.Title, Target: e.Target, Reason: e.Reason, Line: e.Line})
+			continue
+		}
+		s := specs[e.To]
+		if s == nil {
+			continue // a task, or a file that is not a spec
+		}
+		ref := TaskRef{Path: e.From, Title: from.Title, Status: from.Status, Anchor: e.Anchor, Line: e.Line}
+		if e.Anchor == "" {
+			s.Tasks = append(s.Tasks, ref)
+			continue
+		}
+		if i := s.section(e.Anchor); i >= 0 {
+			s.Sections[i].Tasks = append(s.Sections[i].Tasks, ref)
+		} else {
+			// the anchor resolves to a heading outside the reported levels
+			s.Tasks = append(s.Tasks, ref)
+		}
+	}
+	for _, id := range order {
+		s := specs[id]
+		s.cover()
+		for _, sec := range s.Sections {
+			if !sec.Covered {
+				rep.Uncovered = append(rep.Uncovered, Uncovered{Spec: s.Path, Anchor: sec.Anchor, Title: sec.Title, Line: sec.Line, Whole: sec.Whole})
+			}
+		}
+		rep.Specs = append(rep.Specs, *s)
+	}
+	sort.SliceStable(rep.Dangling, func(i, j int) bool { return rep.Dangling[i].Task < rep.Dangling[j].Task })
+	return rep
+}
+
+// section returns the index of the section anchor refers to, matching the
+// heading slug or its section number, or -1.
+func (s *Spec) section(anchor string) int {
+	slug := specgraph.Slug(anchor)
+	for i, sec := range s.Sections {
+		if sec.Anchor == anchor || sec.Anchor == slug || (sec.Number != "" && sec.Number == anchor) {
+			return i
+		}
+	}
+	return -1
+}
+
+// cover marks sections covered by their own tasks or an enclosing section's,
+// and counts them. Whole-spec tasks do not cover sections, since they do not
+// say which ones they implement; the remaining sections are marked Whole and
+// counted separately.
+func (s *Spec) cover() {
+	var stack []Section // enclosing sections
+	for i := range s.Sections {
+		sec := &s.Sections[i]
+		for len(stack) > 0 && stack[len(stack)-1].Level >= sec.Level {
+			stack = stack[:len(stack)-1]
+		}
+		sec.Covered = len(sec.Tasks) > 0 || (len(stack) > 0 && stack[len(stack)-1].Covered)
+		stack = append(stack, *sec)
+		sec.Whole = !sec.Covered && len(s.Tasks) > 0
+		switch {
+		case sec.Covered:
+			s.Covered++
+		case sec.Whole:
+			s.Whole++
+		}
+	}
+	s.Total = len(s.Sections)
+}
+
+// TaskCount returns the number of distinct tasks referencing the spec.
+func (s Spec) TaskCount() int {
+	seen := map[string]bool{}
+	for _, t := range s.Tasks {
+		seen[t.Path] = true
+	}
+	for _, sec := range s.Sections {
+		for _, t := range sec.Tasks {
+			seen[t.Path] = true
+		}
+	}
+	return len(seen)
+}
+
+// String returns the display text of a task reference.
+func (t TaskRef) String() string {
+	st := t.Status
+	if strings.TrimSpace(st) == "" {
+		st = "-"
+	}
+	name := t.Title
+	if name == "" {
+		name = t.Path
+	}
+	return name + " [" + st + "] " + t.Path
+}
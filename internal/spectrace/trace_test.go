@@ -0,0 +1,70 @@
+package spectrace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func write(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "vibe-docs/spec/100-a.spec.mdx", "---\ntitle: A\n---\n# A\n\n## 1. 目标\n\n## 2. 登录\n\n### 2.1 密码\n\n## 3. 注销\n\n## 4. 变更记录\n")
+	write(t, root, "vibe-docs/task/t1.task.mdx", "---\ntitle: Login\nstatus: todo\nspec: vibe-docs/spec/100-a.spec.mdx#2\n---\n")
+	write(t, root, "vibe-docs/task/t2.task.mdx", "---\ntitle: Whole\nstatus: done\nrelatedSpec:\n  - vibe-docs/spec/100-a.spec.mdx\n---\n")
+	write(t, root, "vibe-docs/task/t3.task.mdx", "---\ntitle: Lost\nspec:\n  - vibe-docs/spec/nope.spec.mdx\n  - spec:100#9-nope\n---\n")
+
+	rep, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Specs) != 1 {
+		t.Fatalf("specs = %+v", rep.Specs)
+	}
+	s := rep.Specs[0]
+	if s.Total != 4 || s.Covered != 2 || s.Whole != 2 || len(s.Tasks) != 1 || s.Tasks[0].Path != "vibe-docs/task/t2.task.mdx" || s.TaskCount() != 2 {
+		t.Fatalf("spec = %+v", s)
+	}
+	login := s.Sections[1]
+	if login.Number != "2" || len(login.Tasks) != 1 || login.Tasks[0].Status != "todo" || !s.Sections[2].Covered {
+		t.Fatalf("sections = %+v", s.Sections)
+	}
+	if len(rep.Uncovered) != 2 || rep.Uncovered[0].Title != "1. 目标" || rep.Uncovered[1].Title != "3. 注销" || !rep.Uncovered[0].Whole {
+		t.Fatalf("uncovered = %+v", rep.Uncovered)
+	}
+	if len(rep.Dangling) != 2 || rep.Dangling[0].Task != "vibe-docs/task/t3.task.mdx" {
+		t.Fatalf("dangling = %+v", rep.Dangling)
+	}
+}
+
+func TestWholeSpecTasks(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, "vibe-docs/spec/100-a.spec.mdx", "---\ntitle: A\n---\n# A\n\n## 1. 目标\n\n## 2. 登录\n")
+	write(t, root, "vibe-docs/spec/200-b.spec.mdx", "---\ntitle: B\n---\n# B\n\n## 1. 目标\n")
+	write(t, root, "vibe-docs/task/t1.task.mdx", "---\ntitle: All of A\nspec: vibe-docs/spec/100-a.spec.mdx\n---\n")
+
+	rep, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := rep.Specs[0], rep.Specs[1]
+	if a.Covered != 0 || a.Whole != 2 || a.Total != 2 || !a.Sections[0].Whole || !a.Sections[1].Whole {
+		t.Fatalf("whole-spec tasks not reported: %+v", a)
+	}
+	if b.Whole != 0 || b.Sections[0].Whole {
+		t.Fatalf("spec without tasks marked whole: %+v", b)
+	}
+	if len(rep.Uncovered) != 3 || !rep.Uncovered[0].Whole || !rep.Uncovered[1].Whole || rep.Uncovered[2].Whole {
+		t.Fatalf("uncovered = %+v", rep.Uncovered)
+	}
+}
@@ -0,0 +1,47 @@
+package specversion
+
+import (
+	"fmt"
+	"strings"
+
+	"codectl/internal/document"
+)
+
+// DefaultNote is the changelog text used when no note is given.
+const DefaultNote = "更新。"
+
+// Result describes a bump.
+type Result struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Content []byte `json:"-"`
+}
+
+// BumpDoc raises the specVersion of document content b by kind and appends
+// a changelog entry dated date. A document without specVersion starts from
+// 0.0.0.
+func BumpDoc(b []byte, kind, date, note string) (Result, error) {
+	doc, err := document.Parse(b)
+	if err != nil {
+		return Result{}, err
+	}
+	from := strings.TrimSpace(doc.String("specVersion"))
+	cur := Version{}
+	if from != "" {
+		if cur, err = Parse(from); err != nil {
+			return Result{}, fmt.Errorf("specVersion: %w", err)
+		}
+	}
+	next, err := cur.Bump(kind)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := doc.Set("specVersion", next.String()); err != nil {
+		return Result{}, err
+	}
+	if strings.TrimSpace(note) == "" {
+		note = DefaultNote
+	}
+	doc.Body = AddEntry(doc.Body, next.String(), date, strings.TrimSpace(note))
+	return Result{From: from, To: next.String(), Content: doc.Bytes()}, nil
+}
@@ -0,0 +1,115 @@
+package specversion
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	changelogHeadRe = regexp.MustCompile(`(?i)^(#{1,6})\s+(?:\d+(?:\.\d+)*\.?\s+)?(变更记录|变更日志|changelog|change log)\s*$`)
+	anyHeadRe       = regexp.MustCompile(`^(#{1,6})\s+`)
+	entryRe         = regexp.MustCompile(`^\s*[-*]\s+v?(\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?)\b`)
+)
+
+// section returns the line range [start, end) of the changelog section body
+// (the lines after its heading) and the heading level; ok is false when the
+// body has no changelog heading.
+func section(lines []string) (start, end, level int, ok bool) {
+	for i, ln := range lines {
+		m := changelogHeadRe.FindStringSubmatch(strings.TrimRight(ln, "\r"))
+		if m == nil {
+			continue
+		}
+		level = len(m[1])
+		end = len(lines)
+		for j := i + 1; j < len(lines); j++ {
+			if h := anyHeadRe.FindStringSubmatch(lines[j]); h != nil && len(h[1]) <= level {
+				end = j
+				break
+			}
+		}
+		return i + 1, end, level, true
+	}
+	return 0, 0, 0, false
+}
+
+// Entries returns the versions listed in the changelog section of body.
+func Entries(body string) []string {
+	lines := strings.Split(body, "\n")
+	start, end, _, ok := section(lines)
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, ln := range lines[start:end] {
+		if m := entryRe.FindStringSubmatch(ln); m != nil {
+			out = append(out, m[1])
+		}
+	}
+	return out
+}
+
+// Changelog returns the body of the changelog section (without its
+// heading); ok is false when there is none.
+func Changelog(body string) (string, bool) {
+	lines := strings.Split(body, "\n")
+	start, end, _, ok := section(lines)
+	if !ok {
+		return "", false
+	}
+	return strings.TrimSpace(strings.Join(lines[start:end], "\n")), true
+}
+
+// HasEntry reports whether the changelog lists version v.
+func HasEntry(body, v string) bool {
+	for _, e := range Entries(body) {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// StripChangelog returns body without its changelog section, so edits that
+// only touch the changelog do not count as content changes.
+func StripChangelog(body string) string {
+	lines := strings.Split(body, "\n")
+	start, end, _, ok := section(lines)
+	if !ok {
+		return body
+	}
+	return strings.Join(append(lines[:start-1:start-1], lines[end:]...), "\n")
+}
+
+// AddEntry appends "- <version>（<date>）：<note>" after the last entry of the
+// changelog section, creating a "## 变更记录" section at the end of body when
+// there is none.
+func AddEntry(body, version, date, note string) string {
+	nl := "\n"
+	if strings.Contains(body, "\r\n") {
+		nl = "\r\n"
+	}
+	entry := "- " + version + "（" + date + "）：" + note
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	start, end, _, ok := section(lines)
+	if !ok {
+		trimmed := strings.TrimRight(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+		out := trimmed + "\n\n## 变更记录\n" + entry + "\n"
+		if trimmed == "" {
+			out = "## 变更记录\n" + entry + "\n"
+		}
+		return strings.ReplaceAll(out, "\n", nl)
+	}
+	// insert after the last non-blank line of the section
+	at := start
+	for i := end - 1; i >= start; i-- {
+		if strings.TrimSpace(lines[i]) != "" {
+			at = i + 1
+			break
+		}
+	}
+	out := append([]string{}, lines[:at]...)
+	out = append(out, entry)
+	out = append(out, lines[at:]...)
+	return strings.Join(out, nl)
+}
@@ -0,0 +1,68 @@
+package specversion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"codectl/internal/document"
+	"codectl/internal/speccheck"
+	"codectl/internal/spechistory"
+)
+
+// CheckAgainst compares content cur of the spec at repository-relative path
+// rel with its version at git revision base. It reports a body change
+// without a specVersion bump, and a new specVersion without a changelog
+// entry. Documents without specVersion, or absent at base, are skipped.
+func CheckAgainst(ctx context.Context, root, rel, base string, cur []byte, rule speccheck.VersionRule) ([]speccheck.Finding, error) {
+	if rule.Disabled {
+		return nil, nil
+	}
+	doc, err := document.Parse(cur)
+	if err != nil || !doc.Has("specVersion") {
+		return nil, nil
+	}
+	old, err := spechistory.Show(ctx, root, base, rel)
+	if errors.Is(err, spechistory.ErrNotInRevision) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prev, err := document.Parse(old)
+	if err != nil {
+		return nil, nil
+	}
+	sev := rule.Severity
+	if sev != speccheck.SeverityError {
+		sev = speccheck.SeverityWarning
+	}
+	ver := strings.TrimSpace(doc.String("specVersion"))
+	pos, _ := doc.ValuePos("specVersion")
+	var out []speccheck.Finding
+	if ver == strings.TrimSpace(prev.String("specVersion")) {
+		if normalize(StripChangelog(doc.Body)) != normalize(StripChangelog(prev.Body)) {
+			out = append(out, speccheck.Finding{
+				Rule: speccheck.RuleVersionBump, Severity: sev, Field: "specVersion", Line: pos.Line, Column: pos.Col,
+				Message: fmt.Sprintf("content changed since %s but specVersion is still %s (run `codectl spec bump`)", base, ver),
+			})
+		}
+		return out, nil
+	}
+	if !HasEntry(doc.Body, ver) {
+		out = append(out, speccheck.Finding{
+			Rule: speccheck.RuleChangelog, Severity: sev, Field: "specVersion", Line: pos.Line, Column: pos.Col,
+			Message: fmt.Sprintf("changelog has no entry for specVersion %s", ver),
+		})
+	}
+	return out, nil
+}
+
+func normalize(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, ln := range lines {
+		lines[i] = strings.TrimRight(ln, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
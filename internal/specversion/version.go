@@ -0,0 +1,66 @@
+// Package specversion handles spec semantic versions and the changelog
+// ("变更记录") section: bumping specVersion with a dated entry, and checking a
+// spec against its git base for missing bumps or changelog entries.
+package specversion
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Bump kinds.
+const (
+	Major = "major"
+	Minor = "minor"
+	Patch = "patch"
+)
+
+// Version is a semantic version (MAJOR.MINOR.PATCH[-pre]).
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string
+}
+
+var semverRe = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?$`)
+
+// ErrInvalid is returned for strings that are not semantic versions.
+var ErrInvalid = errors.New("invalid semantic version")
+
+// Parse parses a semantic version; a leading "v" is accepted.
+func Parse(s string) (Version, error) {
+	m := semverRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Version{}, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	v.Pre = m[4]
+	return v, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Bump returns the next version for kind (major|minor|patch). A
+// pre-release is dropped.
+func (v Version) Bump(kind string) (Version, error) {
+	switch kind {
+	case Major:
+		return Version{Major: v.Major + 1}, nil
+	case Minor:
+		return Version{Major: v.Major, Minor: v.Minor + 1}, nil
+	case Patch:
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}, nil
+	}
+	return v, fmt.Errorf("unknown bump %q (want major|minor|patch)", kind)
+}
@@ -0,0 +1,105 @@
+package specversion
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codectl/internal/speccheck"
+)
+
+func TestBump(t *testing.T) {
+	v, err := Parse("v1.2.3-rc.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for kind, want := range map[string]string{Major: "2.0.0", Minor: "1.3.0", Patch: "1.2.4"} {
+		if got, _ := v.Bump(kind); got.String() != want {
+			t.Errorf("bump %s = %s, want %s", kind, got, want)
+		}
+	}
+	if _, err := Parse("1.2"); err == nil {
+		t.Fatalf("expected error for 1.2")
+	}
+}
+
+func TestBumpDoc(t *testing.T) {
+	src := "---\ntitle: X\nspecVersion: 0.1.0 # semver\n---\n# X\n\n## 6. 变更记录\n- 0.1.0：初稿。\n\n## 7. 附录\ntext\n"
+	res, err := BumpDoc([]byte(src), Minor, "2025-10-01", "新增附录")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "---\ntitle: X\nspecVersion: 0.2.0 # semver\n---\n# X\n\n## 6. 变更记录\n- 0.1.0：初稿。\n- 0.2.0（2025-10-01）：新增附录\n\n## 7. 附录\ntext\n"
+	if string(res.Content) != want || res.From != "0.1.0" || res.To != "0.2.0" {
+		t.Fatalf("unexpected bump:\n%s", res.Content)
+	}
+	if got := Entries(string(res.Content)); len(got) != 2 || got[1] != "0.2.0" {
+		t.Fatalf("entries = %v", got)
+	}
+
+	res, err = BumpDoc([]byte("---\ntitle: Y\n---\nbody\n"), Patch, "2025-10-01", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(res.Content), "body\n\n## 变更记录\n- 0.0.1（2025-10-01）："+DefaultNote+"\n") {
+		t.Fatalf("unexpected bump:\n%s", res.Content)
+	}
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestCheckAgainst(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	root := t.TempDir()
+	git(t, root, "init", "-q")
+	rel := "vibe-docs/spec/a.spec.mdx"
+	full := filepath.Join(root, filepath.FromSlash(rel))
+	_ = os.MkdirAll(filepath.Dir(full), 0o755)
+	base := "---\ntitle: A\nspecVersion: 0.1.0\n---\nbody\n\n## 变更记录\n- 0.1.0：初稿。\n"
+	_ = os.WriteFile(full, []byte(base), 0o644)
+	git(t, root, "add", "-A")
+	git(t, root, "commit", "-q", "-m", "init")
+	ctx := context.Background()
+	rule := speccheck.VersionRule{}
+
+	check := func(content string) []speccheck.Finding {
+		t.Helper()
+		fs, err := CheckAgainst(ctx, root, rel, "HEAD", []byte(content), rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fs
+	}
+	if fs := check(base); len(fs) != 0 {
+		t.Fatalf("unchanged: %+v", fs)
+	}
+	if fs := check(strings.Replace(base, "- 0.1.0：初稿。", "- 0.1.0：初稿（修订措辞）。", 1)); len(fs) != 0 {
+		t.Fatalf("changelog-only edit: %+v", fs)
+	}
+	edited := strings.Replace(base, "body", "new body", 1)
+	if fs := check(edited); len(fs) != 1 || fs[0].Rule != speccheck.RuleVersionBump || fs[0].Line != 3 {
+		t.Fatalf("body edit: %+v", fs)
+	}
+	bumped := strings.Replace(edited, "specVersion: 0.1.0", "specVersion: 0.2.0", 1)
+	if fs := check(bumped); len(fs) != 1 || fs[0].Rule != speccheck.RuleChangelog {
+		t.Fatalf("bump without changelog: %+v", fs)
+	}
+	if fs := check(bumped + "- 0.2.0（2025-10-01）：更新。\n"); len(fs) != 0 {
+		t.Fatalf("bump with changelog: %+v", fs)
+	}
+	if _, err := CheckAgainst(ctx, root, rel, "no-such-rev", []byte(base), rule); err == nil {
+		t.Fatalf("expected error for unknown revision")
+	}
+}
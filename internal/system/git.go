@@ -94,3 +94,16 @@ func GitRoot(ctx context.Context, dir string) (string, error) {
 	}
 	return strings.TrimSpace(string(out)), nil
 }
+
+// GitUser returns the configured user.name of the repository at dir (or the
+// global one), empty when unset.
+func GitUser(ctx context.Context, dir string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", err
+	}
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "config", "user.name").Output()
+	if err != nil {
+		return "", nil // unset
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,369 @@
+// Package taskgen breaks a spec down into task documents: it asks a coding
+// agent for a task list, then renders one vibe-docs/task/*.task.mdx per task
+// linking back to the spec, with acceptance criteria copied from it.
+package taskgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"codectl/internal/document"
+	"codectl/internal/history"
+	"codectl/internal/safefs"
+)
+
+// Dir is the repository-relative task directory.
+const Dir = "vibe-docs/task"
+
+// StatusTodo is the status of generated tasks.
+const StatusTodo = "todo"
+
+// Task is one entry of the agent's breakdown.
+type Task struct {
+	Title    string `json:"title"`
+	Summary  string `json:"summary,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	// Criteria are 1-based indices into the spec's acceptance criteria.
+	Criteria []int `json:"criteria,omitempty"`
+	// Acceptance holds extra criteria proposed by the agent, used when the
+	// spec has none for this task.
+	Acceptance []string `json:"acceptance,omitempty"`
+	Steps      []string `json:"steps,omitempty"`
+}
+
+// File is a rendered task document.
+type File struct {
+	Path    string `json:"path"` // repository-relative
+	Title   string `json:"title"`
+	Content []byte `json:"-"`
+}
+
+// Runner sends a prompt to the agent and returns its output.
+type Runner func(ctx context.Context, prompt string) (string, error)
+
+var (
+	acceptHeadRe = regexp.MustCompile(`(?i)^(#{1,6})\s+.*(验收|acceptance|conformance)`)
+	headRe       = regexp.MustCompile(`^(#{1,6})\s+`)
+	itemRe       = regexp.MustCompile(`^\s{0,3}(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?(.+?)\s*$`)
+	priorityRe   = regexp.MustCompile(`^P[0-2]$`)
+)
+
+// Acceptance returns the list items under the spec's acceptance headings
+// (验收 / Acceptance / Conformance), including their subsections. Fenced
+// code is skipped.
+func Acceptance(body string) []string {
+	var out []string
+	level := 0 // level of the current acceptance heading, 0 outside
+	inFence := false
+	for _, ln := range strings.Split(body, "\n") {
+		ln = strings.TrimRight(ln, "\r")
+		if t := strings.TrimSpace(ln); strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if m := headRe.FindStringSubmatch(ln); m != nil {
+			if level > 0 && len(m[1]) <= level {
+				level = 0
+			}
+			if level == 0 {
+				if am := acceptHeadRe.FindStringSubmatch(ln); am != nil {
+					level = len(am[1])
+				}
+			}
+			continue
+		}
+		if level == 0 {
+			continue
+		}
+		if m := itemRe.FindStringSubmatch(ln); m != nil && m[1] != "" {
+			out = append(out, m[1])
+		}
+	}
+	return out
+}
+
+// Prompt builds the agent prompt for the spec at rel with the numbered
+// acceptance criteria.
+func Prompt(rel, spec string, criteria []string) string {
+	var b strings.Builder
+	b.WriteString("你是项目的技术负责人。请把下面的规范（Spec）拆解为可独立交付的开发任务。\n")
+	b.WriteString("只输出一个 JSON 数组，不要输出其它内容。数组元素格式：\n")
+	b.WriteString(`{"title": "任务标题", "summary": "一两句话的目标", "priority": "P0|P1|P2", "criteria": [验收标准编号], "steps": ["实现要点"], "acceptance": ["规范未覆盖时补充的验收标准"]}` + "\n")
+	b.WriteString("要求：每个任务粒度为 0.5～3 天；criteria 引用下方编号，每条验收标准至少归属一个任务。\n\n")
+	if len(criteria) > 0 {
+		b.WriteString("验收标准：\n")
+		for i, c := range criteria {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, c)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "规范文件：%s\n\n", rel)
+	b.WriteString(spec)
+	return b.String()
+}
+
+// ErrNoTasks is returned when the agent output contains no tasks.
+var ErrNoTasks = errors.New("agent output contains no tasks")
+
+// ParseTasks extracts the JSON task array from agent output, tolerating
+// surrounding prose and code fences.
+func ParseTasks(out string) ([]Task, error) {
+	var lastErr error
+	for i := strings.Index(out, "["); i >= 0; {
+		var tasks []Task
+		dec := json.NewDecoder(strings.NewReader(out[i:]))
+		err := dec.Decode(&tasks)
+		if err == nil {
+			kept := tasks[:0]
+			for _, t := range tasks {
+				if t.Title = strings.TrimSpace(t.Title); t.Title != "" {
+					kept = append(kept, t)
+				}
+			}
+			if len(kept) > 0 {
+				return kept, nil
+			}
+		} else {
+			lastErr = err
+		}
+		next := strings.Index(out[i+1:], "[")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	if lastErr != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoTasks, lastErr)
+	}
+	return nil, ErrNoTasks
+}
+
+// Render returns the task document for t. spec is the repository-relative
+// spec path; criteria are the spec's acceptance criteria.
+func Render(t Task, spec string, criteria []string, now time.Time) []byte {
+	accept := criteriaFor(t, criteria)
+	doc := document.New("")
+	_ = doc.Set("title", t.Title)
+	_ = doc.Set("status", StatusTodo)
+	if p := strings.ToUpper(strings.TrimSpace(t.Priority)); priorityRe.MatchString(p) {
+		_ = doc.Set("priority", p)
+	}
+	_ = doc.SetStrings("relatedSpec", []string{spec})
+	_ = doc.SetStrings("acceptance", accept)
+	_ = doc.Set("createdAt", now.Format(time.RFC3339))
+	_ = doc.SetRaw("lastUpdated", "{auto}")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n# %s\n\n", t.Title)
+	fmt.Fprintf(&b, "> 由 codectl task new --from-spec 根据 [%s](%s) 生成。\n\n", path.Base(spec), relLink(spec))
+	// sections without content are left out, except the ones task rules
+	// require, which get a placeholder
+	section := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "## %s\n", title)
+		for _, it := range items {
+			fmt.Fprintf(&b, "- %s\n", it)
+		}
+		b.WriteString("\n")
+	}
+	orPlaceholder := func(items []string) []string {
+		if len(items) == 0 {
+			return []string{Placeholder}
+		}
+		return items
+	}
+	var goal []string
+	if s := strings.TrimSpace(t.Summary); s != "" {
+		goal = []string{s}
+	}
+	section("背景", []string{"见规范 " + path.Base(spec) + "。"})
+	section("目标", orPlaceholder(goal))
+	section("验收标准", orPlaceholder(accept))
+	section("实现要点", t.Steps)
+	section("参考链接", []string{"[" + path.Base(spec) + "](" + relLink(spec) + ")"})
+	doc.Body = strings.TrimRight(b.String(), "\n") + "\n"
+	return doc.Bytes()
+}
+
+// DefaultTitle is the title of a task created without one.
+const DefaultTitle = "未命名任务"
+
+// Placeholder marks a section still to be written. Unlike an empty list
+// item it counts as content for the noEmptySections rule.
+const Placeholder = "待补充。"
+
+// Blank returns an empty task document from the standard template, each
+// section holding Placeholder.
+func Blank(title string, now time.Time) []byte {
+	if strings.TrimSpace(title) == "" {
+		title = DefaultTitle
+	}
+	doc := document.New("")
+	_ = doc.Set("title", title)
+	_ = doc.Set("status", StatusTodo)
+	_ = doc.Set("createdAt", now.Format(time.RFC3339))
+	_ = doc.SetRaw("lastUpdated", "{auto}")
+	var b strings.Builder
+	b.WriteString("\n# 任务说明（草案）\n\n> 由 codectl task new 生成。可使用 'codectl task new <标题>' 指定标题。\n")
+	for _, h := range []string{"背景", "目标", "非目标", "验收标准", "实现要点", "风险与依赖", "参考链接"} {
+		fmt.Fprintf(&b, "\n## %s\n- %s\n", h, Placeholder)
+	}
+	doc.Body = b.String()
+	return doc.Bytes()
+}
+
+// criteriaFor returns the criteria referenced by t, copied verbatim from the
+// spec, followed by the agent's own criteria.
+func criteriaFor(t Task, criteria []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	add := func(s string) {
+		if s = strings.TrimSpace(s); s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	for _, i := range t.Criteria {
+		if i >= 1 && i <= len(criteria) {
+			add(criteria[i-1])
+		}
+	}
+	for _, s := range t.Acceptance {
+		add(s)
+	}
+	return out
+}
+
+// relLink returns the link from a task document to the repository-relative
+// spec path.
+func relLink(spec string) string {
+	if r, err := filepath.Rel(filepath.FromSlash(Dir), filepath.FromSlash(spec)); err == nil {
+		return filepath.ToSlash(r)
+	}
+	return "/" + spec
+}
+
+// FileName returns "YYMMDD-HHMMSS-<slug>.task.mdx".
+func FileName(now time.Time, title string) string {
+	return now.Format("060102-150405") + "-" + Slug(title, "task") + ".task.mdx"
+}
+
+// Slug turns a title into a file name part, keeping ASCII letters, digits
+// and CJK characters and at most 48 runes; fallback is used when nothing is
+// left. It names both task and spec files.
+func Slug(s, fallback string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	b := make([]rune, 0, len(s))
+	lastDash := false
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || (r >= 0x4E00 && r <= 0x9FFF) {
+			b = append(b, r)
+			lastDash = false
+			continue
+		}
+		if !lastDash {
+			b = append(b, '-')
+			lastDash = true
+		}
+	}
+	res := strings.Trim(string(b), "-")
+	if r := []rune(res); len(r) > 48 {
+		res = strings.Trim(string(r[:48]), "-")
+	}
+	if res == "" {
+		res = fallback
+	}
+	return res
+}
+
+// Plan renders the files for tasks. Names that exist under root or repeat
+// within the batch get a numeric suffix.
+func Plan(root, spec string, criteria []string, tasks []Task, now time.Time) []File {
+	used := map[string]bool{}
+	files := make([]File, 0, len(tasks))
+	for _, t := range tasks {
+		files = append(files, File{
+			Path:    NewPath(root, now, t.Title, used),
+			Title:   t.Title,
+			Content: Render(t, spec, criteria, now),
+		})
+	}
+	return files
+}
+
+// NewPath returns the repository-relative path for a new task titled title
+// that neither exists under root nor is in used, and records it in used
+// (which may be nil).
+func NewPath(root string, now time.Time, title string, used map[string]bool) string {
+	name := FileName(now, title)
+	stem := strings.TrimSuffix(name, ".task.mdx")
+	for n := 2; used[name] || exists(root, path.Join(Dir, name)); n++ {
+		name = stem + "-" + strconv.Itoa(n) + ".task.mdx"
+	}
+	if used != nil {
+		used[name] = true
+	}
+	return path.Join(Dir, name)
+}
+
+func exists(root, rel string) bool {
+	_, err := safefs.Lstat(root, rel)
+	return err == nil
+}
+
+// Generate asks run for a breakdown of the spec at the repository-relative
+// path spec and returns the rendered task files; nothing is written. An
+// error from run, or a done ctx, is returned even when run produced output.
+func Generate(ctx context.Context, run Runner, root, spec string, now time.Time) ([]File, error) {
+	b, err := safefs.ReadFile(root, spec)
+	if err != nil {
+		return nil, err
+	}
+	body := string(b)
+	if doc, err := document.Parse(b); err == nil {
+		body = doc.Body
+	}
+	criteria := Acceptance(body)
+	// a failed or cancelled run may still have printed part of an answer;
+	// never plan tasks from it.
+	out, err := run(ctx, Prompt(spec, string(b), criteria))
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tasks, perr := ParseTasks(out)
+	if perr != nil {
+		return nil, perr
+	}
+	return Plan(root, spec, criteria, tasks, now), nil
+}
+
+// Write writes files beneath root, creating the task directory. Paths stay
+// confined to root even through symlinks; a file that is overwritten is kept
+// in the local history first.
+func Write(root string, files []File) error {
+	for _, f := range files {
+		if _, err := history.Snapshot(root, f.Path, history.OpWrite); err != nil {
+			return err
+		}
+		if err := safefs.WriteFile(root, f.Path, f.Content, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
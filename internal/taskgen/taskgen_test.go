@@ -0,0 +1,220 @@
+package taskgen
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"codectl/internal/document"
+	"codectl/internal/history"
+	"codectl/internal/speccheck"
+)
+
+const specSrc = `---
+title: Demo
+specVersion: 0.1.0
+status: draft
+---
+# Demo
+
+## 1. 目标
+- not a criterion
+
+## 9. 验收标准
+- [ ] 登录成功后跳转首页
+- 密码错误时提示
+
+### 9.1 边界
+1. 连续失败 5 次锁定
+
+` + "```" + `
+- not in fence
+` + "```" + `
+
+## 10. 变更记录
+- 0.1.0：初稿。
+`
+
+func TestAcceptance(t *testing.T) {
+	got := Acceptance(specSrc)
+	want := []string{"登录成功后跳转首页", "密码错误时提示", "连续失败 5 次锁定"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("Acceptance = %q", got)
+	}
+}
+
+func TestParseTasks(t *testing.T) {
+	out := "Here is the plan:\n```json\n[{\"title\": \"登录页\", \"criteria\": [1, 2]}, {\"title\": \" \"}]\n```\n"
+	tasks, err := ParseTasks(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Title != "登录页" || len(tasks[0].Criteria) != 2 {
+		t.Fatalf("tasks = %+v", tasks)
+	}
+	if _, err := ParseTasks("no json [here"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	root := t.TempDir()
+	spec := "vibe-docs/spec/100-login.spec.mdx"
+	if err := os.MkdirAll(filepath.Join(root, "vibe-docs", "spec"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(spec)), []byte(specSrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var prompt string
+	run := func(_ context.Context, p string) (string, error) {
+		prompt = p
+		return `[{"title":"Login page","priority":"p1","criteria":[1,2,9]},{"title":"Login page","criteria":[3],"acceptance":["有审计日志"]}]`, nil
+	}
+	now := time.Date(2025, 9, 14, 14, 30, 15, 0, time.UTC)
+	files, err := Generate(context.Background(), run, root, spec, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "3. 连续失败 5 次锁定") {
+		t.Fatalf("prompt misses numbered criteria:\n%s", prompt)
+	}
+	if len(files) != 2 || files[0].Path != "vibe-docs/task/250914-143015-login-page.task.mdx" || files[1].Path != "vibe-docs/task/250914-143015-login-page-2.task.mdx" {
+		t.Fatalf("files = %+v", files)
+	}
+	if err := Write(root, files); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(files[0].Path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := document.Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.String("status") != StatusTodo || doc.String("priority") != "P1" {
+		t.Fatalf("fields = %v", doc.Fields())
+	}
+	if rs := doc.Strings("relatedSpec"); len(rs) != 1 || rs[0] != spec {
+		t.Fatalf("relatedSpec = %q", rs)
+	}
+	if acc := doc.Strings("acceptance"); strings.Join(acc, "|") != "登录成功后跳转首页|密码错误时提示" {
+		t.Fatalf("acceptance = %q", acc)
+	}
+	if !strings.Contains(doc.Body, "](../spec/100-login.spec.mdx)") {
+		t.Fatalf("body misses spec link:\n%s", doc.Body)
+	}
+	second, _ := document.Parse(files[1].Content)
+	if acc := second.Strings("acceptance"); strings.Join(acc, "|") != "连续失败 5 次锁定|有审计日志" {
+		t.Fatalf("acceptance = %q", acc)
+	}
+}
+
+func TestWriteStaysInRoot(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	outside := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(root, "vibe-docs", "spec"), 0o755)
+	if err := os.WriteFile(filepath.Join(outside, "x.spec.mdx"), []byte(specSrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "x.spec.mdx"), filepath.Join(root, "vibe-docs", "spec", "x.spec.mdx")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "vibe-docs", "task")); err != nil {
+		t.Fatal(err)
+	}
+	run := func(context.Context, string) (string, error) { return `[{"title":"A"}]`, nil }
+	if _, err := Generate(context.Background(), run, root, "vibe-docs/spec/x.spec.mdx", time.Now()); err == nil {
+		t.Fatal("read a spec outside the root")
+	}
+	files := []File{{Path: "vibe-docs/task/a.task.mdx", Content: []byte("x")}}
+	if err := Write(root, files); err == nil {
+		t.Fatal("wrote through a symlinked task directory")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "a.task.mdx")); !os.IsNotExist(err) {
+		t.Fatalf("task written outside the root: %v", err)
+	}
+}
+
+func TestWriteKeepsHistory(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	files := []File{{Path: "vibe-docs/task/a.task.mdx", Content: []byte("first\n")}}
+	if err := Write(root, files); err != nil {
+		t.Fatal(err)
+	}
+	files[0].Content = []byte("second\n")
+	if err := Write(root, files); err != nil {
+		t.Fatal(err)
+	}
+	vs, err := history.List(filepath.Join(root, "vibe-docs", "task", "a.task.mdx"))
+	if err != nil || len(vs) != 1 {
+		t.Fatalf("versions = %+v, %v", vs, err)
+	}
+}
+
+func TestGenerate_AgentErrors(t *testing.T) {
+	root := t.TempDir()
+	spec := "vibe-docs/spec/100-login.spec.mdx"
+	_ = os.MkdirAll(filepath.Join(root, "vibe-docs", "spec"), 0o755)
+	if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(spec)), []byte(specSrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	const partial = `[{"title":"Login page"}]`
+	failed := errors.New("agent failed")
+	run := func(context.Context, string) (string, error) { return partial, failed }
+	if _, err := Generate(context.Background(), run, root, spec, time.Now()); !errors.Is(err, failed) {
+		t.Fatalf("want the agent error despite output, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run = func(context.Context, string) (string, error) {
+		cancel()
+		return partial, nil
+	}
+	if _, err := Generate(ctx, run, root, spec, time.Now()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+}
+
+// TestTasksPassCheck runs new task documents through the repository's own
+// check rules, which forbid empty sections.
+func TestTasksPassCheck(t *testing.T) {
+	rules, err := speccheck.Load(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 9, 14, 14, 30, 15, 0, time.UTC)
+	docs := map[string][]byte{
+		"rendered": Render(Task{Title: "Login page", Priority: "P1"}, "vibe-docs/spec/100-login.spec.mdx", nil, now),
+		"full": Render(Task{Title: "Login page", Summary: "Sign in", Criteria: []int{1}, Steps: []string{"form"}},
+			"vibe-docs/spec/100-login.spec.mdx", []string{"登录成功后跳转首页"}, now),
+		"blank": Blank("", now),
+	}
+	for name, b := range docs {
+		res := speccheck.Check(rules, path.Join(Dir, FileName(now, name)), b)
+		if len(res.Findings) != 0 {
+			t.Fatalf("%s task has findings %+v:\n%s", name, res.Findings, b)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	for in, want := range map[string]string{
+		"Add Login Page!":         "add-login-page",
+		"  登录 flow ":              "登录-flow",
+		"???":                     "spec",
+		strings.Repeat("ab-", 30): strings.Trim(strings.Repeat("ab-", 16), "-"),
+	} {
+		if got := Slug(in, "spec"); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
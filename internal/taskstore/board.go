@@ -0,0 +1,236 @@
+package taskstore
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"codectl/internal/document"
+	"codectl/internal/speccheck"
+)
+
+// OrderField is the frontmatter field holding a task's position within its
+// board column.
+const OrderField = "order"
+
+// orderGap is the distance between consecutive orders after renumbering,
+// leaving room to insert tasks by writing a single file.
+const orderGap = 1024
+
+// ErrWIP is returned when a move would exceed a column's WIP limit.
+var ErrWIP = errors.New("WIP limit reached")
+
+// Column is a board column with its tasks in manual order.
+type Column struct {
+	speccheck.BoardColumn
+	// Count is the number of tasks in the column, regardless of filters.
+	Count int `json:"count"`
+	// Over reports a column holding more tasks than its WIP limit.
+	Over  bool   `json:"over,omitempty"`
+	Tasks []Task `json:"tasks"`
+}
+
+// Board is the task board. Archived tasks are not shown.
+type Board struct {
+	Columns []Column `json:"columns"`
+	// Unmapped holds tasks whose status belongs to no column.
+	Unmapped []Task `json:"unmapped,omitempty"`
+}
+
+// Column returns the column with the given ID, ignoring case.
+func (b *Board) Column(id string) (*Column, bool) {
+	for i := range b.Columns {
+		if strings.EqualFold(b.Columns[i].ID, id) {
+			return &b.Columns[i], true
+		}
+	}
+	return nil, false
+}
+
+// columnOf returns the index of the column showing status, or -1.
+func (b *Board) columnOf(status string) int {
+	for i, c := range b.Columns {
+		for _, s := range c.Statuses {
+			if strings.EqualFold(s, status) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// BoardRule returns the effective board configuration.
+func (s *Store) BoardRule() speccheck.BoardRule {
+	return s.rules.Board.Effective(s.Enum("status"))
+}
+
+// Board returns the tasks matching f by column. Within a column, tasks with
+// an order come first, ascending, followed by the others by path. Counts and
+// WIP limits consider all tasks, not only the matching ones.
+func (s *Store) Board(f Filter) (*Board, error) {
+	ts, err := s.List(Filter{})
+	if err != nil {
+		return nil, err
+	}
+	b := &Board{}
+	for _, c := range s.BoardRule().Columns {
+		b.Columns = append(b.Columns, Column{BoardColumn: c, Tasks: []Task{}})
+	}
+	for _, t := range ts {
+		i := b.columnOf(t.Status)
+		if i >= 0 {
+			b.Columns[i].Count++
+		}
+		if !f.Match(t) {
+			continue
+		}
+		if i < 0 {
+			b.Unmapped = append(b.Unmapped, t)
+			continue
+		}
+		b.Columns[i].Tasks = append(b.Columns[i].Tasks, t)
+	}
+	for i := range b.Columns {
+		c := &b.Columns[i]
+		c.Over = c.WIP > 0 && c.Count > c.WIP
+		sortByOrder(c.Tasks)
+	}
+	return b, nil
+}
+
+func sortByOrder(ts []Task) {
+	sort.SliceStable(ts, func(i, j int) bool {
+		a, b := ts[i], ts[j]
+		if (a.Order > 0) != (b.Order > 0) {
+			return a.Order > 0
+		}
+		if a.Order != b.Order {
+			return a.Order < b.Order
+		}
+		return strings.ToLower(a.Path) < strings.ToLower(b.Path)
+	})
+}
+
+// Move places a task in a column at a position.
+type Move struct {
+	Path   string `json:"path"`
+	Column string `json:"column"`
+	// Index is the position among the column's other tasks; a negative or
+	// too large index appends.
+	Index int `json:"index"`
+	// Force ignores the column's WIP limit.
+	Force bool `json:"force,omitempty"`
+}
+
+// moveMu serializes moves, which read a column before writing its orders.
+var moveMu sync.Mutex
+
+// Move moves a task to a column position, writing its status and order in
+// one edit. The status is kept when the column already shows it, otherwise
+// it becomes the column's first status. Other tasks of the column are only
+// rewritten when their orders leave no room at the position; the new status
+// and order are validated first, and the other tasks get their orders back
+// when a write fails.
+func (s *Store) Move(m Move) (Task, error) {
+	moveMu.Lock()
+	defer moveMu.Unlock()
+	t, _, err := s.Get(m.Path)
+	if err != nil {
+		return Task{}, err
+	}
+	b, err := s.Board(Filter{})
+	if err != nil {
+		return Task{}, err
+	}
+	col, ok := b.Column(m.Column)
+	if !ok {
+		ids := make([]string, 0, len(b.Columns))
+		for _, c := range b.Columns {
+			ids = append(ids, c.ID)
+		}
+		return Task{}, fmt.Errorf("%w: unknown column %q (columns: %s)", ErrInvalid, m.Column, strings.Join(ids, ", "))
+	}
+	others := slices.DeleteFunc(slices.Clone(col.Tasks), func(o Task) bool { return o.Path == t.Path })
+	if len(others) == len(col.Tasks) && col.WIP > 0 && col.Count >= col.WIP && !m.Force {
+		return Task{}, fmt.Errorf("%w: column %s holds %d of %d tasks", ErrWIP, col.ID, col.Count, col.WIP)
+	}
+	status := col.Statuses[0]
+	for _, st := range col.Statuses {
+		if strings.EqualFold(st, t.Status) {
+			status = t.Status
+		}
+	}
+	idx := m.Index
+	if idx < 0 || idx > len(others) {
+		idx = len(others)
+	}
+	order, ok := orderBetween(others, idx)
+	if !ok {
+		order = (idx + 1) * orderGap
+	}
+	fields := map[string]string{"status": status, OrderField: strconv.Itoa(order)}
+	if err := s.apply(document.New(""), fields); err != nil {
+		return Task{}, err
+	}
+	var renumbered []Task
+	if !ok {
+		// Renumber the column around the moved task.
+		for i, o := range others {
+			want := (i + 1) * orderGap
+			if i >= idx {
+				want += orderGap
+			}
+			if o.Order == want {
+				continue
+			}
+			if _, err := s.Set(o.Path, map[string]string{OrderField: strconv.Itoa(want)}); err != nil {
+				s.restoreOrders(renumbered)
+				return Task{}, err
+			}
+			renumbered = append(renumbered, o)
+		}
+	}
+	n, err := s.Set(t.Path, fields)
+	if err != nil {
+		s.restoreOrders(renumbered)
+		return Task{}, err
+	}
+	return n, nil
+}
+
+// restoreOrders writes back the orders ts had before a failed move.
+func (s *Store) restoreOrders(ts []Task) {
+	for _, o := range ts {
+		old := ""
+		if o.Order > 0 {
+			old = strconv.Itoa(o.Order)
+		}
+		_, _ = s.Set(o.Path, map[string]string{OrderField: old})
+	}
+}
+
+// orderBetween returns an order placing a task at idx among ts without
+// changing ts; ok is false when every task must be renumbered.
+func orderBetween(ts []Task, idx int) (int, bool) {
+	for _, o := range ts {
+		if o.Order <= 0 {
+			return 0, false
+		}
+	}
+	lo := 0
+	if idx > 0 {
+		lo = ts[idx-1].Order
+	}
+	if idx == len(ts) {
+		return lo + orderGap, true
+	}
+	hi := ts[idx].Order
+	if hi-lo < 2 {
+		return 0, false
+	}
+	return lo + (hi-lo)/2, true
+}
@@ -0,0 +1,166 @@
+package taskstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const boardRules = `{
+  "dirs": {
+    "vibe-docs/task": {
+      "fields": { "status": { "enum": ["todo", "backlog", "doing", "done"] } },
+      "board": {
+        "columns": [
+          { "id": "todo", "title": "To do", "statuses": ["todo", "backlog"] },
+          { "id": "doing", "wip": 1 },
+          { "id": "done" }
+        ]
+      }
+    }
+  }
+}`
+
+func boardStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	s, root := newStore(t)
+	if err := os.WriteFile(filepath.Join(root, "vibe-docs", ".specrules.json"), []byte(boardRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, root
+}
+
+func paths(ts []Task) string {
+	var out []string
+	for _, t := range ts {
+		out = append(out, strings.TrimSuffix(t.Path, Suffix))
+	}
+	return strings.Join(out, ",")
+}
+
+func TestBoard(t *testing.T) {
+	s, root := boardStore(t)
+	write(t, root, "a.task.mdx", "---\ntitle: A\nstatus: todo\nowner: ann\n---\n")
+	write(t, root, "b.task.mdx", "---\ntitle: B\nstatus: backlog\norder: 5\n---\n")
+	write(t, root, "c.task.mdx", "---\ntitle: C\nstatus: doing\n---\n")
+	write(t, root, "d.task.mdx", "---\ntitle: D\nstatus: doing\n---\n")
+	write(t, root, "e.task.mdx", "---\ntitle: E\nstatus: weird\n---\n")
+	b, err := s.Board(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Columns) != 3 || b.Columns[0].Title != "To do" || b.Columns[1].Statuses[0] != "doing" {
+		t.Fatalf("unexpected columns: %+v", b.Columns)
+	}
+	if got := paths(b.Columns[0].Tasks); got != "b,a" {
+		t.Fatalf("todo order: %s", got)
+	}
+	if c := b.Columns[1]; c.Count != 2 || !c.Over {
+		t.Fatalf("doing column: %+v", c)
+	}
+	if got := paths(b.Unmapped); got != "e" {
+		t.Fatalf("unmapped: %s", got)
+	}
+	b, _ = s.Board(Filter{Owner: "ann"})
+	if got := paths(b.Columns[0].Tasks); got != "a" || b.Columns[0].Count != 2 {
+		t.Fatalf("filtered: %s %+v", got, b.Columns[0])
+	}
+}
+
+func TestMove(t *testing.T) {
+	s, root := boardStore(t)
+	write(t, root, "a.task.mdx", "---\ntitle: A\nstatus: todo\n---\n")
+	write(t, root, "b.task.mdx", "---\ntitle: B\nstatus: backlog\n---\n")
+	write(t, root, "c.task.mdx", "---\ntitle: C\nstatus: doing\n---\n\nBody\n")
+	write(t, root, "d.task.mdx", "---\ntitle: D\nstatus: done\n---\n")
+
+	// Moving in front of unordered tasks renumbers the column.
+	tk, err := s.Move(Move{Path: "b.task.mdx", Column: "todo", Index: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.Status != "backlog" || tk.Order != orderGap {
+		t.Fatalf("unexpected task: %+v", tk)
+	}
+	// A second move fits between existing orders and only writes the task.
+	tk, err = s.Move(Move{Path: "c.task.mdx", Column: "TODO", Index: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.Status != "todo" || tk.Order != orderGap+orderGap/2 {
+		t.Fatalf("unexpected task: %+v", tk)
+	}
+	want := "---\ntitle: C\nstatus: todo\norder: 1536\n---\n\nBody\n"
+	if got := read(t, root, "vibe-docs/task/c.task.mdx"); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	b, _ := s.Board(Filter{})
+	if got := paths(b.Columns[0].Tasks); got != "b,c,a" {
+		t.Fatalf("todo order: %s", got)
+	}
+
+	if _, err := s.Move(Move{Path: "a.task.mdx", Column: "doing", Index: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Move(Move{Path: "b.task.mdx", Column: "doing"}); !errors.Is(err, ErrWIP) {
+		t.Fatalf("want ErrWIP, got %v", err)
+	}
+	if _, err := s.Move(Move{Path: "b.task.mdx", Column: "doing", Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Move(Move{Path: "a.task.mdx", Column: "nope"}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("want ErrInvalid, got %v", err)
+	}
+}
+
+func TestMoveFailureKeepsColumn(t *testing.T) {
+	s, root := newStore(t)
+	rules := `{"dirs": {"vibe-docs/task": {
+	  "fields": { "status": { "enum": ["todo", "done"] } },
+	  "board": { "columns": [
+	    { "id": "todo" },
+	    { "id": "review", "statuses": ["review"] },
+	    { "id": "done" }
+	  ] }
+	}}}`
+	if err := os.WriteFile(filepath.Join(root, "vibe-docs", ".specrules.json"), []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, root, "a.task.mdx", "---\ntitle: A\nstatus: todo\n---\n")
+	write(t, root, "p.task.mdx", "---\ntitle: P\nstatus: review\n---\n")
+	write(t, root, "q.task.mdx", "---\ntitle: Q\nstatus: review\n---\n")
+	// review is not in the status enum, so the move fails before the
+	// unordered tasks of the column are renumbered.
+	if _, err := s.Move(Move{Path: "a.task.mdx", Column: "review", Index: 0}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("want ErrInvalid, got %v", err)
+	}
+	for _, p := range []string{"p", "q"} {
+		if got := read(t, root, "vibe-docs/task/"+p+".task.mdx"); strings.Contains(got, "order") {
+			t.Fatalf("%s renumbered by a failed move:\n%s", p, got)
+		}
+	}
+	if got := read(t, root, "vibe-docs/task/a.task.mdx"); got != "---\ntitle: A\nstatus: todo\n---\n" {
+		t.Fatalf("moved task changed:\n%s", got)
+	}
+}
+
+func TestDefaultColumns(t *testing.T) {
+	s, _ := newStore(t)
+	var ids []string
+	for _, c := range s.BoardRule().Columns {
+		ids = append(ids, c.ID)
+	}
+	if strings.Join(ids, ",") != "todo,in-progress,done" {
+		t.Fatalf("columns from status enum: %v", ids)
+	}
+}
@@ -0,0 +1,343 @@
+package taskstore
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"codectl/internal/document"
+	"codectl/internal/speccheck"
+	"codectl/internal/taskgen"
+)
+
+// DependsField is the frontmatter list of tasks a task is blocked by.
+const DependsField = "dependsOn"
+
+// Closed reports whether status is a closed status of the board (see
+// speccheck.BoardRule.Closed): the task no longer blocks others.
+func (s *Store) Closed(status string) bool {
+	return s.BoardRule().IsClosed(status)
+}
+
+// GraphNode is a task in the dependency graph.
+type GraphNode struct {
+	Path   string `json:"path"`
+	Title  string `json:"title,omitempty"`
+	Status string `json:"status,omitempty"`
+	// DependsOn are the resolved paths of the tasks this one waits for.
+	DependsOn []string `json:"dependsOn,omitempty"`
+	Archived  bool     `json:"archived,omitempty"`
+	// Blocked reports an open task with an unfinished or missing
+	// dependency.
+	Blocked bool `json:"blocked,omitempty"`
+
+	// depPos is the position of the entry naming each dependency, keyPos
+	// that of the dependsOn key.
+	depPos map[string]document.Pos
+	keyPos document.Pos
+}
+
+// MissingRef is a dependsOn entry that names no task.
+type MissingRef struct {
+	Path string `json:"path"`
+	Ref  string `json:"ref"`
+	// Line and Column locate the entry in the task file; zero when unknown.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+// Graph is the task dependency DAG. An edge runs from a dependency to the
+// task that depends on it.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	// Order lists the tasks outside cycles so that every task follows its
+	// dependencies.
+	Order []string `json:"order"`
+	// Unblocked lists the open tasks whose dependencies are all closed.
+	Unblocked []string     `json:"unblocked"`
+	Cycles    [][]string   `json:"cycles,omitempty"`
+	Missing   []MissingRef `json:"missing,omitempty"`
+
+	index  map[string]int
+	closed func(status string) bool
+}
+
+// Node returns the node of the task at path.
+func (g *Graph) Node(path string) (GraphNode, bool) {
+	i, ok := g.index[path]
+	if !ok {
+		return GraphNode{}, false
+	}
+	return g.Nodes[i], true
+}
+
+// Dependents returns the tasks that depend directly on path.
+func (g *Graph) Dependents(path string) []string {
+	var out []string
+	for _, n := range g.Nodes {
+		if slices.Contains(n.DependsOn, path) {
+			out = append(out, n.Path)
+		}
+	}
+	return out
+}
+
+// UnblockedBy returns the dependents of path that are no longer blocked.
+// Called after path was closed, it lists the tasks its closing released.
+func (g *Graph) UnblockedBy(path string) []string {
+	out := []string{}
+	for _, d := range g.Dependents(path) {
+		if n, _ := g.Node(d); !n.Blocked && !g.closed(n.Status) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// resolveRef returns the task path a dependsOn entry names: a path relative
+// to the task directory or the repository, with or without the suffix. A
+// bare file name also matches an archived task.
+func (g *Graph) resolveRef(ref string) (string, bool) {
+	ref = strings.TrimPrefix(strings.TrimSpace(ref), "./")
+	ref = strings.TrimPrefix(ref, taskgen.Dir+"/")
+	if ref == "" {
+		return "", false
+	}
+	if !strings.HasSuffix(strings.ToLower(ref), Suffix) {
+		ref += Suffix
+	}
+	ref = path.Clean(ref)
+	if _, ok := g.index[ref]; ok {
+		return ref, true
+	}
+	if !strings.Contains(ref, "/") {
+		if _, ok := g.index[path.Join(ArchiveDir, ref)]; ok {
+			return path.Join(ArchiveDir, ref), true
+		}
+	}
+	return "", false
+}
+
+// checkRefs reports dependsOn entries that name no task.
+func (s *Store) checkRefs(refs []string) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	g, err := s.Graph()
+	if err != nil {
+		return err
+	}
+	for _, r := range refs {
+		if _, ok := g.resolveRef(r); !ok {
+			return fmt.Errorf("%w: %s %q is not a task", ErrInvalid, DependsField, r)
+		}
+	}
+	return nil
+}
+
+// BuildGraph returns the dependency graph of tasks, including archived
+// ones so that dependencies on them resolve. closed reports the statuses of
+// tasks that no longer block others.
+func BuildGraph(tasks []Task, closed func(status string) bool) *Graph {
+	g := &Graph{Order: []string{}, Unblocked: []string{}, index: map[string]int{}, closed: closed}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Path < tasks[j].Path })
+	for i, t := range tasks {
+		g.Nodes = append(g.Nodes, GraphNode{Path: t.Path, Title: t.Title, Status: t.Status, Archived: t.Archived, keyPos: t.dependsKey, depPos: map[string]document.Pos{}})
+		g.index[t.Path] = i
+	}
+	for i, t := range tasks {
+		n := &g.Nodes[i]
+		for j, ref := range t.DependsOn {
+			pos := t.dependsKey
+			if j < len(t.dependsPos) {
+				pos = t.dependsPos[j]
+			}
+			dep, ok := g.resolveRef(ref)
+			if !ok {
+				g.Missing = append(g.Missing, MissingRef{Path: t.Path, Ref: ref, Line: pos.Line, Column: pos.Col})
+				n.Blocked = true
+				continue
+			}
+			if !slices.Contains(n.DependsOn, dep) {
+				n.DependsOn = append(n.DependsOn, dep)
+				n.depPos[dep] = pos
+			}
+		}
+	}
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		for _, d := range n.DependsOn {
+			if dn, _ := g.Node(d); !closed(dn.Status) {
+				n.Blocked = true
+			}
+		}
+		if closed(n.Status) {
+			n.Blocked = false
+		} else if !n.Blocked && !n.Archived {
+			g.Unblocked = append(g.Unblocked, n.Path)
+		}
+	}
+	g.Cycles = g.cycles()
+	g.Order = g.topo()
+	return g
+}
+
+// topo orders the nodes with Kahn's algorithm, taking the smallest path
+// first among ready nodes. Nodes on or behind a cycle are left out.
+func (g *Graph) topo() []string {
+	indeg := make([]int, len(g.Nodes))
+	for i, n := range g.Nodes {
+		indeg[i] = len(n.DependsOn)
+	}
+	var ready []int
+	for i, d := range indeg {
+		if d == 0 {
+			ready = append(ready, i)
+		}
+	}
+	out := []string{}
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		i := ready[0]
+		ready = ready[1:]
+		out = append(out, g.Nodes[i].Path)
+		for _, d := range g.Dependents(g.Nodes[i].Path) {
+			j := g.index[d]
+			if indeg[j]--; indeg[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+	return out
+}
+
+// cycles returns the strongly connected components that form cycles, each
+// sorted by path, using Tarjan's algorithm.
+func (g *Graph) cycles() [][]string {
+	var (
+		idx     = make([]int, len(g.Nodes))
+		low     = make([]int, len(g.Nodes))
+		onStack = make([]bool, len(g.Nodes))
+		stack   []int
+		next    = 1
+		out     [][]string
+	)
+	var visit func(v int)
+	visit = func(v int) {
+		idx[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, d := range g.Nodes[v].DependsOn {
+			w := g.index[d]
+			if idx[w] == 0 {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], idx[w])
+			}
+		}
+		if low[v] != idx[v] {
+			return
+		}
+		var comp []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			comp = append(comp, g.Nodes[w].Path)
+			if w == v {
+				break
+			}
+		}
+		if len(comp) > 1 || slices.Contains(g.Nodes[v].DependsOn, g.Nodes[v].Path) {
+			sort.Strings(comp)
+			out = append(out, comp)
+		}
+	}
+	for v := range g.Nodes {
+		if idx[v] == 0 {
+			visit(v)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i][0] < out[j][0] })
+	return out
+}
+
+// Findings returns the check findings of the graph by repository-relative
+// task path: dependencies that name no task, located at their entry, and
+// dependency cycles, located at the entry naming the next task of the cycle.
+func (g *Graph) Findings() map[string][]speccheck.Finding {
+	out := map[string][]speccheck.Finding{}
+	for _, m := range g.Missing {
+		rel := path.Join(taskgen.Dir, m.Path)
+		out[rel] = append(out[rel], speccheck.Finding{
+			Rule:     speccheck.RuleMissingDependency,
+			Severity: speccheck.SeverityError,
+			Field:    DependsField,
+			Message:  fmt.Sprintf("%s: %q is not a task", DependsField, m.Ref),
+			Line:     m.Line,
+			Column:   m.Column,
+		})
+	}
+	for _, c := range g.Cycles {
+		msg := "task depends on itself"
+		if len(c) > 1 {
+			msg = "dependency cycle between " + strings.Join(c, ", ")
+		}
+		for _, p := range c {
+			n, _ := g.Node(p)
+			pos := n.keyPos
+			for _, d := range n.DependsOn {
+				if slices.Contains(c, d) {
+					pos = n.depPos[d]
+					break
+				}
+			}
+			rel := path.Join(taskgen.Dir, p)
+			out[rel] = append(out[rel], speccheck.Finding{
+				Rule:     speccheck.RuleDependencyCycle,
+				Severity: speccheck.SeverityError,
+				Field:    DependsField,
+				Message:  msg,
+				Line:     pos.Line,
+				Column:   pos.Col,
+			})
+		}
+	}
+	return out
+}
+
+// Graph returns the dependency graph of all tasks, archived ones included.
+func (s *Store) Graph() (*Graph, error) {
+	ts, err := s.List(Filter{Archived: true})
+	if err != nil {
+		return nil, err
+	}
+	return BuildGraph(ts, s.Closed), nil
+}
+
+// Released returns the open tasks that depend on any of paths and have no
+// unfinished dependency left; after closing paths, these are the tasks the
+// change unblocked.
+func (s *Store) Released(paths []string) ([]string, error) {
+	out := []string{}
+	if len(paths) == 0 {
+		return out, nil
+	}
+	g, err := s.Graph()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		for _, d := range g.UnblockedBy(p) {
+			if !slices.Contains(out, d) {
+				out = append(out, d)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
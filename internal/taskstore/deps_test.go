@@ -0,0 +1,122 @@
+package taskstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"codectl/internal/speccheck"
+)
+
+func TestBuildGraph(t *testing.T) {
+	g := BuildGraph([]Task{
+		{Path: "a.task.mdx", Status: "done"},
+		{Path: "b.task.mdx", Status: "todo", DependsOn: []string{"a"}},
+		{Path: "c.task.mdx", Status: "todo", DependsOn: []string{"./b.task.mdx", "vibe-docs/task/a.task.mdx"}},
+		{Path: "d.task.mdx", Status: "todo", DependsOn: []string{"old", "ghost"}},
+		{Path: "archive/old.task.mdx", Status: "done", Archived: true},
+		{Path: "x.task.mdx", Status: "todo", DependsOn: []string{"y"}},
+		{Path: "y.task.mdx", Status: "todo", DependsOn: []string{"x"}},
+		{Path: "z.task.mdx", Status: "todo", DependsOn: []string{"z"}},
+	}, speccheck.BoardRule{}.Effective(nil).IsClosed)
+	if want := []string{"a.task.mdx", "archive/old.task.mdx", "b.task.mdx", "c.task.mdx", "d.task.mdx"}; !reflect.DeepEqual(g.Order, want) {
+		t.Fatalf("order: %v", g.Order)
+	}
+	if want := []string{"b.task.mdx"}; !reflect.DeepEqual(g.Unblocked, want) {
+		t.Fatalf("unblocked: %v", g.Unblocked)
+	}
+	if want := [][]string{{"x.task.mdx", "y.task.mdx"}, {"z.task.mdx"}}; !reflect.DeepEqual(g.Cycles, want) {
+		t.Fatalf("cycles: %v", g.Cycles)
+	}
+	if len(g.Missing) != 1 || g.Missing[0].Ref != "ghost" {
+		t.Fatalf("missing: %+v", g.Missing)
+	}
+	if n, _ := g.Node("d.task.mdx"); !n.Blocked || !reflect.DeepEqual(n.DependsOn, []string{"archive/old.task.mdx"}) {
+		t.Fatalf("d: %+v", n)
+	}
+	f := g.Findings()
+	if len(f["vibe-docs/task/d.task.mdx"]) != 1 || len(f["vibe-docs/task/x.task.mdx"]) != 1 || len(f["vibe-docs/task/z.task.mdx"]) != 1 {
+		t.Fatalf("findings: %+v", f)
+	}
+	if msg := f["vibe-docs/task/y.task.mdx"][0].Message; !strings.Contains(msg, "x.task.mdx, y.task.mdx") {
+		t.Fatalf("cycle message: %s", msg)
+	}
+}
+
+func TestUnblockedByDone(t *testing.T) {
+	s, root := newStore(t)
+	write(t, root, "a.task.mdx", "---\ntitle: A\nstatus: in-progress\n---\n")
+	write(t, root, "b.task.mdx", "---\ntitle: B\nstatus: todo\n---\n")
+	write(t, root, "c.task.mdx", "---\ntitle: C\nstatus: todo\ndependsOn:\n  - a\n---\n")
+	if _, err := s.Set("b.task.mdx", map[string]string{DependsField: "a, c"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, root, "vibe-docs/task/b.task.mdx"); !strings.Contains(got, "dependsOn:\n  - a\n  - c\n") {
+		t.Fatalf("dependsOn not written:\n%s", got)
+	}
+	if _, err := s.Set("b.task.mdx", map[string]string{DependsField: "a, ghost"}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("unknown dependency: want ErrInvalid, got %v", err)
+	}
+	if _, err := s.Set("a.task.mdx", map[string]string{"status": "done"}); err != nil {
+		t.Fatal(err)
+	}
+	g, err := s.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := g.UnblockedBy("a.task.mdx"); !reflect.DeepEqual(got, []string{"c.task.mdx"}) {
+		t.Fatalf("unblocked by a: %v", got)
+	}
+}
+
+func TestFindingPositions(t *testing.T) {
+	s, root := newStore(t)
+	write(t, root, "a.task.mdx", "---\ntitle: A\nstatus: todo\ndependsOn:\n  - b\n  - ghost\n---\n")
+	write(t, root, "b.task.mdx", "---\ntitle: B\ndependsOn: a\n---\n")
+	g, err := s.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := g.Findings()
+	a := f["vibe-docs/task/a.task.mdx"]
+	if len(a) != 2 || a[0].Rule != speccheck.RuleMissingDependency || a[0].Line != 6 || a[0].Column != 5 {
+		t.Fatalf("a: %+v", a)
+	}
+	if a[1].Rule != speccheck.RuleDependencyCycle || a[1].Line != 5 || a[1].Column != 5 {
+		t.Fatalf("a cycle: %+v", a[1])
+	}
+	if b := f["vibe-docs/task/b.task.mdx"]; len(b) != 1 || b[0].Line != 3 || b[0].Column != 12 {
+		t.Fatalf("b: %+v", b)
+	}
+}
+
+func TestClosedStatuses(t *testing.T) {
+	s, root := newStore(t)
+	if !s.Closed("DONE") || s.Closed("todo") {
+		t.Fatal("enum without canceled: want done closed only")
+	}
+	rules := `{"dirs":{"vibe-docs/task":{"fields":{"status":{"enum":["open","shipped","dropped"]}},` +
+		`"board":{"columns":[{"id":"open"},{"id":"closed","statuses":["shipped","dropped"]}]}}}}`
+	if err := os.WriteFile(filepath.Join(root, "vibe-docs", ".specrules.json"), []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Closed("shipped") || !s.Closed("dropped") || s.Closed("done") {
+		t.Fatalf("closed from last column: %v", s.BoardRule().Closed)
+	}
+	write(t, root, "a.task.mdx", "---\ntitle: A\nstatus: dropped\n---\n")
+	write(t, root, "b.task.mdx", "---\ntitle: B\nstatus: open\ndependsOn: [a]\n---\n")
+	g, err := s.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g.Unblocked, []string{"b.task.mdx"}) {
+		t.Fatalf("unblocked: %v", g.Unblocked)
+	}
+}
@@ -0,0 +1,530 @@
+// Package taskstore reads and edits the task documents under vibe-docs/task:
+// filtered listing, creation from templates, frontmatter field edits checked
+// against the rules file, deletion, archiving and bulk edits. Task paths are
+// relative to the task directory.
+package taskstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"codectl/internal/document"
+	"codectl/internal/history"
+	"codectl/internal/safefs"
+	"codectl/internal/speccheck"
+	"codectl/internal/specmove"
+	"codectl/internal/spectemplate"
+	"codectl/internal/taskgen"
+)
+
+// ArchiveDir is the archive directory, relative to the task directory.
+const ArchiveDir = "archive"
+
+// Suffix is the file name suffix of task documents.
+const Suffix = ".task.mdx"
+
+var (
+	// ErrInvalid is returned for field values rejected by the rules file.
+	ErrInvalid = errors.New("invalid field value")
+	// ErrNotFound is returned for paths that are not task documents.
+	ErrNotFound = errors.New("task not found")
+)
+
+// Task is the summary of a task document.
+type Task struct {
+	Path     string `json:"path"` // relative to the task directory
+	Title    string `json:"title,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Owner    string `json:"owner,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	Due      string `json:"due,omitempty"`
+	// Order is the manual position within its board column; 0 when unset.
+	Order int `json:"order,omitempty"`
+	// DependsOn lists the tasks this one is blocked by, as written.
+	DependsOn []string          `json:"dependsOn,omitempty"`
+	Archived  bool              `json:"archived,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+
+	// dependsKey and dependsPos are the file positions of the dependsOn key
+	// and of each DependsOn entry, for check findings.
+	dependsKey document.Pos
+	dependsPos []document.Pos
+}
+
+// Parse returns the summary of the task document b; Path is left empty.
+func Parse(b []byte) Task {
+	doc, err := document.Parse(b)
+	if doc == nil || !doc.HasFrontmatter {
+		return Task{}
+	}
+	t := Task{Fields: map[string]string{}}
+	if err == nil {
+		t.Fields = doc.Fields()
+	}
+	t.Title = doc.String("title")
+	t.Status = doc.String("status")
+	t.Owner = doc.String("owner")
+	t.Priority = doc.String("priority")
+	t.Due = doc.String("due")
+	t.Order, _ = strconv.Atoi(doc.String(OrderField))
+	t.DependsOn = doc.Strings(DependsField)
+	t.dependsKey, _ = doc.KeyPos(DependsField)
+	if v := doc.Node(DependsField); v != nil && v.Kind == yaml.SequenceNode {
+		for _, c := range v.Content {
+			t.dependsPos = append(t.dependsPos, document.Pos{Line: c.Line + 1, Col: c.Column})
+		}
+	} else if pos, ok := doc.ValuePos(DependsField); ok {
+		t.dependsPos = []document.Pos{pos}
+	}
+	return t
+}
+
+// Filter selects tasks. Empty fields match everything; Status, Owner and
+// Priority compare case-insensitively and Query is a substring of the title
+// or path. Archived tasks are only listed when Archived is set.
+type Filter struct {
+	Status   string   `json:"status,omitempty"`
+	Owner    string   `json:"owner,omitempty"`
+	Priority string   `json:"priority,omitempty"`
+	Query    string   `json:"q,omitempty"`
+	Paths    []string `json:"paths,omitempty"`
+	Archived bool     `json:"archived,omitempty"`
+}
+
+// Match reports whether t passes the filter.
+func (f Filter) Match(t Task) bool {
+	if t.Archived && !f.Archived {
+		return false
+	}
+	eq := func(want, got string) bool {
+		want = strings.TrimSpace(want)
+		return want == "" || strings.EqualFold(want, got)
+	}
+	if !eq(f.Status, t.Status) || !eq(f.Owner, t.Owner) || !eq(f.Priority, t.Priority) {
+		return false
+	}
+	if q := strings.ToLower(strings.TrimSpace(f.Query)); q != "" &&
+		!strings.Contains(strings.ToLower(t.Title), q) && !strings.Contains(strings.ToLower(t.Path), q) {
+		return false
+	}
+	if len(f.Paths) > 0 {
+		for _, p := range f.Paths {
+			if path.Clean(filepath.ToSlash(p)) == t.Path {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Store is the task directory of a repository.
+type Store struct {
+	root  string // repository root
+	dir   string // task directory
+	rules *speccheck.Rules
+}
+
+// Open returns the store of the repository at root. A broken rules file is
+// reported alongside a store using the default rules.
+func Open(root string) (*Store, error) {
+	rules, err := speccheck.Load(root)
+	return &Store{
+		root:  root,
+		dir:   filepath.Join(root, filepath.FromSlash(taskgen.Dir)),
+		rules: rules.For(taskgen.Dir + "/x" + Suffix),
+	}, err
+}
+
+// Dir returns the absolute task directory.
+func (s *Store) Dir() string { return s.dir }
+
+// Rules returns the rules that apply to task documents.
+func (s *Store) Rules() *speccheck.Rules { return s.rules }
+
+// Enum returns the allowed values of the field key, or nil when any value
+// is accepted.
+func (s *Store) Enum(key string) []string { return s.rules.Fields[key].Enum }
+
+// Validate returns value in the spelling of the field's enum, matching
+// case-insensitively. Fields without an enum accept any value.
+func (s *Store) Validate(key, value string) (string, error) {
+	enum := s.Enum(key)
+	if len(enum) == 0 || value == "" {
+		return value, nil
+	}
+	for _, e := range enum {
+		if strings.EqualFold(e, strings.TrimSpace(value)) {
+			return e, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s %q (allowed: %s)", ErrInvalid, key, value, strings.Join(enum, ", "))
+}
+
+// List returns the tasks matching f, sorted by path.
+func (s *Store) List(f Filter) ([]Task, error) {
+	out := []Task{}
+	if st, err := os.Stat(s.dir); err != nil || !st.IsDir() {
+		return out, nil
+	}
+	err := filepath.WalkDir(s.dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(strings.ToLower(d.Name()), Suffix) {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, p)
+		if err != nil {
+			return nil
+		}
+		t, _, err := s.Get(filepath.ToSlash(rel))
+		if err == nil && f.Match(t) {
+			out = append(out, t)
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return strings.ToLower(out[i].Path) < strings.ToLower(out[j].Path) })
+	return out, err
+}
+
+// Get reads the task at rel.
+func (s *Store) Get(rel string) (Task, []byte, error) {
+	rel, err := s.clean(rel)
+	if err != nil {
+		return Task{}, nil, err
+	}
+	b, err := safefs.ReadFile(s.dir, rel)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = fmt.Errorf("%w: %s", ErrNotFound, rel)
+		}
+		return Task{}, nil, err
+	}
+	t := Parse(b)
+	t.Path = rel
+	t.Archived = strings.HasPrefix(rel, ArchiveDir+"/")
+	return t, b, nil
+}
+
+// Find resolves name to a task: an absolute path, a path relative to the
+// task directory or the repository with or without the suffix, or a unique
+// case-insensitive part of a task file name. Archived tasks are included.
+func (s *Store) Find(name string) (Task, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Task{}, fmt.Errorf("%w: empty name", ErrNotFound)
+	}
+	if filepath.IsAbs(name) {
+		rel, err := filepath.Rel(s.dir, name)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return Task{}, fmt.Errorf("%w: %s is outside %s", ErrNotFound, name, taskgen.Dir)
+		}
+		name = rel
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(filepath.ToSlash(name), "./"), taskgen.Dir+"/")
+	if !strings.HasSuffix(strings.ToLower(rel), Suffix) {
+		rel += Suffix
+	}
+	if t, _, err := s.Get(rel); err == nil {
+		return t, nil
+	}
+	ts, err := s.List(Filter{Archived: true})
+	if err != nil {
+		return Task{}, err
+	}
+	var hits []Task
+	for _, t := range ts {
+		if strings.Contains(strings.ToLower(path.Base(t.Path)), strings.ToLower(name)) {
+			hits = append(hits, t)
+		}
+	}
+	switch len(hits) {
+	case 0:
+		return Task{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	case 1:
+		return hits[0], nil
+	}
+	names := make([]string, 0, len(hits))
+	for _, t := range hits {
+		names = append(names, t.Path)
+	}
+	return Task{}, fmt.Errorf("%q matches several tasks: %s", name, strings.Join(names, ", "))
+}
+
+func (s *Store) clean(rel string) (string, error) {
+	rel, err := safefs.Clean(filepath.ToSlash(strings.TrimSpace(rel)))
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+	if !strings.HasSuffix(strings.ToLower(rel), Suffix) {
+		return "", fmt.Errorf("%w: %s is not a %s file", ErrNotFound, rel, Suffix)
+	}
+	return rel, nil
+}
+
+// Create describes a new task.
+type Create struct {
+	Title string
+	// Template names a spec template (see spectemplate) rendered instead of
+	// the standard task skeleton.
+	Template string
+	// Vars are extra template variables.
+	Vars map[string]string
+	// Fields are frontmatter values set on the new document.
+	Fields map[string]string
+	Now    time.Time
+}
+
+// Create writes a new task with a generated file name and returns it.
+func (s *Store) Create(c Create) (Task, error) {
+	if c.Now.IsZero() {
+		c.Now = time.Now()
+	}
+	title := strings.TrimSpace(c.Title)
+	if title == "" {
+		title = taskgen.DefaultTitle
+	}
+	content := taskgen.Blank(title, c.Now)
+	if c.Template != "" {
+		t, err := spectemplate.Find(s.root, c.Template)
+		if err != nil {
+			return Task{}, err
+		}
+		vars := map[string]string{
+			"title":       title,
+			"description": title,
+			"date":        c.Now.Format("2006-01-02"),
+			"slug":        taskgen.Slug(title, "task"),
+			"author":      "",
+		}
+		for k, v := range c.Vars {
+			vars[k] = v
+		}
+		out, err := t.Render(vars)
+		if err != nil {
+			return Task{}, err
+		}
+		content = []byte(out)
+	}
+	doc, err := document.Parse(content)
+	if err != nil {
+		return Task{}, err
+	}
+	_ = doc.Set("title", title)
+	// Spec templates start as drafts; tasks start in the initial task state.
+	if v, err := s.Validate("status", doc.String("status")); err != nil || v == "" {
+		_ = doc.Set("status", taskgen.StatusTodo)
+	}
+	if !doc.Has("createdAt") {
+		_ = doc.Set("createdAt", c.Now.Format(time.RFC3339))
+	}
+	if err := s.apply(doc, c.Fields); err != nil {
+		return Task{}, err
+	}
+	rel := strings.TrimPrefix(taskgen.NewPath(s.root, c.Now, title, nil), taskgen.Dir+"/")
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return Task{}, err
+	}
+	b := doc.Bytes()
+	if err := safefs.WriteFile(s.dir, rel, b, 0o644); err != nil {
+		return Task{}, err
+	}
+	t := Parse(b)
+	t.Path = rel
+	return t, nil
+}
+
+// apply sets fields on doc after validating them; an empty value removes
+// the field. DependsField takes a comma-separated list of existing tasks.
+func (s *Store) apply(doc *document.Document, fields map[string]string) error {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.TrimSpace(k) == "" {
+			return fmt.Errorf("%w: empty field name", ErrInvalid)
+		}
+		v, err := s.Validate(k, fields[k])
+		if err != nil {
+			return err
+		}
+		if v == "" {
+			if k == "title" {
+				return fmt.Errorf("%w: title cannot be removed", ErrInvalid)
+			}
+			doc.Delete(k)
+			continue
+		}
+		if k == DependsField {
+			var refs []string
+			for _, r := range strings.Split(v, ",") {
+				if r = strings.TrimSpace(r); r != "" {
+					refs = append(refs, r)
+				}
+			}
+			if err := s.checkRefs(refs); err != nil {
+				return err
+			}
+			if err := doc.SetStrings(k, refs); err != nil {
+				return err
+			}
+			continue
+		}
+		if k == OrderField {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("%w: %s %q is not a non-negative integer", ErrInvalid, k, v)
+			}
+			if err := doc.SetRaw(k, strconv.Itoa(n)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := doc.Set(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set changes frontmatter fields of the task at rel, keeping the body and
+// the other fields as written. An empty value removes the field. The
+// previous content is recorded in the file history.
+func (s *Store) Set(rel string, fields map[string]string) (Task, error) {
+	t, b, err := s.Get(rel)
+	if err != nil {
+		return Task{}, err
+	}
+	doc, err := document.Parse(b)
+	if err != nil {
+		return Task{}, err
+	}
+	if err := s.apply(doc, fields); err != nil {
+		return Task{}, err
+	}
+	out := doc.Bytes()
+	if string(out) == string(b) {
+		return t, nil
+	}
+	if _, err := history.Snapshot(s.dir, t.Path, history.OpWrite); err != nil {
+		return Task{}, err
+	}
+	if err := safefs.WriteFile(s.dir, t.Path, out, 0o644); err != nil {
+		return Task{}, err
+	}
+	n := Parse(out)
+	n.Path, n.Archived = t.Path, t.Archived
+	return n, nil
+}
+
+// Delete removes the task at rel after recording it in the file history.
+func (s *Store) Delete(rel string) error {
+	t, _, err := s.Get(rel)
+	if err != nil {
+		return err
+	}
+	if _, err := history.Snapshot(s.dir, t.Path, history.OpDelete); err != nil {
+		return err
+	}
+	return safefs.Remove(s.dir, t.Path)
+}
+
+// Archive moves the task at rel into ArchiveDir, rewriting links to it, and
+// returns it at its new path.
+func (s *Store) Archive(rel string) (Task, error) {
+	t, _, err := s.Get(rel)
+	if err != nil {
+		return Task{}, err
+	}
+	if t.Archived {
+		return t, nil
+	}
+	dest := path.Join(ArchiveDir, path.Base(t.Path))
+	if _, err := safefs.Lstat(s.dir, dest); err == nil {
+		return Task{}, fmt.Errorf("%s already exists", path.Join(taskgen.Dir, dest))
+	}
+	if _, err := history.Snapshot(s.dir, t.Path, history.OpRename); err != nil {
+		return Task{}, err
+	}
+	p, err := specmove.New(s.root, path.Join(taskgen.Dir, t.Path), path.Join(taskgen.Dir, dest))
+	if err != nil {
+		return Task{}, err
+	}
+	if err := p.Apply(); err != nil {
+		return Task{}, err
+	}
+	t, _, err = s.Get(dest)
+	return t, err
+}
+
+// Action is a bulk operation.
+type Action string
+
+const (
+	ActionSet     Action = "set"
+	ActionDelete  Action = "delete"
+	ActionArchive Action = "archive"
+)
+
+// Outcome is the result of a bulk operation on one task.
+type Outcome struct {
+	Path  string `json:"path"`
+	Task  *Task  `json:"task,omitempty"` // the task afterwards; nil when deleted or failed
+	Error string `json:"error,omitempty"`
+}
+
+// Bulk applies action to every task matching f. Field values go through the
+// validation of Set before anything is written, so an invalid value changes
+// no task; other failures are reported per task.
+func (s *Store) Bulk(f Filter, action Action, fields map[string]string) ([]Outcome, error) {
+	switch action {
+	case ActionSet:
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%w: no fields to set", ErrInvalid)
+		}
+		if err := s.apply(document.New(""), fields); err != nil {
+			return nil, err
+		}
+	case ActionDelete, ActionArchive:
+	default:
+		return nil, fmt.Errorf("%w: unknown action %q (want set|delete|archive)", ErrInvalid, action)
+	}
+	ts, err := s.List(f)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Outcome, 0, len(ts))
+	for _, t := range ts {
+		o := Outcome{Path: t.Path}
+		var err error
+		switch action {
+		case ActionSet:
+			var n Task
+			if n, err = s.Set(t.Path, fields); err == nil {
+				o.Task = &n
+			}
+		case ActionDelete:
+			err = s.Delete(t.Path)
+		case ActionArchive:
+			var n Task
+			if n, err = s.Archive(t.Path); err == nil {
+				o.Task = &n
+			}
+		}
+		if err != nil {
+			o.Error = err.Error()
+		}
+		out = append(out, o)
+	}
+	return out, nil
+}
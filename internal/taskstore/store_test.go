@@ -0,0 +1,213 @@
+package taskstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rulesJSON = `{
+  "dirs": {
+    "vibe-docs/task": {
+      "fields": {
+        "status": { "enum": ["todo", "in-progress", "done"] },
+        "priority": { "enum": ["P0", "P1", "P2"] }
+      }
+    }
+  }
+}`
+
+func newStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	_ = os.MkdirAll(filepath.Join(root, "vibe-docs", "task"), 0o755)
+	if err := os.WriteFile(filepath.Join(root, "vibe-docs", ".specrules.json"), []byte(rulesJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, root
+}
+
+func write(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, "vibe-docs", "task", filepath.FromSlash(rel))
+	_ = os.MkdirAll(filepath.Dir(p), 0o755)
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, root, rel string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestCreate(t *testing.T) {
+	s, root := newStore(t)
+	now := time.Date(2025, 9, 14, 10, 0, 0, 0, time.UTC)
+	tk, err := s.Create(Create{Title: "Add login", Fields: map[string]string{"priority": "p1", "owner": "ann"}, Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.Path != "250914-100000-add-login.task.mdx" || tk.Status != "todo" || tk.Priority != "P1" || tk.Owner != "ann" {
+		t.Fatalf("unexpected task: %+v", tk)
+	}
+	got := read(t, root, "vibe-docs/task/"+tk.Path)
+	if !strings.Contains(got, "## 验收标准") || !strings.Contains(got, "createdAt:") {
+		t.Fatalf("missing skeleton:\n%s", got)
+	}
+	if _, err := s.Create(Create{Title: "x", Fields: map[string]string{"priority": "P9"}, Now: now}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("want ErrInvalid, got %v", err)
+	}
+
+	tpl := filepath.Join(root, "vibe-docs", "templates")
+	_ = os.MkdirAll(tpl, 0o755)
+	_ = os.WriteFile(filepath.Join(tpl, "bug.mdx"), []byte("---\ntitle: {{.title}}\nstatus: in-progress\n---\n## 复现\n- {{.description}}\n"), 0o644)
+	tk, err = s.Create(Create{Title: "Crash", Template: "bug", Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.Status != "in-progress" || !strings.Contains(read(t, root, "vibe-docs/task/"+tk.Path), "## 复现\n- Crash") {
+		t.Fatalf("template not rendered: %+v", tk)
+	}
+}
+
+func TestSetKeepsBody(t *testing.T) {
+	s, root := newStore(t)
+	write(t, root, "a.task.mdx", "---\ntitle: A # keep\nstatus: todo\nowner: bob\n---\n\nBody text\n")
+	tk, err := s.Set("a.task.mdx", map[string]string{"status": "DONE", "owner": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.Status != "done" || tk.Owner != "" {
+		t.Fatalf("unexpected task: %+v", tk)
+	}
+	want := "---\ntitle: A # keep\nstatus: done\n---\n\nBody text\n"
+	if got := read(t, root, "vibe-docs/task/a.task.mdx"); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if _, err := s.Set("a.task.mdx", map[string]string{"status": "later"}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("want ErrInvalid, got %v", err)
+	}
+	if _, err := s.Set("../x.task.mdx", map[string]string{"status": "done"}); err == nil {
+		t.Fatal("escape accepted")
+	}
+	if _, err := s.Set("missing.task.mdx", map[string]string{"status": "done"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+}
+
+func TestArchiveAndDelete(t *testing.T) {
+	s, root := newStore(t)
+	write(t, root, "a.task.mdx", "---\ntitle: A\nstatus: done\n---\n")
+	write(t, root, "b.task.mdx", "---\ntitle: B\nstatus: todo\n---\n\nAfter [A](./a.task.mdx).\n")
+	tk, err := s.Archive("a.task.mdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tk.Path != "archive/a.task.mdx" || !tk.Archived {
+		t.Fatalf("unexpected task: %+v", tk)
+	}
+	if got := read(t, root, "vibe-docs/task/b.task.mdx"); !strings.Contains(got, "(./archive/a.task.mdx)") {
+		t.Fatalf("link not rewritten:\n%s", got)
+	}
+	if ts, _ := s.List(Filter{}); len(ts) != 1 || ts[0].Path != "b.task.mdx" {
+		t.Fatalf("archived task listed: %+v", ts)
+	}
+	if ts, _ := s.List(Filter{Archived: true}); len(ts) != 2 {
+		t.Fatalf("want 2 tasks with archive, got %+v", ts)
+	}
+	if err := s.Delete("b.task.mdx"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "vibe-docs", "task", "b.task.mdx")); !os.IsNotExist(err) {
+		t.Fatalf("not deleted: %v", err)
+	}
+}
+
+func TestBulk(t *testing.T) {
+	s, root := newStore(t)
+	write(t, root, "a.task.mdx", "---\ntitle: A\nstatus: todo\nowner: ann\n---\n")
+	write(t, root, "b.task.mdx", "---\ntitle: B\nstatus: todo\nowner: bob\n---\n")
+	write(t, root, "c.task.mdx", "---\ntitle: C\nstatus: done\nowner: ann\n---\n")
+
+	res, err := s.Bulk(Filter{Owner: "ANN"}, ActionSet, map[string]string{"priority": "p0"})
+	if err != nil || len(res) != 2 {
+		t.Fatalf("bulk set: %v %+v", err, res)
+	}
+	for _, o := range res {
+		if o.Error != "" || o.Task == nil || o.Task.Priority != "P0" {
+			t.Fatalf("unexpected outcome: %+v", o)
+		}
+	}
+	if _, err := s.Bulk(Filter{}, ActionSet, map[string]string{"status": "nope"}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("want ErrInvalid, got %v", err)
+	}
+	if ts, _ := s.List(Filter{Status: "nope"}); len(ts) != 0 {
+		t.Fatalf("invalid bulk wrote tasks: %+v", ts)
+	}
+	before := read(t, root, "vibe-docs/task/a.task.mdx")
+	for _, fields := range []map[string]string{
+		{"priority": "P2", OrderField: "x"},
+		{"priority": "P2", "title": ""},
+		{"priority": "P2", DependsField: "b, ghost"},
+	} {
+		if _, err := s.Bulk(Filter{}, ActionSet, fields); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("bulk %v: want ErrInvalid, got %v", fields, err)
+		}
+	}
+	if got := read(t, root, "vibe-docs/task/a.task.mdx"); got != before {
+		t.Fatalf("invalid bulk changed a task:\n%s", got)
+	}
+	res, err = s.Bulk(Filter{Status: "done"}, ActionArchive, nil)
+	if err != nil || len(res) != 1 || res[0].Task == nil || res[0].Task.Path != "archive/c.task.mdx" {
+		t.Fatalf("bulk archive: %v %+v", err, res)
+	}
+	res, err = s.Bulk(Filter{Paths: []string{"b.task.mdx"}}, ActionDelete, nil)
+	if err != nil || len(res) != 1 || res[0].Error != "" {
+		t.Fatalf("bulk delete: %v %+v", err, res)
+	}
+	if ts, _ := s.List(Filter{}); len(ts) != 1 || ts[0].Path != "a.task.mdx" {
+		t.Fatalf("unexpected remaining tasks: %+v", ts)
+	}
+	if _, err := s.Bulk(Filter{}, "rename", nil); !errors.Is(err, ErrInvalid) {
+		t.Fatal("unknown action accepted")
+	}
+}
+
+func TestFind(t *testing.T) {
+	s, root := newStore(t)
+	write(t, root, "250914-100000-add-login.task.mdx", "---\ntitle: Login\n---\n")
+	write(t, root, "250914-110000-add-logout.task.mdx", "---\ntitle: Logout\n---\n")
+	write(t, root, "archive/250901-090000-setup.task.mdx", "---\ntitle: Setup\n---\n")
+	for _, name := range []string{
+		"250914-100000-add-login",
+		"vibe-docs/task/250914-100000-add-login.task.mdx",
+		filepath.Join(root, "vibe-docs", "task", "250914-100000-add-login.task.mdx"),
+		"LOGIN",
+	} {
+		if tk, err := s.Find(name); err != nil || tk.Title != "Login" {
+			t.Fatalf("Find(%q) = %+v, %v", name, tk, err)
+		}
+	}
+	if tk, err := s.Find("setup"); err != nil || !tk.Archived {
+		t.Fatalf("archived: %+v %v", tk, err)
+	}
+	if _, err := s.Find("add-log"); err == nil || !strings.Contains(err.Error(), "several") {
+		t.Fatalf("ambiguous: %v", err)
+	}
+	if _, err := s.Find("nothing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing: %v", err)
+	}
+}
@@ -20,6 +20,7 @@ func mountAPI(mux *http.ServeMux) {
 	// FS
 	mux.HandleFunc("/api/fs/tree", fsTreeHandler)
 	mux.HandleFunc("/api/fs/read", fsReadHandler)
+	mux.HandleFunc("/api/fs/raw", fsRawHandler)
 	mux.HandleFunc("/api/fs/write", fsWriteHandler)
 	mux.HandleFunc("/api/fs/rename", fsRenameHandler)
 	mux.HandleFunc("/api/fs/delete", fsDeleteHandler)
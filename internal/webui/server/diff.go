@@ -13,7 +13,9 @@ import (
 	"strings"
 	"time"
 
+	"codectl/internal/safefs"
 	sys "codectl/internal/system"
+	"codectl/internal/workspace"
 )
 
 type changeItem struct {
@@ -22,8 +24,9 @@ type changeItem struct {
 	Group  string `json:"group"`  // Staged|Unstaged|Untracked
 }
 
-// diffChangesHandler lists working tree changes similar to TUI Diff tab.
-// GET /api/diff/changes?mode=all|staged|worktree&specOnly=0|1
+// diffChangesHandler lists working tree changes similar to TUI Diff tab,
+// limited to the workspace root and with paths relative to it.
+// GET /api/diff/changes?root=&mode=all|staged|worktree&specOnly=0|1
 func diffChangesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -31,16 +34,21 @@ func diffChangesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
 	defer cancel()
-	root, err := sys.GitRoot(ctx, ".")
-	if err != nil || strings.TrimSpace(root) == "" {
-		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("not in a git repository")))
+	dir, err := workspace.Resolve(ctx, r.URL.Query().Get("root"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	root, prefix, err := gitWorkspace(ctx, dir)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
 	mode := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("mode")))
 	specOnly := strings.TrimSpace(r.URL.Query().Get("specOnly")) == "1" || strings.ToLower(strings.TrimSpace(r.URL.Query().Get("specOnly"))) == "true"
 
 	// Always use porcelain to build list. Mode only affects grouping semantics a bit; we keep full list.
-	out, err := runGitOutput(ctx, root, "status", "--porcelain=1")
+	out, err := runGitOutput(ctx, root, "--literal-pathspecs", "status", "--porcelain=1", "--", pathspecOr(prefix))
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, errJSON(err))
 		return
@@ -62,8 +70,12 @@ func diffChangesHandler(w http.ResponseWriter, r *http.Request) {
 		if i := strings.Index(rest, " -> "); i >= 0 {
 			p = strings.TrimSpace(rest[i+4:])
 		}
-		// Normalize to forward slashes
+		// Normalize to forward slashes, relative to the workspace root
 		p = filepath.ToSlash(p)
+		if !strings.HasPrefix(p, prefix) || p == prefix {
+			continue
+		}
+		p = strings.TrimPrefix(p, prefix)
 		group := ""
 		switch {
 		case xy == "??":
@@ -115,8 +127,9 @@ func orderGroup(g string) int {
 	}
 }
 
-// diffFileHandler returns a unified diff for a file/path.
-// GET /api/diff/file?path=...&mode=all|staged|worktree
+// diffFileHandler returns a unified diff for a file/path relative to the
+// workspace root.
+// GET /api/diff/file?root=&path=...&mode=all|staged|worktree
 func diffFileHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -127,16 +140,30 @@ func diffFileHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("missing path")))
 		return
 	}
+	clean, err := safefs.Clean(p)
+	if err != nil || clean == "." {
+		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("path is outside the workspace root")))
+		return
+	}
+	p = filepath.ToSlash(clean)
 	mode := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("mode")))
 	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format"))) // "split" for side-by-side rows
 	ctx, cancel := context.WithTimeout(r.Context(), 6*time.Second)
 	defer cancel()
-	root, err := sys.GitRoot(ctx, ".")
-	if err != nil || strings.TrimSpace(root) == "" {
-		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("not in a git repository")))
+	dir, err := workspace.Resolve(ctx, r.URL.Query().Get("root"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	root, prefix, err := gitWorkspace(ctx, dir)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	args := []string{"-c", "color.ui=false", "-c", "core.pager=cat", "diff", "--no-ext-diff"}
+	args := []string{"-c", "color.ui=false", "-c", "core.pager=cat", "--literal-pathspecs", "diff", "--no-ext-diff"}
+	if prefix != "" {
+		args = append(args, "--relative="+prefix)
+	}
 	switch mode {
 	case "staged":
 		args = append(args, "--cached")
@@ -145,7 +172,7 @@ func diffFileHandler(w http.ResponseWriter, r *http.Request) {
 	default: // all
 		args = append(args, "HEAD")
 	}
-	args = append(args, "--", p)
+	args = append(args, "--", prefix+p)
 	out, err := runGitOutput(ctx, root, args...)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, errJSON(err))
@@ -155,7 +182,7 @@ func diffFileHandler(w http.ResponseWriter, r *http.Request) {
 	if strings.TrimSpace(out) == "" {
 		out = "(no diff) — file might be untracked or unchanged)"
 	}
-	res := map[string]any{"path": filepath.ToSlash(p), "mode": modeOrAll(mode), "diff": out}
+	res := map[string]any{"path": p, "mode": modeOrAll(mode), "diff": out}
 	if format == "split" {
 		rows := unifiedToSplit(out)
 		res["split"] = rows
@@ -163,6 +190,29 @@ func diffFileHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, res)
 }
 
+// gitWorkspace returns the top level of the repository holding the
+// workspace root dir and the path of dir within it: empty at the top level,
+// otherwise ending in a slash.
+func gitWorkspace(ctx context.Context, dir string) (root, prefix string, err error) {
+	root, err = sys.GitRoot(ctx, dir)
+	if err != nil || strings.TrimSpace(root) == "" {
+		return "", "", errors.New("not in a git repository")
+	}
+	prefix, err = runGitOutput(ctx, dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", "", err
+	}
+	return root, strings.TrimRight(prefix, "\r\n"), nil
+}
+
+// pathspecOr returns prefix as a pathspec, or "." for the whole repository.
+func pathspecOr(prefix string) string {
+	if prefix == "" {
+		return "."
+	}
+	return prefix
+}
+
 func modeOrAll(m string) string {
 	if m == "" {
 		return "all"
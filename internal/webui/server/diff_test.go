@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tu "codectl/internal/testutil"
+	"codectl/internal/workspace"
+)
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Ada", "GIT_AUTHOR_EMAIL=ada@example.com",
+		"GIT_COMMITTER_NAME=Ada", "GIT_COMMITTER_EMAIL=ada@example.com",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// diffRepo creates a repository with changes inside and outside its
+// "docs" subdirectory, which is configured as workspace root "docs".
+func diffRepo(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	tmp := t.TempDir()
+	t.Cleanup(tu.WithEnv(t, "HOME", tmp))
+	repo := filepath.Join(tmp, "repo")
+	_ = os.MkdirAll(filepath.Join(repo, "docs"), 0o755)
+	git(t, repo, "init", "-q")
+	_ = os.WriteFile(filepath.Join(repo, "top.txt"), []byte("one\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(repo, "docs", "a.md"), []byte("one\n"), 0o644)
+	git(t, repo, "add", "-A")
+	git(t, repo, "commit", "-q", "-m", "init")
+	_ = os.WriteFile(filepath.Join(repo, "top.txt"), []byte("two\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(repo, "docs", "a.md"), []byte("two\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(repo, "docs", "new.md"), []byte("new\n"), 0o644)
+
+	wd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := workspace.Save(workspace.Config{Roots: []workspace.Root{{ID: "docs", Path: filepath.Join(repo, "docs")}}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiffChanges_WorkspaceRoot(t *testing.T) {
+	diffRepo(t)
+	list := func(q string) []string {
+		rr := httptest.NewRecorder()
+		diffChangesHandler(rr, httptest.NewRequest(http.MethodGet, "/api/diff/changes"+q, nil))
+		var items []changeItem
+		if err := json.Unmarshal(rr.Body.Bytes(), &items); err != nil || rr.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", q, rr.Code, rr.Body.String())
+		}
+		var paths []string
+		for _, it := range items {
+			paths = append(paths, it.Path)
+		}
+		return paths
+	}
+	if got := strings.Join(list("?root=docs"), ","); got != "a.md,new.md" {
+		t.Fatalf("docs root lists %q", got)
+	}
+	if got := strings.Join(list(""), ","); got != "docs/a.md,top.txt,docs/new.md" {
+		t.Fatalf("repo root lists %q", got)
+	}
+}
+
+func TestDiffFile_WorkspaceRoot(t *testing.T) {
+	diffRepo(t)
+	rr := httptest.NewRecorder()
+	diffFileHandler(rr, httptest.NewRequest(http.MethodGet, "/api/diff/file?root=docs&path=a.md", nil))
+	var res struct {
+		Path string `json:"path"`
+		Diff string `json:"diff"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("%d %s", rr.Code, rr.Body.String())
+	}
+	if res.Path != "a.md" || !strings.Contains(res.Diff, "+++ b/a.md") || !strings.Contains(res.Diff, "+two") {
+		t.Fatalf("unexpected diff: %+v", res)
+	}
+	for _, p := range []string{"../top.txt", "/etc/passwd", "."} {
+		rr := httptest.NewRecorder()
+		diffFileHandler(rr, httptest.NewRequest(http.MethodGet, "/api/diff/file?root=docs&path="+p, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("path %q: want 400, got %d %s", p, rr.Code, rr.Body.String())
+		}
+	}
+}
@@ -1,21 +1,31 @@
 package server
 
 import (
-	sys "codectl/internal/system"
+	"bufio"
+	"bytes"
+	"codectl/internal/history"
+	"codectl/internal/safefs"
+	"codectl/internal/workspace"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
-// Allowed base roots to limit FS access surface.
-// "repo": repository root if available, else CWD
-// "vibe-spec": repo/vibe-docs/spec
+// Allowed base keys within a workspace root (see internal/workspace).
+// "repo": the root directory itself
+// "vibe-spec": <root>/vibe-docs/spec
+// The root itself is chosen by a separate root id parameter.
 var allowedBases = []string{"repo", "vibe-spec"}
 
 type fsTreeNode struct {
@@ -25,61 +35,107 @@ type fsTreeNode struct {
 	Children []fsTreeNode `json:"children,omitempty"`
 }
 
-// resolveBase returns the absolute path for a base key.
-func resolveBase(r *http.Request, base string) (string, error) {
-	cwd, _ := os.Getwd()
-	root := cwd
-	if gi, err := sys.GitRoot(r.Context(), cwd); err == nil && strings.TrimSpace(gi) != "" {
-		root = gi
+// resolveBase returns the absolute path for base key base within workspace
+// root id root. The two never overlap: base only takes the keys in
+// allowedBases ("" meaning "repo") and root only takes root ids ("" meaning
+// the repository).
+func resolveBase(r *http.Request, root, base string) (string, error) {
+	if err := checkBase(base); err != nil {
+		return "", err
+	}
+	dir, err := workspace.Resolve(r.Context(), root)
+	if err != nil {
+		return "", err
+	}
+	if base == "vibe-spec" {
+		return filepath.Join(dir, "vibe-docs", "spec"), nil
 	}
+	return dir, nil
+}
+
+// checkBase rejects base values other than "", "repo" and "vibe-spec";
+// workspace roots are selected with root, never with base.
+func checkBase(base string) error {
 	switch base {
-	case "repo", "":
-		return root, nil
-	case "vibe-spec":
-		return filepath.Join(root, "vibe-docs", "spec"), nil
-	default:
-		return "", errors.New("invalid base")
+	case "repo", "", "vibe-spec":
+		return nil
 	}
+	return fmt.Errorf("invalid base %q (use root= to select a workspace root)", base)
 }
 
 // secureJoin joins base and p and ensures the result stays within base.
+// Symlinks are resolved on the longest existing prefix, so a not-yet-existing
+// file below a symlinked directory is checked against the link target.
+// This is an early check only; operations that open, write, rename or
+// delete must go through internal/safefs, which enforces confinement at
+// open time.
 func secureJoin(base, p string) (string, error) {
-	// Clean and prevent absolute paths
-	if filepath.IsAbs(p) {
-		return "", errors.New("absolute path not allowed")
+	clean, err := safefs.Clean(p)
+	if err != nil {
+		return "", err
 	}
-	clean := filepath.Clean(p)
 	full := filepath.Join(base, clean)
-	// Resolve symlinks best-effort
-	baseEval, _ := filepath.EvalSymlinks(base)
-	fullEval, _ := filepath.EvalSymlinks(full)
-	if baseEval == "" {
-		baseEval = base
+	baseEval, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		// base may not exist yet (e.g. vibe-docs/spec in a fresh repo)
+		if baseEval, err = evalExisting(base); err != nil {
+			return "", err
+		}
 	}
-	if fullEval == "" {
-		fullEval = full
+	fullEval, err := evalExisting(full)
+	if err != nil {
+		return "", err
 	}
-	// Ensure prefix
-	rel, err := filepath.Rel(baseEval, fullEval)
-	if err != nil || strings.HasPrefix(rel, "..") || strings.Contains(rel, string(os.PathSeparator)+"..") {
-		return "", errors.New("path escapes base")
+	if !within(baseEval, fullEval) {
+		return "", safefs.ErrEscape
 	}
 	return full, nil
 }
 
+// evalExisting resolves symlinks in the longest existing prefix of p and
+// appends the remaining components. A dangling symlink is an error.
+func evalExisting(p string) (string, error) {
+	cur, rest := filepath.Clean(p), ""
+	for {
+		ev, err := filepath.EvalSymlinks(cur)
+		if err == nil {
+			return filepath.Join(ev, rest), nil
+		}
+		if _, lerr := os.Lstat(cur); lerr == nil || !os.IsNotExist(err) {
+			return "", errors.New("cannot resolve path")
+		}
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return filepath.Clean(p), nil
+		}
+		rest = filepath.Join(filepath.Base(cur), rest)
+		cur = parent
+	}
+}
+
+// within reports whether p equals base or lies below it.
+func within(base, p string) bool {
+	rel, err := filepath.Rel(base, p)
+	if err != nil || filepath.IsAbs(rel) {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
+
 func fsTreeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 	baseKey := r.URL.Query().Get("base")
+	rootKey := r.URL.Query().Get("root")
 	depth := 2
 	if v := strings.TrimSpace(r.URL.Query().Get("depth")); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 8 {
 			depth = n
 		}
 	}
-	base, err := resolveBase(r, baseKey)
+	base, err := resolveBase(r, rootKey, baseKey)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
@@ -140,32 +196,299 @@ func relSafe(root, p string) string {
 	return filepath.ToSlash(p)
 }
 
+// maxReadBytes caps the payload returned by fs/read for a line range.
+const maxReadBytes = 1 << 20
+
+// sniffLen is the number of leading bytes inspected for MIME/binary detection.
+const sniffLen = 512
+
+type fsReadResult struct {
+	Path      string `json:"path"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	ModTime   string `json:"modTime"`
+	Mime      string `json:"mime"`
+	Binary    bool   `json:"binary"`
+	Encoding  string `json:"encoding"` // utf8|base64
+	Content   string `json:"content"`
+	Offset    int64  `json:"offset"`
+	Length    int64  `json:"length"`
+	StartLine int    `json:"startLine,omitempty"`
+	EndLine   int    `json:"endLine,omitempty"`
+	Truncated bool   `json:"truncated"`
+}
+
+// fsReadHandler returns file content plus size/MIME metadata.
+// GET /api/fs/read?base=&path=&offset=&length=&startLine=&endLine=&encoding=utf8|base64
+//
+// Byte ranges (offset/length) and line ranges (startLine/endLine, 1-based, inclusive)
+// are mutually exclusive; without either the whole file is returned, and
+// without a length a byte range runs to the end of the file. A line range
+// stops after maxReadBytes. Truncated reports content beyond the returned
+// range; clients that save content back must only do so for complete reads.
+// Binary content is always base64 encoded.
 func fsReadHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	base, err := resolveBase(r, r.URL.Query().Get("base"))
+	q := r.URL.Query()
+	base, p, err := resolveReadPath(r)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	p := r.URL.Query().Get("path")
-	if strings.TrimSpace(p) == "" {
-		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("missing path")))
+	full := filepath.Join(base, filepath.Clean(p))
+	f, err := safefs.Open(base, p)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, errJSON(err))
+		return
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, errJSON(err))
+		return
+	}
+	if st.IsDir() {
+		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("path is a directory")))
 		return
 	}
-	full, err := secureJoin(base, p)
+	mimeType, binary, err := sniffFile(f, full)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	res := fsReadResult{
+		Path:    filepath.ToSlash(p),
+		Name:    filepath.Base(full),
+		Size:    st.Size(),
+		ModTime: st.ModTime().UTC().Format(time.RFC3339),
+		Mime:    mimeType,
+		Binary:  binary,
+	}
+	enc := strings.ToLower(strings.TrimSpace(q.Get("encoding")))
+	switch enc {
+	case "", "utf8", "utf-8", "base64":
+	default:
+		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("invalid encoding")))
+		return
+	}
+
+	var data []byte
+	if q.Get("startLine") != "" || q.Get("endLine") != "" {
+		if binary {
+			writeJSON(w, http.StatusBadRequest, errJSON(errors.New("line range not supported for binary files")))
+			return
+		}
+		start, end, err := parseLineRange(q.Get("startLine"), q.Get("endLine"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errJSON(err))
+			return
+		}
+		var off int64
+		var last int
+		data, off, last, res.Truncated, err = readLines(f, start, end, maxReadBytes)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errJSON(err))
+			return
+		}
+		res.Offset = off
+		res.StartLine = start
+		res.EndLine = last
+	} else {
+		off, err := parseNonNegative(q.Get("offset"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errJSON(errors.New("invalid offset")))
+			return
+		}
+		length, err := parseNonNegative(q.Get("length"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errJSON(errors.New("invalid length")))
+			return
+		}
+		if off > st.Size() {
+			off = st.Size()
+		}
+		if length == 0 {
+			length = st.Size() - off
+		}
+		if rem := st.Size() - off; length > rem {
+			length = rem
+		}
+		data = make([]byte, length)
+		n, err := f.ReadAt(data, off)
+		if err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, http.StatusInternalServerError, errJSON(err))
+			return
+		}
+		data = data[:n]
+		res.Offset = off
+		res.Truncated = off+int64(n) < st.Size()
+	}
+	res.Length = int64(len(data))
+	if binary || enc == "base64" {
+		res.Encoding = "base64"
+		res.Content = base64.StdEncoding.EncodeToString(data)
+	} else {
+		res.Encoding = "utf8"
+		res.Content = string(data)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// fsRawHandler streams a file with its detected Content-Type.
+// GET /api/fs/raw?base=&path=  (honours HTTP Range requests)
+func fsRawHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	base, p, err := resolveReadPath(r)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	b, err := os.ReadFile(full)
+	f, err := safefs.Open(base, p)
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, errJSON(err))
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"path": filepath.ToSlash(p), "content": string(b)})
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil || st.IsDir() {
+		writeJSON(w, http.StatusNotFound, errJSON(errors.New("file not found")))
+		return
+	}
+	mimeType, _, err := sniffFile(f, p)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// SVG may carry scripts; keep it from running when opened directly.
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	http.ServeContent(w, r, st.Name(), st.ModTime(), f)
+}
+
+// resolveReadPath resolves the root/base/path query parameters to a base
+// directory and a path confined beneath it.
+func resolveReadPath(r *http.Request) (string, string, error) {
+	base, err := resolveBase(r, r.URL.Query().Get("root"), r.URL.Query().Get("base"))
+	if err != nil {
+		return "", "", err
+	}
+	p := r.URL.Query().Get("path")
+	if strings.TrimSpace(p) == "" {
+		return "", "", errors.New("missing path")
+	}
+	if _, err := secureJoin(base, p); err != nil {
+		return "", "", err
+	}
+	return base, p, nil
+}
+
+// sniffFile detects the MIME type of f and whether it holds binary data.
+// The extension wins when it is known; otherwise the leading bytes are sniffed.
+// It reads with ReadAt, so the read offset of f is not touched.
+func sniffFile(f *os.File, name string) (string, bool, error) {
+	buf := make([]byte, sniffLen)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", false, err
+	}
+	buf = buf[:n]
+	binary := isBinarySample(buf)
+	ct := mime.TypeByExtension(strings.ToLower(filepath.Ext(name)))
+	if ct == "" {
+		ct = http.DetectContentType(buf)
+		if !binary && strings.HasPrefix(ct, "application/octet-stream") {
+			ct = "text/plain; charset=utf-8"
+		}
+	}
+	return ct, binary, nil
+}
+
+// isBinarySample reports whether b looks like non-text content:
+// it contains NUL bytes or is not valid UTF-8 (ignoring a rune cut at the end).
+func isBinarySample(b []byte) bool {
+	if bytes.IndexByte(b, 0) >= 0 {
+		return true
+	}
+	if len(b) == sniffLen {
+		// the sample may end in the middle of a multi-byte rune
+		for i := 0; i < utf8.UTFMax-1 && !utf8.Valid(b); i++ {
+			b = b[:len(b)-1]
+		}
+	}
+	return !utf8.Valid(b)
+}
+
+func parseNonNegative(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid number")
+	}
+	return n, nil
+}
+
+// parseLineRange parses 1-based inclusive line bounds; end 0 means "to EOF".
+func parseLineRange(startS, endS string) (int, int, error) {
+	start, end := 1, 0
+	if v := strings.TrimSpace(startS); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, errors.New("invalid startLine")
+		}
+		start = n
+	}
+	if v := strings.TrimSpace(endS); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < start {
+			return 0, 0, errors.New("invalid endLine")
+		}
+		end = n
+	}
+	return start, end, nil
+}
+
+// readLines reads lines [start, end] (end 0 = EOF) from rd, stopping at limit bytes.
+// It returns the data, the byte offset of the first returned line, the last line
+// number returned and whether more content follows.
+func readLines(rd io.Reader, start, end int, limit int64) ([]byte, int64, int, bool, error) {
+	br := bufio.NewReader(rd)
+	var out bytes.Buffer
+	var off int64
+	line := 0
+	last := 0
+	for {
+		s, err := br.ReadString('\n')
+		if len(s) > 0 {
+			line++
+			switch {
+			case line < start:
+				off += int64(len(s))
+			case end > 0 && line > end:
+				return out.Bytes(), off, last, true, nil
+			case int64(out.Len()+len(s)) > limit:
+				return out.Bytes(), off, last, true, nil
+			default:
+				out.WriteString(s)
+				last = line
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return out.Bytes(), off, last, false, nil
+			}
+			return nil, 0, 0, false, err
+		}
+	}
 }
 
 func fsWriteHandler(w http.ResponseWriter, r *http.Request) {
@@ -174,6 +497,7 @@ func fsWriteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var in struct {
+		Root    string `json:"root"`
 		Base    string `json:"base"`
 		Path    string `json:"path"`
 		Content string `json:"content"`
@@ -183,27 +507,26 @@ func fsWriteHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	base, err := resolveBase(r, in.Base)
+	base, err := resolveBase(r, in.Root, in.Base)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	full, err := secureJoin(base, in.Path)
-	if err != nil {
+	if _, err := secureJoin(base, in.Path); err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
-		writeJSON(w, http.StatusInternalServerError, errJSON(err))
-		return
-	}
 	if !in.Create {
-		if st, err := os.Stat(full); err != nil || st.IsDir() {
+		if st, err := safefs.Lstat(base, in.Path); err != nil || st.IsDir() {
 			writeJSON(w, http.StatusNotFound, errJSON(errors.New("file not found")))
 			return
 		}
 	}
-	if err := os.WriteFile(full, []byte(in.Content), 0o644); err != nil {
+	if _, err := history.Snapshot(base, in.Path, history.OpWrite); err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	if err := safefs.WriteFile(base, in.Path, []byte(in.Content), 0o644); err != nil {
 		writeJSON(w, http.StatusInternalServerError, errJSON(err))
 		return
 	}
@@ -216,6 +539,7 @@ func fsRenameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var in struct {
+		Root    string `json:"root"`
 		Base    string `json:"base"`
 		Path    string `json:"path"`
 		NewPath string `json:"newPath"`
@@ -224,26 +548,29 @@ func fsRenameHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	base, err := resolveBase(r, in.Base)
+	base, err := resolveBase(r, in.Root, in.Base)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	src, err := secureJoin(base, in.Path)
-	if err != nil {
+	if _, err := secureJoin(base, in.Path); err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	dst, err := secureJoin(base, in.NewPath)
-	if err != nil {
+	if _, err := secureJoin(base, in.NewPath); err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+	// keep the source content under its old path, and the target if it gets replaced
+	if _, err := history.Snapshot(base, in.Path, history.OpRename); err != nil {
 		writeJSON(w, http.StatusInternalServerError, errJSON(err))
 		return
 	}
-	if err := os.Rename(src, dst); err != nil {
+	if _, err := history.Snapshot(base, in.NewPath, history.OpWrite); err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	if err := safefs.Rename(base, in.Path, in.NewPath); err != nil {
 		writeJSON(w, http.StatusInternalServerError, errJSON(err))
 		return
 	}
@@ -256,6 +583,7 @@ func fsDeleteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var in struct {
+		Root string `json:"root"`
 		Base string `json:"base"`
 		Path string `json:"path"`
 	}
@@ -263,17 +591,20 @@ func fsDeleteHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	base, err := resolveBase(r, in.Base)
+	base, err := resolveBase(r, in.Root, in.Base)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	full, err := secureJoin(base, in.Path)
-	if err != nil {
+	if _, err := secureJoin(base, in.Path); err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	if err := os.Remove(full); err != nil {
+	if _, err := history.Snapshot(base, in.Path, history.OpDelete); err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	if err := safefs.Remove(base, in.Path); err != nil {
 		writeJSON(w, http.StatusInternalServerError, errJSON(err))
 		return
 	}
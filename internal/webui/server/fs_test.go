@@ -0,0 +1,207 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	tu "codectl/internal/testutil"
+)
+
+func TestSecureJoin_Symlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+	tmp := t.TempDir()
+	repo := filepath.Join(tmp, "repo")
+	outside := filepath.Join(tmp, "outside")
+	_ = os.MkdirAll(filepath.Join(repo, "docs"), 0o755)
+	_ = os.MkdirAll(outside, 0o755)
+	_ = os.Symlink(outside, filepath.Join(repo, "outdir"))
+	_ = os.Symlink(filepath.Join(outside, "missing"), filepath.Join(repo, "dangling"))
+
+	ok := []string{"docs", "docs/new/file.md", "a.md", "."}
+	for _, p := range ok {
+		if _, err := secureJoin(repo, p); err != nil {
+			t.Fatalf("secureJoin(%q) unexpected error: %v", p, err)
+		}
+	}
+	bad := []string{"../x", "/etc/passwd", "outdir", "outdir/new.txt", "outdir/a/b/c.md", "dangling"}
+	for _, p := range bad {
+		if _, err := secureJoin(repo, p); err == nil {
+			t.Fatalf("secureJoin(%q) expected escape error", p)
+		}
+	}
+}
+
+func TestFSWrite_SymlinkEscapeRejected(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on windows")
+	}
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+	repo := filepath.Join(tmp, "repo")
+	outside := filepath.Join(tmp, "outside")
+	_ = os.MkdirAll(repo, 0o755)
+	_ = os.MkdirAll(outside, 0o755)
+	_ = os.Symlink(outside, filepath.Join(repo, "outdir"))
+	wd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"path":"outdir/pwned.txt","content":"x","create":true}`
+	w := httptest.NewRecorder()
+	fsWriteHandler(w, httptest.NewRequest(http.MethodPut, "/api/fs/write", strings.NewReader(body)))
+	if w.Code == http.StatusOK {
+		t.Fatalf("expected write through symlink to fail, got %d", w.Code)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "pwned.txt")); !os.IsNotExist(err) {
+		t.Fatalf("file created outside repo")
+	}
+
+	body = `{"path":"docs/ok.md","content":"ok","create":true}`
+	w = httptest.NewRecorder()
+	fsWriteHandler(w, httptest.NewRequest(http.MethodPut, "/api/fs/write", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected write within repo to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// readFS calls fsReadHandler with query and decodes the result.
+func readFS(t *testing.T, query string) (int, fsReadResult) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	fsReadHandler(w, httptest.NewRequest(http.MethodGet, "/api/fs/read?"+query, nil))
+	var res fsReadResult
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, res
+}
+
+func TestFSRead(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+	repo := filepath.Join(tmp, "repo")
+	_ = os.MkdirAll(repo, 0o755)
+	big := strings.Repeat("0123456789abcdef\n", (maxReadBytes/17)+100)
+	_ = os.WriteFile(filepath.Join(repo, "big.txt"), []byte(big), 0o644)
+	_ = os.WriteFile(filepath.Join(repo, "lines.md"), []byte("one\ntwo\nthree\nfour\n"), 0o644)
+	bin := []byte{0x89, 'P', 'N', 'G', 0, 1, 2, 0xff}
+	_ = os.WriteFile(filepath.Join(repo, "blob.bin"), bin, 0o644)
+	wd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a range the whole file is returned, even beyond maxReadBytes.
+	code, res := readFS(t, "path=big.txt")
+	if code != http.StatusOK || res.Content != big || res.Truncated || res.Size != int64(len(big)) {
+		t.Fatalf("whole file: %d len=%d truncated=%v size=%d", code, len(res.Content), res.Truncated, res.Size)
+	}
+
+	code, res = readFS(t, "path=lines.md&offset=4&length=3")
+	if code != http.StatusOK || res.Content != "two" || res.Offset != 4 || res.Length != 3 || !res.Truncated {
+		t.Fatalf("byte range: %d %+v", code, res)
+	}
+	code, res = readFS(t, "path=lines.md&offset=8")
+	if code != http.StatusOK || res.Content != "three\nfour\n" || res.Truncated {
+		t.Fatalf("open byte range: %d %+v", code, res)
+	}
+	code, res = readFS(t, "path=lines.md&offset=-1")
+	if code != http.StatusBadRequest {
+		t.Fatalf("negative offset: %d", code)
+	}
+
+	code, res = readFS(t, "path=lines.md&startLine=2&endLine=3")
+	if code != http.StatusOK || res.Content != "two\nthree\n" || res.Offset != 4 || res.StartLine != 2 || res.EndLine != 3 || !res.Truncated {
+		t.Fatalf("line range: %d %+v", code, res)
+	}
+	code, res = readFS(t, "path=lines.md&startLine=3")
+	if code != http.StatusOK || res.Content != "three\nfour\n" || res.EndLine != 4 || res.Truncated {
+		t.Fatalf("line range to EOF: %d %+v", code, res)
+	}
+	if code, _ := readFS(t, "path=lines.md&startLine=3&endLine=2"); code != http.StatusBadRequest {
+		t.Fatalf("inverted line range: %d", code)
+	}
+	code, res = readFS(t, "path=big.txt&startLine=1")
+	if code != http.StatusOK || !res.Truncated || res.Length > maxReadBytes {
+		t.Fatalf("line range cap: %d len=%d truncated=%v", code, res.Length, res.Truncated)
+	}
+
+	code, res = readFS(t, "path=blob.bin")
+	if code != http.StatusOK || !res.Binary || res.Encoding != "base64" || res.Content != base64.StdEncoding.EncodeToString(bin) {
+		t.Fatalf("binary: %d %+v", code, res)
+	}
+	if code, _ := readFS(t, "path=blob.bin&startLine=1"); code != http.StatusBadRequest {
+		t.Fatalf("binary line range: %d", code)
+	}
+	code, res = readFS(t, "path=lines.md&encoding=base64")
+	if code != http.StatusOK || res.Binary || res.Encoding != "base64" || res.Content != base64.StdEncoding.EncodeToString([]byte("one\ntwo\nthree\nfour\n")) {
+		t.Fatalf("base64 text: %d %+v", code, res)
+	}
+}
+
+func TestFSRaw(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+	repo := filepath.Join(tmp, "repo")
+	_ = os.MkdirAll(repo, 0o755)
+	_ = os.WriteFile(filepath.Join(repo, "page.html"), []byte("<p>hello world</p>"), 0o644)
+	_ = os.WriteFile(filepath.Join(repo, "noext"), []byte("plain text"), 0o644)
+	wd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	fsRawHandler(w, httptest.NewRequest(http.MethodGet, "/api/fs/raw?path=page.html", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("raw: %d %v", w.Code, w.Header())
+	}
+	w = httptest.NewRecorder()
+	fsRawHandler(w, httptest.NewRequest(http.MethodGet, "/api/fs/raw?path=noext", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Fatalf("sniffed type: %q", ct)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/fs/raw?path=page.html", nil)
+	req.Header.Set("Range", "bytes=3-7")
+	w = httptest.NewRecorder()
+	fsRawHandler(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "hello" || w.Header().Get("Content-Range") != "bytes 3-7/18" {
+		t.Fatalf("range: %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	fsRawHandler(w, httptest.NewRequest(http.MethodGet, "/api/fs/raw?path=../etc/passwd", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("escape: %d", w.Code)
+	}
+}
+
+func TestIsBinarySample(t *testing.T) {
+	if isBinarySample([]byte("plain ascii\n")) || isBinarySample([]byte("中文")) {
+		t.Fatal("text reported as binary")
+	}
+	if !isBinarySample([]byte("a\x00b")) || !isBinarySample([]byte{0xff, 0xfe, 'a'}) {
+		t.Fatal("binary reported as text")
+	}
+	// A multi-byte rune cut at the end of a full sample is still text.
+	cut := append([]byte(strings.Repeat("a", sniffLen-1)), "中"[0])
+	if isBinarySample(cut) {
+		t.Fatal("cut rune reported as binary")
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"codectl/internal/history"
+)
+
+type historyVersion struct {
+	ID   string `json:"id"`
+	Op   string `json:"op"`
+	Size int64  `json:"size"`
+	Time string `json:"time"`
+}
+
+// fsHistoryHandler lists local history versions of a path, or returns one version's content.
+// GET /api/fs/history?root=&base=&path=[&id=]
+func fsHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	base, p, err := resolveReadPath(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	full := filepath.Join(base, filepath.Clean(p))
+	if id := strings.TrimSpace(r.URL.Query().Get("id")); id != "" {
+		b, v, err := history.Read(full, id)
+		if err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, history.ErrNotFound) {
+				code = http.StatusNotFound
+			}
+			writeJSON(w, code, errJSON(err))
+			return
+		}
+		res := map[string]any{"path": filepath.ToSlash(p), "version": toHistoryVersion(v)}
+		if !isBinarySample(b) {
+			res["encoding"] = "utf8"
+			res["content"] = string(b)
+		} else {
+			res["encoding"] = "base64"
+			res["content"] = base64.StdEncoding.EncodeToString(b)
+		}
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+	vs, err := history.List(full)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	out := make([]historyVersion, 0, len(vs))
+	for _, v := range vs {
+		out = append(out, toHistoryVersion(v))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"path": filepath.ToSlash(p), "versions": out})
+}
+
+// fsRestoreHandler writes a stored version back to its path.
+// POST /api/fs/history/restore { root, base, path, id }
+func fsRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var in struct {
+		Root string `json:"root"`
+		Base string `json:"base"`
+		Path string `json:"path"`
+		ID   string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	base, err := resolveBase(r, in.Root, in.Base)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	if _, err := secureJoin(base, in.Path); err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	v, err := history.Restore(base, in.Path, strings.TrimSpace(in.ID))
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, history.ErrNotFound) {
+			code = http.StatusNotFound
+		}
+		writeJSON(w, code, errJSON(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "version": toHistoryVersion(v)})
+}
+
+func toHistoryVersion(v history.Version) historyVersion {
+	return historyVersion{ID: v.ID, Op: v.Op, Size: v.Size, Time: v.Time.UTC().Format(time.RFC3339)}
+}
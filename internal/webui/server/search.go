@@ -0,0 +1,67 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"codectl/internal/docsearch"
+	"codectl/internal/workspace"
+)
+
+// searchIndexes caches one index per workspace directory; each search
+// refreshes the files that changed since the last one.
+var (
+	searchMu      sync.Mutex
+	searchIndexes = map[string]*docsearch.Index{}
+)
+
+func searchIndex(dir string) *docsearch.Index {
+	searchMu.Lock()
+	defer searchMu.Unlock()
+	ix := searchIndexes[dir]
+	if ix == nil {
+		ix = docsearch.New(dir)
+		searchIndexes[dir] = ix
+	}
+	return ix
+}
+
+// docsSearchHandler ranks spec and task documents for a query.
+// GET /api/docs/search?root=&q=&kind=spec|task&limit=
+func docsSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	query := strings.TrimSpace(q.Get("q"))
+	if query == "" {
+		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("missing q")))
+		return
+	}
+	kind := q.Get("kind")
+	switch kind {
+	case "", "spec", "task":
+	default:
+		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("kind must be spec or task")))
+		return
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	dir, err := workspace.Resolve(r.Context(), q.Get("root"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	ix := searchIndex(dir)
+	if err := ix.Refresh(); err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"query":   query,
+		"results": ix.Search(query, docsearch.Options{Kind: kind, Limit: limit}),
+	})
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"codectl/internal/history"
 	"codectl/internal/system"
 	appver "codectl/internal/version"
 	webembed "codectl/internal/webui/embed"
@@ -36,10 +37,29 @@ func (s *Server) Start(ctx context.Context) error {
 		<-ctx.Done()
 		_ = srv.Shutdown(context.Background())
 	}()
+	go sweepHistory(ctx)
 	system.Logger.Info("webui server listening", "addr", s.Addr)
 	return srv.ListenAndServe()
 }
 
+// sweepHistory expires old local history versions now and once a day while
+// the server runs; Snapshot only prunes the file it is saving.
+func sweepHistory(ctx context.Context) {
+	t := time.NewTicker(24 * time.Hour)
+	defer t.Stop()
+	for {
+		c, _ := history.LoadConfig()
+		if err := history.Sweep(c); err != nil {
+			system.Logger.Warn("history sweep failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
 // OpenBrowser tries to open a URL in the system browser.
 func OpenBrowser(url string) error {
 	var cmd string
@@ -74,18 +94,31 @@ func mountAPIGin(r *gin.Engine) {
 	api.POST("/chat", gin.WrapF(chatHandler))
 	api.GET("/chat/:id/stream", gin.WrapF(chatReconnectHandler))
 
+	// Workspace roots
+	api.Any("/workspace", gin.WrapF(workspaceHandler))
+
 	// FS
 	api.GET("/fs/tree", gin.WrapF(fsTreeHandler))
 	api.GET("/fs/read", gin.WrapF(fsReadHandler))
+	api.GET("/fs/raw", gin.WrapF(fsRawHandler))
+	api.HEAD("/fs/raw", gin.WrapF(fsRawHandler))
 	api.PUT("/fs/write", gin.WrapF(fsWriteHandler))
 	api.POST("/fs/rename", gin.WrapF(fsRenameHandler))
 	api.POST("/fs/delete", gin.WrapF(fsDeleteHandler))
 	api.POST("/fs/patch", gin.WrapF(fsPatchHandler))
+	api.GET("/fs/history", gin.WrapF(fsHistoryHandler))
+	api.POST("/fs/history/restore", gin.WrapF(fsRestoreHandler))
 
 	// Spec
 	api.GET("/spec/docs", gin.WrapF(specListHandler))
 	api.Any("/spec/doc", gin.WrapF(specDocHandler))
 	api.POST("/spec/validate", gin.WrapF(specValidateHandler))
+	api.GET("/spec/graph", gin.WrapF(specGraphHandler))
+	api.GET("/spec/status", gin.WrapF(specStatusHandler))
+	api.POST("/spec/transition", gin.WrapF(specTransitionHandler))
+	api.GET("/trace", gin.WrapF(traceHandler))
+	api.GET("/spec/history", gin.WrapF(specHistoryHandler))
+	api.GET("/docs/search", gin.WrapF(docsSearchHandler))
 
 	// Diff
 	api.GET("/diff/changes", gin.WrapF(diffChangesHandler))
@@ -94,6 +127,17 @@ func mountAPIGin(r *gin.Engine) {
 	// Tasks
 	api.GET("/tasks/list", gin.WrapF(tasksListHandler))
 	api.PUT("/tasks/update", gin.WrapF(tasksUpdateHandler))
+	api.POST("/tasks/generate", gin.WrapF(tasksGenerateHandler))
+	api.GET("/tasks/enums", gin.WrapF(tasksEnumsHandler))
+	api.POST("/tasks/create", gin.WrapF(tasksCreateHandler))
+	api.POST("/tasks/set", gin.WrapF(tasksSetHandler))
+	api.PATCH("/tasks/set", gin.WrapF(tasksSetHandler))
+	api.POST("/tasks/delete", gin.WrapF(tasksDeleteHandler))
+	api.POST("/tasks/archive", gin.WrapF(tasksArchiveHandler))
+	api.POST("/tasks/bulk", gin.WrapF(tasksBulkHandler))
+	api.GET("/tasks/board", gin.WrapF(tasksBoardHandler))
+	api.POST("/tasks/move", gin.WrapF(tasksMoveHandler))
+	api.GET("/tasks/graph", gin.WrapF(tasksGraphHandler))
 
 	// Sessions
 	api.Any("/sessions", gin.WrapF(sessionsRootHandler))
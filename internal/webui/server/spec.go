@@ -1,24 +1,35 @@
 package server
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strings"
+	"time"
+
+	"codectl/internal/history"
+	"codectl/internal/safefs"
+	"codectl/internal/speccheck"
+	"codectl/internal/specgraph"
+	"codectl/internal/spechistory"
+	"codectl/internal/specstatus"
+	"codectl/internal/workspace"
 )
 
 type specDocMeta struct {
-	Path     string            `json:"path"`
-	Title    string            `json:"title,omitempty"`
-	Status   string            `json:"status,omitempty"`
-	Fields   map[string]string `json:"fields,omitempty"`
-	Errors   []string          `json:"errors,omitempty"`
-	Warnings []string          `json:"warnings,omitempty"`
+	Path     string              `json:"path"`
+	Title    string              `json:"title,omitempty"`
+	Status   string              `json:"status,omitempty"`
+	Fields   map[string]string   `json:"fields,omitempty"`
+	Errors   []string            `json:"errors,omitempty"`
+	Warnings []string            `json:"warnings,omitempty"`
+	Findings []speccheck.Finding `json:"findings,omitempty"`
+	// Updated is the last commit touching the document.
+	Updated *spechistory.Commit `json:"updated,omitempty"`
 }
 
 func specListHandler(w http.ResponseWriter, r *http.Request) {
@@ -27,11 +38,19 @@ func specListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// base fixed to vibe-spec for listing
-	base, err := resolveBase(r, "vibe-spec")
+	base, err := resolveBase(r, r.URL.Query().Get("root"), "vibe-spec")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	rules, err := loadSpecRules(r, r.URL.Query().Get("root"), "vibe-spec")
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	last := specLastModified(ctx, base)
 	entries := make([]specDocMeta, 0, 16)
 	_ = filepath.WalkDir(base, func(p string, d os.DirEntry, err error) error {
 		if err != nil {
@@ -44,8 +63,10 @@ func specListHandler(w http.ResponseWriter, r *http.Request) {
 		if !strings.HasSuffix(name, ".spec.mdx") {
 			return nil
 		}
-		it := checkMDXFile(p)
-		it.Path = filepath.ToSlash(relSafe(base, p))
+		rel := relSafe(base, p)
+		it := rules.checkFile(base, rel)
+		it.Path = rel
+		applyHistory(&it, last[p])
 		if it.Fields != nil {
 			it.Title = it.Fields["title"]
 			it.Status = it.Fields["status"]
@@ -60,7 +81,7 @@ func specListHandler(w http.ResponseWriter, r *http.Request) {
 func specDocHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		base, err := resolveBase(r, r.URL.Query().Get("base"))
+		base, err := resolveBase(r, r.URL.Query().Get("root"), r.URL.Query().Get("base"))
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, errJSON(err))
 			return
@@ -70,27 +91,38 @@ func specDocHandler(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusBadRequest, errJSON(errors.New("missing path")))
 			return
 		}
-		full, err := secureJoin(base, p)
-		if err != nil {
+		if _, err := secureJoin(base, p); err != nil {
 			writeJSON(w, http.StatusBadRequest, errJSON(err))
 			return
 		}
-		b, err := os.ReadFile(full)
+		b, err := safefs.ReadFile(base, p)
 		if err != nil {
 			writeJSON(w, http.StatusNotFound, errJSON(err))
 			return
 		}
-		it := checkMDXBytes(b)
+		q := r.URL.Query()
+		rules, err := loadSpecRules(r, q.Get("root"), q.Get("base"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errJSON(err))
+			return
+		}
+		it := rules.check(filepath.Join(base, p), b)
 		it.Path = filepath.ToSlash(p)
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		applyHistory(&it, specLastCommit(ctx, filepath.Join(base, p)))
+		cancel()
 		writeJSON(w, http.StatusOK, map[string]any{
 			"path":     it.Path,
 			"fields":   it.Fields,
 			"errors":   it.Errors,
 			"warnings": it.Warnings,
+			"findings": it.Findings,
+			"updated":  it.Updated,
 			"content":  string(b),
 		})
 	case http.MethodPut:
 		var in struct {
+			Root    string `json:"root"`
 			Base    string `json:"base"`
 			Path    string `json:"path"`
 			Content string `json:"content"`
@@ -101,25 +133,35 @@ func specDocHandler(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, http.StatusBadRequest, errJSON(err))
 			return
 		}
-		base, err := resolveBase(r, in.Base)
+		base, err := resolveBase(r, in.Root, in.Base)
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, errJSON(err))
 			return
 		}
-		full, err := secureJoin(base, in.Path)
+		if _, err := secureJoin(base, in.Path); err != nil {
+			writeJSON(w, http.StatusBadRequest, errJSON(err))
+			return
+		}
+		rules, err := loadSpecRules(r, in.Root, in.Base)
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, errJSON(err))
 			return
 		}
-		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		if old, err := safefs.ReadFile(base, in.Path); err == nil {
+			if err := specstatus.CheckEdit(rules.workflow(filepath.Join(base, in.Path)), old, []byte(in.Content)); err != nil {
+				writeJSON(w, http.StatusConflict, errJSON(err))
+				return
+			}
+		}
+		if _, err := history.Snapshot(base, in.Path, history.OpWrite); err != nil {
 			writeJSON(w, http.StatusInternalServerError, errJSON(err))
 			return
 		}
-		if err := os.WriteFile(full, []byte(in.Content), 0o644); err != nil {
+		if err := safefs.WriteFile(base, in.Path, []byte(in.Content), 0o644); err != nil {
 			writeJSON(w, http.StatusInternalServerError, errJSON(err))
 			return
 		}
-		it := checkMDXFile(full)
+		it := rules.check(filepath.Join(base, in.Path), []byte(in.Content))
 		writeJSON(w, http.StatusOK, it)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -132,9 +174,13 @@ func specValidateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var in struct {
+		Root    string `json:"root"`
 		Base    string `json:"base"`
 		Path    string `json:"path"`
 		Content string `json:"content"`
+		// Fix applies mechanical fixes: content is returned fixed, a file
+		// given by path is rewritten in place.
+		Fix bool `json:"fix"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
@@ -144,83 +190,166 @@ func specValidateHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("must provide content or path")))
 		return
 	}
-	if strings.TrimSpace(in.Content) != "" {
-		it := checkMDXBytes([]byte(in.Content))
-		writeJSON(w, http.StatusOK, it)
+	base, err := resolveBase(r, in.Root, in.Base)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	base, err := resolveBase(r, in.Base)
+	rules, err := loadSpecRules(r, in.Root, in.Base)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	full, err := secureJoin(base, in.Path)
-	if err != nil {
+	if strings.TrimSpace(in.Content) != "" {
+		// unsaved content: path (when given) only locates the document for path rules
+		full := ""
+		if strings.TrimSpace(in.Path) != "" {
+			if _, err := secureJoin(base, in.Path); err != nil {
+				writeJSON(w, http.StatusBadRequest, errJSON(err))
+				return
+			}
+			full = filepath.Join(base, in.Path)
+		}
+		if in.Fix {
+			out, fixed := speccheck.Fix(rules.rules, rules.rel(full), []byte(in.Content))
+			if fixed == nil {
+				fixed = []speccheck.Finding{}
+			}
+			writeJSON(w, http.StatusOK, specFixResult{specDocMeta: rules.check(full, out), Fixed: fixed, Content: string(out)})
+			return
+		}
+		writeJSON(w, http.StatusOK, rules.check(full, []byte(in.Content)))
+		return
+	}
+	if _, err := secureJoin(base, in.Path); err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	it := checkMDXFile(full)
+	if in.Fix {
+		res, err := rules.fixFile(base, in.Path)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errJSON(err))
+			return
+		}
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+	it := rules.checkFile(base, in.Path)
 	writeJSON(w, http.StatusOK, it)
 }
 
-// checkMDXFile parses an MDX file's frontmatter with minimal checks.
-func checkMDXFile(p string) specDocMeta {
-	b, err := os.ReadFile(p)
+// specFixResult is the validate response when fixes were requested.
+type specFixResult struct {
+	specDocMeta
+	Fixed   []speccheck.Finding `json:"fixed"`
+	Content string              `json:"content,omitempty"`
+	// Written reports whether the file was rewritten.
+	Written bool `json:"written"`
+}
+
+// specGraphHandler returns the cross-reference graph of spec and task docs.
+func specGraphHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	dir, err := workspace.Resolve(r.Context(), r.URL.Query().Get("root"))
 	if err != nil {
-		return specDocMeta{Path: p, Errors: []string{err.Error()}}
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
 	}
-	return checkMDXBytes(b)
+	g, err := specgraph.Build(dir)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, g)
 }
 
-func checkMDXBytes(b []byte) specDocMeta {
-	it := specDocMeta{}
-	s := string(b)
-	rd := bufio.NewReader(strings.NewReader(s))
-	first, _ := rd.ReadString('\n')
-	first = strings.TrimRight(first, "\r\n")
-	if first != "---" {
-		it.Errors = append(it.Errors, "missing frontmatter start '---' on first line")
-		return it
-	}
-	lines := strings.Split(s, "\n")
-	endIdx := -1
-	for i := 1; i < len(lines); i++ {
-		if strings.TrimRight(lines[i], "\r") == "---" {
-			endIdx = i
-			break
-		}
-	}
-	if endIdx < 0 {
-		it.Errors = append(it.Errors, "missing frontmatter end '---'")
-		return it
-	}
-	it.Fields = map[string]string{}
-	fm := lines[1:endIdx]
-	keyRe := regexp.MustCompile(`^([A-Za-z0-9_-]+)\s*:\s*(.*)$`)
-	for _, ln := range fm {
-		l := strings.TrimSpace(ln)
-		if l == "" || strings.HasPrefix(l, "#") {
-			continue
-		}
-		if m := keyRe.FindStringSubmatch(l); len(m) == 3 {
-			k := strings.ToLower(strings.TrimSpace(m[1]))
-			v := strings.TrimSpace(m[2])
-			if len(v) >= 2 {
-				if (v[0] == '\'' && v[len(v)-1] == '\'') || (v[0] == '"' && v[len(v)-1] == '"') {
-					v = v[1 : len(v)-1]
-				}
-			}
-			it.Fields[k] = v
+// specRules holds the validation rules of one workspace.
+type specRules struct {
+	rules *speccheck.Rules
+	dir   string // workspace directory paths are made relative to
+	err   error  // rules file error, reported with every document
+}
+
+// loadSpecRules loads the rules file of the workspace root refers to; base
+// is validated like in resolveBase so rules and documents come from the same
+// root.
+func loadSpecRules(r *http.Request, root, base string) (specRules, error) {
+	if err := checkBase(base); err != nil {
+		return specRules{}, err
+	}
+	dir, err := workspace.Resolve(r.Context(), root)
+	if err != nil {
+		return specRules{}, err
+	}
+	rules, err := speccheck.Load(dir)
+	return specRules{rules: rules, dir: dir, err: err}, nil
+}
+
+// checkFile validates rel beneath base.
+func (s specRules) checkFile(base, rel string) specDocMeta {
+	b, err := safefs.ReadFile(base, rel)
+	if err != nil {
+		return s.meta(speccheck.ReadError(err))
+	}
+	return s.check(filepath.Join(base, rel), b)
+}
+
+// fixFile applies the fixes for rel beneath base and rewrites the file,
+// snapshotting the previous content into local history.
+func (s specRules) fixFile(base, rel string) (specFixResult, error) {
+	b, err := safefs.ReadFile(base, rel)
+	if err != nil {
+		return specFixResult{specDocMeta: s.meta(speccheck.ReadError(err))}, nil
+	}
+	full := filepath.Join(base, rel)
+	out, fixed := speccheck.Fix(s.rules, s.rel(full), b)
+	res := specFixResult{Fixed: fixed}
+	if fixed == nil {
+		res.Fixed = []speccheck.Finding{}
+	} else {
+		if _, err := history.Snapshot(base, rel, history.OpWrite); err != nil {
+			return res, err
 		}
+		if err := safefs.WriteFile(base, rel, out, 0o644); err != nil {
+			return res, err
+		}
+		res.Written = true
 	}
-	if strings.TrimSpace(it.Fields["title"]) == "" {
-		it.Errors = append(it.Errors, "missing required field 'title'")
+	res.specDocMeta = s.check(full, out)
+	res.Path = filepath.ToSlash(rel)
+	return res, nil
+}
+
+// rel returns full relative to the workspace, or "" when unknown.
+func (s specRules) rel(full string) string {
+	if full == "" || s.dir == "" {
+		return ""
+	}
+	return relSafe(s.dir, full)
+}
+
+// workflow returns the review workflow of the document at full.
+func (s specRules) workflow(full string) speccheck.WorkflowRule {
+	return s.rules.For(s.rel(full)).Workflow
+}
+
+// check validates content b of the document at full; full may be empty when
+// the content is not tied to a file.
+func (s specRules) check(full string, b []byte) specDocMeta {
+	return s.meta(speccheck.Check(s.rules, s.rel(full), b))
+}
+
+func (s specRules) meta(res speccheck.Result) specDocMeta {
+	if s.err != nil {
+		res.Findings = append([]speccheck.Finding{{Rule: speccheck.RuleRulesFile, Severity: speccheck.SeverityError, Message: s.err.Error()}}, res.Findings...)
 	}
-	// Heuristic: recommend specVersion for .spec.mdx
-	it.Warnings = []string{}
-	// (The caller knows path; here we can't reliably infer suffix)
-	if _, ok := it.Fields["specversion"]; !ok {
-		it.Warnings = append(it.Warnings, "recommended field 'specVersion' is missing")
+	return specDocMeta{
+		Fields:   res.Fields,
+		Errors:   res.Errors(),
+		Warnings: res.Warnings(),
+		Findings: res.Findings,
 	}
-	return it
 }
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"codectl/internal/spechistory"
+	sys "codectl/internal/system"
+)
+
+// specHistoryHandler lists the commits that touched a spec document.
+// GET /api/spec/history?root=&base=&path=&limit= (default 50, at most 500)
+func specHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	base, err := resolveBase(r, q.Get("root"), q.Get("base"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	p := q.Get("path")
+	if strings.TrimSpace(p) == "" {
+		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("missing path")))
+		return
+	}
+	full, err := secureJoin(base, p)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	// spechistory.Log clamps the limit; 0 (absent) means its default.
+	limit, _ := strconv.Atoi(strings.TrimSpace(q.Get("limit")))
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	root, err := sys.GitRoot(ctx, base)
+	if err != nil || strings.TrimSpace(root) == "" {
+		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("not in a git repository")))
+		return
+	}
+	commits, err := spechistory.Log(ctx, root, relSafe(root, full), limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	if commits == nil {
+		commits = []spechistory.Commit{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"path":    filepath.ToSlash(p),
+		"commits": commits,
+	})
+}
+
+// specLastModified returns the latest commit per document below dir, keyed
+// by absolute path. It is empty outside a git repository.
+func specLastModified(ctx context.Context, dir string) map[string]spechistory.Commit {
+	out := map[string]spechistory.Commit{}
+	root, err := sys.GitRoot(ctx, dir)
+	if err != nil || strings.TrimSpace(root) == "" {
+		return out
+	}
+	last, err := spechistory.LastModified(ctx, root, relSafe(root, dir))
+	if err != nil {
+		return out
+	}
+	for rel, c := range last {
+		out[filepath.Join(root, filepath.FromSlash(rel))] = c
+	}
+	return out
+}
+
+// applyHistory fills it.Updated from c and resolves `lastUpdated: {auto}`.
+func applyHistory(it *specDocMeta, c spechistory.Commit) {
+	if c.SHA == "" {
+		return
+	}
+	c.Changes = nil
+	it.Updated = &c
+	spechistory.ResolveAuto(it.Fields, c)
+}
+
+// specLastCommit returns the latest commit touching full.
+func specLastCommit(ctx context.Context, full string) spechistory.Commit {
+	root, err := sys.GitRoot(ctx, filepath.Dir(full))
+	if err != nil || strings.TrimSpace(root) == "" {
+		return spechistory.Commit{}
+	}
+	c, _, _ := spechistory.Last(ctx, root, relSafe(root, full))
+	return c
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"codectl/internal/history"
+	"codectl/internal/safefs"
+	"codectl/internal/speccheck"
+	"codectl/internal/specstatus"
+	"codectl/internal/system"
+	"codectl/internal/workspace"
+)
+
+// specStatusHandler lists the review state of every spec.
+// GET /api/spec/status?root=
+func specStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	dir, err := workspace.Resolve(r.Context(), r.URL.Query().Get("root"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	rules, err := speccheck.Load(dir)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	list, err := specstatus.List(dir, rules)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	if list == nil {
+		list = []specstatus.Info{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"specs": list})
+}
+
+// specTransitionRequest moves a spec to another review state.
+type specTransitionRequest struct {
+	Root string `json:"root"`
+	Base string `json:"base"`
+	Path string `json:"path"`
+	To   string `json:"to"`
+	// By is the approver recorded when entering a locked state; it
+	// defaults to the git user of the workspace.
+	By        string   `json:"by"`
+	Reviewers []string `json:"reviewers"`
+}
+
+// specTransitionHandler applies a workflow transition to a spec.
+// POST /api/spec/transition
+func specTransitionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var in specTransitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	if strings.TrimSpace(in.Path) == "" || strings.TrimSpace(in.To) == "" {
+		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("missing path or to")))
+		return
+	}
+	base, err := resolveBase(r, in.Root, in.Base)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	if _, err := secureJoin(base, in.Path); err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	b, err := safefs.ReadFile(base, in.Path)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, errJSON(err))
+		return
+	}
+	rules, err := loadSpecRules(r, in.Root, in.Base)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	by := in.By
+	if strings.TrimSpace(by) == "" {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		by, _ = system.GitUser(ctx, base)
+		cancel()
+	}
+	full := filepath.Join(base, in.Path)
+	out, info, err := specstatus.Transition(rules.workflow(full), b, specstatus.Request{To: in.To, By: by, Reviewers: in.Reviewers, Now: time.Now()})
+	if err != nil {
+		code := http.StatusBadRequest
+		if errors.Is(err, specstatus.ErrTransition) {
+			code = http.StatusConflict
+		}
+		writeJSON(w, code, errJSON(err))
+		return
+	}
+	if _, err := history.Snapshot(base, in.Path, history.OpWrite); err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	if err := safefs.WriteFile(base, in.Path, out, 0o644); err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	info.Path = filepath.ToSlash(in.Path)
+	writeJSON(w, http.StatusOK, info)
+}
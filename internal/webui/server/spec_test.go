@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tu "codectl/internal/testutil"
+)
+
+func TestSpecValidate_Fix(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+	repo := filepath.Join(tmp, "repo")
+	spec := filepath.Join(repo, "vibe-docs", "spec")
+	_ = os.MkdirAll(spec, 0o755)
+	doc := filepath.Join(spec, "a.spec.mdx")
+	_ = os.WriteFile(doc, []byte("# A doc\n\nbody\n"), 0o644)
+	wd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	// content mode: fixed content is returned, nothing is written
+	body := `{"base":"vibe-spec","path":"a.spec.mdx","content":"# A doc\n","fix":true}`
+	w := httptest.NewRecorder()
+	specValidateHandler(w, httptest.NewRequest(http.MethodPost, "/api/spec/validate", strings.NewReader(body)))
+	var res specFixResult
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("validate: %d %s", w.Code, w.Body.String())
+	}
+	if res.Written || !strings.HasPrefix(res.Content, "---\ntitle: A doc\n") || len(res.Errors) != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	// path mode: the file is rewritten
+	body = `{"base":"vibe-spec","path":"a.spec.mdx","fix":true}`
+	w = httptest.NewRecorder()
+	specValidateHandler(w, httptest.NewRequest(http.MethodPost, "/api/spec/validate", strings.NewReader(body)))
+	res = specFixResult{}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("validate: %d %s", w.Code, w.Body.String())
+	}
+	b, _ := os.ReadFile(doc)
+	if !res.Written || len(res.Fixed) == 0 || !strings.HasPrefix(string(b), "---\ntitle: A doc\n") {
+		t.Fatalf("file not fixed: %+v\n%s", res, b)
+	}
+}
+
+func TestSpecTransition(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+	repo := filepath.Join(tmp, "repo")
+	spec := filepath.Join(repo, "vibe-docs", "spec")
+	_ = os.MkdirAll(spec, 0o755)
+	doc := filepath.Join(spec, "a.spec.mdx")
+	_ = os.WriteFile(doc, []byte("---\ntitle: A\nstatus: review\n---\n# A\n"), 0o644)
+	wd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	transition := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		specTransitionHandler(w, httptest.NewRequest(http.MethodPost, "/api/spec/transition", strings.NewReader(body)))
+		return w
+	}
+	if w := transition(`{"base":"vibe-spec","path":"a.spec.mdx","to":"deprecated"}`); w.Code != http.StatusConflict {
+		t.Fatalf("review → deprecated: %d %s", w.Code, w.Body.String())
+	}
+	w := transition(`{"base":"vibe-spec","path":"a.spec.mdx","to":"accepted","by":"ann"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"locked":true`) {
+		t.Fatalf("accept: %d %s", w.Code, w.Body.String())
+	}
+	b, _ := os.ReadFile(doc)
+	if !strings.Contains(string(b), "status: accepted\napprovals:\n  - {by: ann, at: ") {
+		t.Fatalf("frontmatter not recorded:\n%s", b)
+	}
+
+	// accepted specs cannot be edited until reopened
+	put := func(content string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"base": "vibe-spec", "path": "a.spec.mdx", "content": content})
+		w := httptest.NewRecorder()
+		specDocHandler(w, httptest.NewRequest(http.MethodPut, "/api/spec/doc", strings.NewReader(string(body))))
+		return w
+	}
+	if w := put(string(b) + "more\n"); w.Code != http.StatusConflict {
+		t.Fatalf("locked edit: %d %s", w.Code, w.Body.String())
+	}
+	if w := transition(`{"base":"vibe-spec","path":"a.spec.mdx","to":"draft"}`); w.Code != http.StatusOK {
+		t.Fatalf("reopen: %d %s", w.Code, w.Body.String())
+	}
+	b, _ = os.ReadFile(doc)
+	if w := put(string(b) + "more\n"); w.Code != http.StatusOK {
+		t.Fatalf("edit after reopen: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	specStatusHandler(w, httptest.NewRequest(http.MethodGet, "/api/spec/status", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"path":"vibe-docs/spec/a.spec.mdx","title":"A","status":"draft"`) {
+		t.Fatalf("status: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDocsSearch(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+	repo := filepath.Join(tmp, "repo")
+	spec := filepath.Join(repo, "vibe-docs", "spec")
+	_ = os.MkdirAll(spec, 0o755)
+	_ = os.WriteFile(filepath.Join(spec, "a.spec.mdx"), []byte("---\ntitle: A\n---\n# A\n\n## 搜索\n\n倒排索引与中文分词。\n"), 0o644)
+	wd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(q string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		docsSearchHandler(w, httptest.NewRequest(http.MethodGet, "/api/docs/search?q="+q, nil))
+		return w
+	}
+	w := search("%E5%88%86%E8%AF%8D") // 分词
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"anchor":"搜索"`) {
+		t.Fatalf("search: %d %s", w.Code, w.Body.String())
+	}
+	// the cached index picks up new files
+	_ = os.WriteFile(filepath.Join(spec, "b.spec.mdx"), []byte("---\ntitle: B\n---\n分词器\n"), 0o644)
+	var res struct {
+		Results []struct{ Path string } `json:"results"`
+	}
+	if err := json.Unmarshal(search("%E5%88%86%E8%AF%8D").Body.Bytes(), &res); err != nil || len(res.Results) != 2 {
+		t.Fatalf("refresh: %+v %v", res, err)
+	}
+	if w := search(""); w.Code != http.StatusBadRequest {
+		t.Fatalf("empty query: %d", w.Code)
+	}
+}
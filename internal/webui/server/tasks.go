@@ -1,162 +1,502 @@
 package server
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
-	"sort"
 	"strings"
-)
+	"time"
 
-type taskItem struct {
-	Path     string            `json:"path"`
-	Title    string            `json:"title,omitempty"`
-	Status   string            `json:"status,omitempty"`
-	Owner    string            `json:"owner,omitempty"`
-	Priority string            `json:"priority,omitempty"`
-	Due      string            `json:"due,omitempty"`
-	Fields   map[string]string `json:"fields,omitempty"`
-}
+	"codectl/internal/agent"
+	"codectl/internal/history"
+	"codectl/internal/safefs"
+	"codectl/internal/spectemplate"
+	"codectl/internal/taskgen"
+	"codectl/internal/taskstore"
+	"codectl/internal/tools"
+	"codectl/internal/workspace"
+)
 
-// GET /api/tasks/list?status=&owner=&priority=&q=
+// GET /api/tasks/list?root=&status=&owner=&priority=&q=&archived=
 func tasksListHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	base, err := resolveBase(r, "repo")
+	q := r.URL.Query()
+	s, ok := openTaskStore(w, r, q.Get("root"))
+	if !ok {
+		return
+	}
+	items, err := s.List(taskstore.Filter{
+		Status:   q.Get("status"),
+		Owner:    q.Get("owner"),
+		Priority: q.Get("priority"),
+		Query:    q.Get("q"),
+		Archived: q.Get("archived") == "1" || q.Get("archived") == "true",
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// PUT /api/tasks/update { root, path, content }
+func tasksUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var in struct{ Root, Path, Content string }
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	base, err := resolveBase(r, in.Root, "repo")
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
 	root := filepath.Join(base, "vibe-docs", "task")
-	st, err := os.Stat(root)
-	if err != nil || !st.IsDir() {
-		writeJSON(w, http.StatusOK, []taskItem{})
+	if _, err := secureJoin(root, in.Path); err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	if _, err := history.Snapshot(root, in.Path, history.OpWrite); err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
 		return
 	}
-	qStatus := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("status")))
-	qOwner := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("owner")))
-	qPri := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("priority")))
-	q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	if err := safefs.WriteFile(root, in.Path, []byte(in.Content), 0o644); err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	it := taskstore.Parse([]byte(in.Content))
+	it.Path = filepath.ToSlash(in.Path)
+	writeJSON(w, http.StatusOK, it)
+}
 
-	items := make([]taskItem, 0, 16)
-	_ = filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-		if d.IsDir() {
-			return nil
-		}
-		name := strings.ToLower(d.Name())
-		if !strings.HasSuffix(name, ".task.mdx") {
-			return nil
-		}
-		it := parseTaskMDX(p)
-		it.Path = filepath.ToSlash(relSafe(root, p))
-		// filters
-		if qStatus != "" && strings.ToLower(it.Status) != qStatus {
-			return nil
-		}
-		if qOwner != "" && strings.ToLower(it.Owner) != qOwner {
-			return nil
-		}
-		if qPri != "" && strings.ToUpper(it.Priority) != qPri {
-			return nil
+// tasksGenerateResult lists the task documents written for a spec.
+type tasksGenerateResult struct {
+	Spec  string           `json:"spec"` // repository-relative
+	Tasks []taskstore.Task `json:"tasks"`
+}
+
+// POST /api/tasks/generate { root, base, path, agent, model }
+// Sends the spec at path (beneath base, default vibe-spec) to the coding
+// agent (codex|claude|gemini, default codex), writes one task document per
+// returned task and lists them.
+func tasksGenerateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var in struct{ Root, Base, Path, Agent, Model string }
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	if strings.TrimSpace(in.Base) == "" {
+		in.Base = "vibe-spec"
+	}
+	if strings.TrimSpace(in.Agent) == "" {
+		in.Agent = string(tools.ToolCodex)
+	}
+	id, err := agent.Parse(in.Agent)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	base, err := resolveBase(r, in.Root, in.Base)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	full, err := secureJoin(base, in.Path)
+	if err != nil || strings.TrimSpace(in.Path) == "" {
+		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("invalid spec path")))
+		return
+	}
+	dir, err := workspace.Resolve(r.Context(), in.Root)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	spec := relSafe(dir, full)
+	if strings.HasPrefix(spec, "../") {
+		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("spec is outside the workspace")))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), taskAgentTimeout)
+	defer cancel()
+	files, err := taskgen.Generate(ctx, taskAgent(id, in.Model), dir, spec, time.Now())
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		code := http.StatusBadGateway
+		if errors.Is(err, os.ErrNotExist) {
+			code = http.StatusNotFound
 		}
-		if q != "" {
-			if !(strings.Contains(strings.ToLower(it.Title), q) || strings.Contains(strings.ToLower(it.Path), q)) {
-				return nil
-			}
+		writeJSON(w, code, errJSON(err))
+		return
+	}
+	if err := taskgen.Write(dir, files); err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	res := tasksGenerateResult{Spec: spec, Tasks: make([]taskstore.Task, 0, len(files))}
+	for _, f := range files {
+		it := taskstore.Parse(f.Content)
+		it.Path = strings.TrimPrefix(f.Path, taskgen.Dir+"/")
+		res.Tasks = append(res.Tasks, it)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// taskAgentTimeout bounds one task generation run.
+const taskAgentTimeout = 5 * time.Minute
+
+// taskAgent returns the runner for task generation with agent id and model;
+// only the agent's final answer is parsed. Tests replace it.
+var taskAgent = func(id tools.ToolID, model string) taskgen.Runner {
+	return func(ctx context.Context, prompt string) (string, error) {
+		res, err := agent.Run(ctx, id, prompt, agent.Options{Model: model})
+		return res.Output, err
+	}
+}
+
+// openTaskStore opens the task store of the workspace root, writing the
+// error response when that fails.
+func openTaskStore(w http.ResponseWriter, r *http.Request, root string) (*taskstore.Store, bool) {
+	dir, err := workspace.Resolve(r.Context(), root)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return nil, false
+	}
+	s, err := taskstore.Open(dir)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return nil, false
+	}
+	return s, true
+}
+
+// taskErrStatus maps task store errors to HTTP status codes.
+func taskErrStatus(err error) int {
+	switch {
+	case errors.Is(err, taskstore.ErrWIP):
+		return http.StatusConflict
+	case errors.Is(err, taskstore.ErrInvalid):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, taskstore.ErrNotFound), errors.Is(err, os.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, spectemplate.ErrNotFound), errors.Is(err, safefs.ErrEscape):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GET /api/tasks/enums?root=
+// Returns the allowed status and priority values from the rules file.
+func tasksEnumsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s, ok := openTaskStore(w, r, r.URL.Query().Get("root"))
+	if !ok {
+		return
+	}
+	out := map[string][]string{}
+	for k, f := range s.Rules().Fields {
+		if len(f.Enum) > 0 {
+			out[k] = f.Enum
 		}
-		items = append(items, it)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// taskCreateRequest creates a task from the standard skeleton or a template.
+type taskCreateRequest struct {
+	Root     string            `json:"root"`
+	Title    string            `json:"title"`
+	Template string            `json:"template"`
+	Vars     map[string]string `json:"vars"`
+	Fields   map[string]string `json:"fields"`
+}
+
+// POST /api/tasks/create { root, title, template?, vars?, fields? }
+func tasksCreateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var in taskCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	s, ok := openTaskStore(w, r, in.Root)
+	if !ok {
+		return
+	}
+	t, err := s.Create(taskstore.Create{Title: in.Title, Template: in.Template, Vars: in.Vars, Fields: in.Fields})
+	if err != nil {
+		writeJSON(w, taskErrStatus(err), errJSON(err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+// taskSetRequest changes frontmatter fields of one task. Field/Value set a
+// single field; Fields sets several. An empty value removes the field.
+type taskSetRequest struct {
+	Root   string            `json:"root"`
+	Path   string            `json:"path"`
+	Field  string            `json:"field"`
+	Value  string            `json:"value"`
+	Fields map[string]string `json:"fields"`
+}
+
+// POST /api/tasks/set { root, path, field, value } or { root, path, fields }
+// Returns the task with the tasks unblocked when the edit closed it.
+func tasksSetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPatch {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var in taskSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	fields := map[string]string{}
+	for k, v := range in.Fields {
+		fields[k] = v
+	}
+	if in.Field != "" {
+		fields[in.Field] = in.Value
+	}
+	if len(fields) == 0 {
+		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("no fields to set")))
+		return
+	}
+	s, ok := openTaskStore(w, r, in.Root)
+	if !ok {
+		return
+	}
+	prev, _, err := s.Get(in.Path)
+	if err != nil {
+		writeJSON(w, taskErrStatus(err), errJSON(err))
+		return
+	}
+	t, err := s.Set(in.Path, fields)
+	if err != nil {
+		writeJSON(w, taskErrStatus(err), errJSON(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, taskChange{Task: t, Unblocked: releasedBy(s, prev.Status, t)})
+}
+
+// taskChange is a task after an edit with the tasks the edit unblocked.
+type taskChange struct {
+	taskstore.Task
+	Unblocked []string `json:"unblocked,omitempty"`
+}
+
+// releasedBy returns the tasks unblocked by an edit that closed t, which
+// had status before.
+func releasedBy(s *taskstore.Store, before string, t taskstore.Task) []string {
+	if s.Closed(before) || !s.Closed(t.Status) {
 		return nil
-	})
-	sort.Slice(items, func(i, j int) bool { return strings.ToLower(items[i].Path) < strings.ToLower(items[j].Path) })
-	writeJSON(w, http.StatusOK, items)
+	}
+	out, _ := s.Released([]string{t.Path})
+	return out
+}
+
+// POST /api/tasks/delete { root, path }
+func tasksDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var in struct{ Root, Path string }
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	s, ok := openTaskStore(w, r, in.Root)
+	if !ok {
+		return
+	}
+	if err := s.Delete(in.Path); err != nil {
+		writeJSON(w, taskErrStatus(err), errJSON(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
-func parseTaskMDX(p string) taskItem {
-	b, err := os.ReadFile(p)
+// POST /api/tasks/archive { root, path }
+// Moves the task to the archive directory and rewrites links to it.
+func tasksArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var in struct{ Root, Path string }
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	s, ok := openTaskStore(w, r, in.Root)
+	if !ok {
+		return
+	}
+	t, err := s.Archive(in.Path)
 	if err != nil {
-		return taskItem{Path: filepath.ToSlash(p)}
+		writeJSON(w, taskErrStatus(err), errJSON(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// taskBulkRequest applies one action to every task matching Filter. An
+// empty filter matches all tasks, so it must be requested with All.
+type taskBulkRequest struct {
+	Root   string            `json:"root"`
+	Filter taskstore.Filter  `json:"filter"`
+	All    bool              `json:"all"`
+	Action taskstore.Action  `json:"action"`
+	Fields map[string]string `json:"fields"`
+}
+
+// POST /api/tasks/bulk { root, filter, all?, action: set|delete|archive, fields? }
+func tasksBulkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
 	}
-	s := string(b)
-	rd := bufio.NewReader(strings.NewReader(s))
-	first, _ := rd.ReadString('\n')
-	first = strings.TrimRight(first, "\r\n")
-	if first != "---" {
-		return taskItem{Path: filepath.ToSlash(p)}
+	var in taskBulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
 	}
-	lines := strings.Split(s, "\n")
-	endIdx := -1
-	for i := 1; i < len(lines); i++ {
-		if strings.TrimRight(lines[i], "\r") == "---" {
-			endIdx = i
-			break
+	f := in.Filter
+	if !in.All && f.Status == "" && f.Owner == "" && f.Priority == "" && f.Query == "" && len(f.Paths) == 0 {
+		writeJSON(w, http.StatusBadRequest, errJSON(errors.New("empty filter (set all to apply to every task)")))
+		return
+	}
+	s, ok := openTaskStore(w, r, in.Root)
+	if !ok {
+		return
+	}
+	before := map[string]string{}
+	if ts, err := s.List(f); err == nil {
+		for _, t := range ts {
+			before[t.Path] = t.Status
 		}
 	}
-	fm := map[string]string{}
-	if endIdx > 1 {
-		keyRe := regexp.MustCompile(`^([A-Za-z0-9_-]+)\s*:\s*(.*)$`)
-		for _, ln := range lines[1:endIdx] {
-			l := strings.TrimSpace(ln)
-			if l == "" || strings.HasPrefix(l, "#") {
-				continue
-			}
-			if m := keyRe.FindStringSubmatch(l); len(m) == 3 {
-				k := strings.ToLower(strings.TrimSpace(m[1]))
-				v := strings.TrimSpace(m[2])
-				if len(v) >= 2 {
-					if (v[0] == '\'' && v[len(v)-1] == '\'') || (v[0] == '"' && v[len(v)-1] == '"') {
-						v = v[1 : len(v)-1]
-					}
-				}
-				fm[k] = v
-			}
+	res, err := s.Bulk(f, in.Action, in.Fields)
+	if err != nil {
+		writeJSON(w, taskErrStatus(err), errJSON(err))
+		return
+	}
+	var closed []string
+	for _, o := range res {
+		if o.Task != nil && !s.Closed(before[o.Path]) && s.Closed(o.Task.Status) {
+			closed = append(closed, o.Task.Path)
 		}
 	}
-	it := taskItem{Fields: fm}
-	it.Title = fm["title"]
-	it.Status = fm["status"]
-	it.Owner = fm["owner"]
-	it.Priority = fm["priority"]
-	it.Due = fm["due"]
-	return it
+	unblocked, _ := s.Released(closed)
+	writeJSON(w, http.StatusOK, map[string]any{"results": res, "unblocked": unblocked})
 }
 
-// PUT /api/tasks/update { path, content }
-func tasksUpdateHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
+// GET /api/tasks/board?root=&owner=&priority=&q=
+// Returns the task board: configured columns with their tasks in manual
+// order, task counts and WIP limits.
+func tasksBoardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	var in struct{ Path, Content string }
+	q := r.URL.Query()
+	s, ok := openTaskStore(w, r, q.Get("root"))
+	if !ok {
+		return
+	}
+	b, err := s.Board(taskstore.Filter{Owner: q.Get("owner"), Priority: q.Get("priority"), Query: q.Get("q")})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+// taskMoveRequest moves a task on the board.
+type taskMoveRequest struct {
+	Root string `json:"root"`
+	taskstore.Move
+}
+
+// POST /api/tasks/move { root, path, column, index, force? }
+// Writes the task's status and order in one edit and returns the task, the
+// updated board and the tasks unblocked by the move; a full column answers
+// 409 unless force is set.
+func tasksMoveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	in := taskMoveRequest{Move: taskstore.Move{Index: -1}}
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		writeJSON(w, http.StatusBadRequest, errJSON(err))
 		return
 	}
-	base, err := resolveBase(r, "repo")
+	s, ok := openTaskStore(w, r, in.Root)
+	if !ok {
+		return
+	}
+	prev, _, err := s.Get(in.Path)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		writeJSON(w, taskErrStatus(err), errJSON(err))
 		return
 	}
-	root := filepath.Join(base, "vibe-docs", "task")
-	full, err := secureJoin(root, in.Path)
+	t, err := s.Move(in.Move)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		writeJSON(w, taskErrStatus(err), errJSON(err))
 		return
 	}
-	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+	b, err := s.Board(taskstore.Filter{})
+	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, errJSON(err))
 		return
 	}
-	if err := os.WriteFile(full, []byte(in.Content), 0o644); err != nil {
+	writeJSON(w, http.StatusOK, map[string]any{"task": t, "board": b, "unblocked": releasedBy(s, prev.Status, t)})
+}
+
+// GET /api/tasks/graph?root=
+// Returns the task dependency graph with a topological order, the open
+// tasks whose dependencies are all closed, cycles and missing references.
+func tasksGraphHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s, ok := openTaskStore(w, r, r.URL.Query().Get("root"))
+	if !ok {
+		return
+	}
+	g, err := s.Graph()
+	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, errJSON(err))
 		return
 	}
-	writeJSON(w, http.StatusOK, parseTaskMDX(full))
+	if g.Nodes == nil {
+		g.Nodes = []taskstore.GraphNode{}
+	}
+	writeJSON(w, http.StatusOK, g)
 }
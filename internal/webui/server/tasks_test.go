@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codectl/internal/taskgen"
+	tu "codectl/internal/testutil"
+	"codectl/internal/tools"
+)
+
+func TestTasksGenerate(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+	repo := filepath.Join(tmp, "repo")
+	spec := filepath.Join(repo, "vibe-docs", "spec")
+	_ = os.MkdirAll(spec, 0o755)
+	_ = os.WriteFile(filepath.Join(spec, "a.spec.mdx"), []byte("---\ntitle: A\n---\n## 验收标准\n- works\n"), 0o644)
+	wd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+	prev := taskAgent
+	defer func() { taskAgent = prev }()
+	var gotAgent tools.ToolID
+	var gotModel string
+	taskAgent = func(id tools.ToolID, model string) taskgen.Runner {
+		gotAgent, gotModel = id, model
+		return func(context.Context, string) (string, error) {
+			return `[{"title":"Build A","criteria":[1]}]`, nil
+		}
+	}
+
+	w := httptest.NewRecorder()
+	tasksGenerateHandler(w, httptest.NewRequest(http.MethodPost, "/api/tasks/generate", strings.NewReader(`{"path":"a.spec.mdx"}`)))
+	var res tasksGenerateResult
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("generate: %d %s", w.Code, w.Body.String())
+	}
+	if res.Spec != "vibe-docs/spec/a.spec.mdx" || len(res.Tasks) != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if gotAgent != tools.ToolCodex || gotModel != "" {
+		t.Fatalf("default agent = %q %q", gotAgent, gotModel)
+	}
+	it := res.Tasks[0]
+	if it.Title != "Build A" || it.Status != "todo" || !strings.HasSuffix(it.Path, "-build-a.task.mdx") {
+		t.Fatalf("unexpected task: %+v", it)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "vibe-docs", "task", it.Path)); err != nil {
+		t.Fatalf("task not written: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	tasksGenerateHandler(w, httptest.NewRequest(http.MethodPost, "/api/tasks/generate", strings.NewReader(`{"path":"missing.spec.mdx"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("missing spec: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	tasksGenerateHandler(w, httptest.NewRequest(http.MethodPost, "/api/tasks/generate", strings.NewReader(`{"path":"a.spec.mdx","agent":"Claude","model":"opus"}`)))
+	if w.Code != http.StatusOK || gotAgent != tools.ToolClaude || gotModel != "opus" {
+		t.Fatalf("agent/model not passed: %d %q %q", w.Code, gotAgent, gotModel)
+	}
+
+	w = httptest.NewRecorder()
+	tasksGenerateHandler(w, httptest.NewRequest(http.MethodPost, "/api/tasks/generate", strings.NewReader(`{"path":"a.spec.mdx","agent":"nope"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown agent: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTasksLifecycle(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+	repo := filepath.Join(tmp, "repo")
+	_ = os.MkdirAll(filepath.Join(repo, "vibe-docs", "task"), 0o755)
+	_ = os.WriteFile(filepath.Join(repo, "vibe-docs", ".specrules.json"), []byte(`{"dirs":{"vibe-docs/task":{"fields":{"priority":{"enum":["P0","P1","P2"]}}}}}`), 0o644)
+	wd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+	call := func(h http.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(method, "/api/tasks/x", strings.NewReader(body)))
+		return w
+	}
+
+	w := call(tasksCreateHandler, http.MethodPost, `{"title":"Ship it","fields":{"priority":"p1"}}`)
+	var created struct{ Path, Status, Priority string }
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	if created.Status != "todo" || created.Priority != "P1" || !strings.HasSuffix(created.Path, "-ship-it.task.mdx") {
+		t.Fatalf("unexpected task: %+v", created)
+	}
+	if w := call(tasksCreateHandler, http.MethodPost, `{"title":"Bad","fields":{"priority":"P7"}}`); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid priority: %d %s", w.Code, w.Body.String())
+	}
+
+	w = call(tasksSetHandler, http.MethodPatch, `{"path":"`+created.Path+`","field":"owner","value":"ann"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"owner":"ann"`) {
+		t.Fatalf("set: %d %s", w.Code, w.Body.String())
+	}
+	if w := call(tasksSetHandler, http.MethodPost, `{"path":"missing.task.mdx","field":"owner","value":"x"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("set missing: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := call(tasksBulkHandler, http.MethodPost, `{"action":"set","fields":{"priority":"P0"}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("bulk without filter: %d %s", w.Code, w.Body.String())
+	}
+	w = call(tasksBulkHandler, http.MethodPost, `{"filter":{"owner":"ann"},"action":"archive"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"path":"archive/`) {
+		t.Fatalf("bulk archive: %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	tasksListHandler(w, httptest.NewRequest(http.MethodGet, "/api/tasks/list", nil))
+	if strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Fatalf("archived task listed: %s", w.Body.String())
+	}
+	archived := "archive/" + filepath.Base(created.Path)
+	if w := call(tasksDeleteHandler, http.MethodPost, `{"path":"`+archived+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(repo, "vibe-docs", "task", filepath.FromSlash(archived))); !os.IsNotExist(err) {
+		t.Fatalf("not deleted: %v", err)
+	}
+}
+
+func TestTasksBoard(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+	repo := filepath.Join(tmp, "repo")
+	dir := filepath.Join(repo, "vibe-docs", "task")
+	_ = os.MkdirAll(dir, 0o755)
+	_ = os.WriteFile(filepath.Join(repo, "vibe-docs", ".specrules.json"), []byte(`{"dirs":{"vibe-docs/task":{"board":{"columns":[{"id":"todo"},{"id":"doing","statuses":["in-progress"],"wip":1},{"id":"done"}]}}}}`), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "a.task.mdx"), []byte("---\ntitle: A\nstatus: todo\n---\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "b.task.mdx"), []byte("---\ntitle: B\nstatus: in-progress\n---\n"), 0o644)
+	wd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	tasksBoardHandler(w, httptest.NewRequest(http.MethodGet, "/api/tasks/board", nil))
+	var board struct {
+		Columns []struct {
+			ID    string
+			WIP   int
+			Count int
+			Tasks []struct{ Path string }
+		}
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &board); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("board: %d %s", w.Code, w.Body.String())
+	}
+	if len(board.Columns) != 3 || board.Columns[1].ID != "doing" || board.Columns[1].WIP != 1 || board.Columns[1].Count != 1 {
+		t.Fatalf("unexpected board: %+v", board)
+	}
+
+	move := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		tasksMoveHandler(w, httptest.NewRequest(http.MethodPost, "/api/tasks/move", strings.NewReader(body)))
+		return w
+	}
+	if w := move(`{"path":"a.task.mdx","column":"doing"}`); w.Code != http.StatusConflict {
+		t.Fatalf("WIP limit: %d %s", w.Code, w.Body.String())
+	}
+	w = move(`{"path":"a.task.mdx","column":"doing","index":0,"force":true}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"in-progress"`) {
+		t.Fatalf("move: %d %s", w.Code, w.Body.String())
+	}
+	b, _ := os.ReadFile(filepath.Join(dir, "a.task.mdx"))
+	if !strings.Contains(string(b), "status: in-progress\norder: 1024\n") {
+		t.Fatalf("not written:\n%s", b)
+	}
+	if w := move(`{"path":"a.task.mdx","column":"later"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown column: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTasksGraph(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+	repo := filepath.Join(tmp, "repo")
+	dir := filepath.Join(repo, "vibe-docs", "task")
+	_ = os.MkdirAll(dir, 0o755)
+	_ = os.WriteFile(filepath.Join(dir, "a.task.mdx"), []byte("---\ntitle: A\nstatus: in-progress\n---\n"), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "b.task.mdx"), []byte("---\ntitle: B\nstatus: todo\ndependsOn: [a]\n---\n"), 0o644)
+	wd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	tasksGraphHandler(w, httptest.NewRequest(http.MethodGet, "/api/tasks/graph", nil))
+	var g struct {
+		Order     []string
+		Unblocked []string
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &g); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("graph: %d %s", w.Code, w.Body.String())
+	}
+	if strings.Join(g.Order, ",") != "a.task.mdx,b.task.mdx" || strings.Join(g.Unblocked, ",") != "a.task.mdx" {
+		t.Fatalf("unexpected graph: %+v", g)
+	}
+
+	w = httptest.NewRecorder()
+	tasksSetHandler(w, httptest.NewRequest(http.MethodPost, "/api/tasks/set", strings.NewReader(`{"path":"a.task.mdx","field":"status","value":"done"}`)))
+	var res struct {
+		Status    string
+		Unblocked []string
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("set: %d %s", w.Code, w.Body.String())
+	}
+	if res.Status != "done" || strings.Join(res.Unblocked, ",") != "b.task.mdx" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
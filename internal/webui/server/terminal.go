@@ -9,12 +9,16 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/creack/pty"
 	"github.com/gorilla/websocket"
+
+	"codectl/internal/workspace"
 )
 
 // wsUpgrader upgrades HTTP connections to WebSocket.
@@ -31,7 +35,14 @@ var wsUpgrader = websocket.Upgrader{
 // - Send plain text messages as input to the shell.
 // - Control messages are JSON: {"type":"resize","cols":<int>,"rows":<int>}.
 // - Server sends PTY output as text messages.
+//
+// The shell starts in the directory chosen by terminalDir.
 func terminalWSHandler(w http.ResponseWriter, r *http.Request) {
+	dir, err := terminalDir(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	conn, err := wsUpgrader.Upgrade(w, r, nil)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("upgrade failed: %v", err), http.StatusBadRequest)
@@ -43,10 +54,7 @@ func terminalWSHandler(w http.ResponseWriter, r *http.Request) {
 	sh, shArgs := defaultShell()
 	cmd := exec.Command(sh, shArgs...)
 
-	// Inherit working dir from server process; allow overriding via ?cwd=
-	if q := r.URL.Query().Get("cwd"); q != "" {
-		cmd.Dir = q
-	}
+	cmd.Dir = dir
 
 	// Create PTY
 	ptmx, err := pty.Start(cmd)
@@ -126,6 +134,29 @@ func terminalWSHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// terminalDir returns the shell's working directory: workspace root ?root=
+// (default repo), or ?cwd= within it. cwd may be relative to the root or,
+// as before roots existed, an absolute path; either way it must stay inside
+// the root, so an absolute path elsewhere is rejected rather than followed.
+func terminalDir(r *http.Request) (string, error) {
+	dir, err := workspace.Resolve(r.Context(), r.URL.Query().Get("root"))
+	if err != nil {
+		return "", err
+	}
+	q := r.URL.Query().Get("cwd")
+	if q == "" {
+		return dir, nil
+	}
+	if filepath.IsAbs(q) {
+		rel, err := filepath.Rel(dir, filepath.Clean(q))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("cwd %s is outside workspace root %s", q, dir)
+		}
+		q = rel
+	}
+	return secureJoin(dir, q)
+}
+
 // defaultShell returns the platform-appropriate shell and arguments.
 func defaultShell() (string, []string) {
 	if runtime.GOOS == "windows" {
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http"
+
+	"codectl/internal/spectrace"
+	"codectl/internal/workspace"
+)
+
+// traceHandler reports which tasks implement each spec section.
+// GET /api/trace?root=
+func traceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	dir, err := workspace.Resolve(r.Context(), r.URL.Query().Get("root"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errJSON(err))
+		return
+	}
+	rep, err := spectrace.Build(dir)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
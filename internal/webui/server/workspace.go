@@ -0,0 +1,28 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"codectl/internal/workspace"
+)
+
+// errWorkspaceReadOnly explains where roots are configured. Roots widen what
+// every fs, spec, task, diff and terminal API may touch, so they cannot be
+// changed over HTTP.
+var errWorkspaceReadOnly = errors.New("workspace roots are read-only here; use `codectl workspace add|rm` or edit ~/.codectl/workspace.json")
+
+// workspaceHandler lists (GET) the configured workspace roots, resolved and
+// including the built-in "repo". Other methods are rejected.
+func workspaceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, errJSON(errWorkspaceReadOnly))
+		return
+	}
+	roots, err := workspace.Roots(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errJSON(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"roots": roots})
+}
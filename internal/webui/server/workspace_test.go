@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tu "codectl/internal/testutil"
+	"codectl/internal/workspace"
+)
+
+// workspaceRepo chdirs into a fresh repo directory with HOME in tmp and one
+// configured root "docs".
+func workspaceRepo(t *testing.T) (repo, docs string) {
+	t.Helper()
+	tmp := t.TempDir()
+	t.Cleanup(tu.WithEnv(t, "HOME", tmp))
+	repo = filepath.Join(tmp, "repo")
+	docs = filepath.Join(tmp, "docs")
+	_ = os.MkdirAll(filepath.Join(repo, "sub"), 0o755)
+	_ = os.MkdirAll(docs, 0o755)
+	wd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := workspace.Save(workspace.Config{Roots: []workspace.Root{{ID: "docs", Path: docs}}}); err != nil {
+		t.Fatal(err)
+	}
+	return repo, docs
+}
+
+func TestWorkspaceHandler_ReadOnly(t *testing.T) {
+	workspaceRepo(t)
+
+	rr := httptest.NewRecorder()
+	workspaceHandler(rr, httptest.NewRequest(http.MethodGet, "/api/workspace", nil))
+	var got struct {
+		Roots []workspace.Root `json:"roots"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("GET: %d %s", rr.Code, rr.Body.String())
+	}
+	if len(got.Roots) != 2 || got.Roots[0].ID != workspace.RepoID || got.Roots[1].ID != "docs" {
+		t.Fatalf("unexpected roots: %+v", got.Roots)
+	}
+
+	for _, m := range []string{http.MethodPut, http.MethodPost, http.MethodDelete} {
+		body := `{"roots":[{"id":"all","path":"/"}]}`
+		rr := httptest.NewRecorder()
+		workspaceHandler(rr, httptest.NewRequest(m, "/api/workspace", strings.NewReader(body)))
+		if rr.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("%s: want 405, got %d", m, rr.Code)
+		}
+	}
+	if _, err := workspace.Resolve(t.Context(), "all"); err == nil {
+		t.Fatal("root registered over HTTP")
+	}
+}
+
+func TestResolveBase_RootAndBase(t *testing.T) {
+	repo, docs := workspaceRepo(t)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	cases := []struct {
+		root, base, want string
+	}{
+		{"", "", repo},
+		{"", "repo", repo},
+		{"", "vibe-spec", filepath.Join(repo, "vibe-docs", "spec")},
+		{"docs", "", docs},
+		{"docs", "vibe-spec", filepath.Join(docs, "vibe-docs", "spec")},
+	}
+	for _, c := range cases {
+		got, err := resolveBase(req, c.root, c.base)
+		if err != nil || !sameDir(got, c.want) {
+			t.Fatalf("resolveBase(%q, %q) = %q, %v; want %q", c.root, c.base, got, err, c.want)
+		}
+	}
+	// base is never a root id, and root ids must be configured.
+	for _, c := range [][2]string{{"", "docs"}, {"docs", "docs"}, {"nope", ""}} {
+		if got, err := resolveBase(req, c[0], c[1]); err == nil {
+			t.Fatalf("resolveBase(%q, %q) = %q, want error", c[0], c[1], got)
+		}
+	}
+}
+
+func TestLoadSpecRules_Root(t *testing.T) {
+	_, docs := workspaceRepo(t)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rs, err := loadSpecRules(req, "docs", "vibe-spec")
+	if err != nil || !sameDir(rs.dir, docs) {
+		t.Fatalf("loadSpecRules(docs) = %q, %v; want %q", rs.dir, err, docs)
+	}
+	for _, c := range [][2]string{{"", "docs"}, {"docs", "docs"}, {"nope", ""}} {
+		if _, err := loadSpecRules(req, c[0], c[1]); err == nil {
+			t.Fatalf("loadSpecRules(%q, %q) accepted", c[0], c[1])
+		}
+	}
+	body := `{"base":"docs","content":"# A\n"}`
+	rr := httptest.NewRecorder()
+	specValidateHandler(rr, httptest.NewRequest(http.MethodPost, "/api/spec/validate", strings.NewReader(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("validate with base=docs: want 400, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestTerminalDir(t *testing.T) {
+	repo, docs := workspaceRepo(t)
+	ok := map[string]string{
+		"":                                   repo,
+		"?cwd=sub":                           filepath.Join(repo, "sub"),
+		"?cwd=" + filepath.Join(repo, "sub"): filepath.Join(repo, "sub"),
+		"?root=docs":                         docs,
+		"?root=docs&cwd=" + docs:             docs,
+	}
+	for q, want := range ok {
+		got, err := terminalDir(httptest.NewRequest(http.MethodGet, "/api/terminal/ws"+q, nil))
+		if err != nil || !sameDir(got, want) {
+			t.Fatalf("terminalDir(%q) = %q, %v; want %q", q, got, err, want)
+		}
+	}
+	for _, q := range []string{"?cwd=..", "?cwd=" + docs, "?cwd=/", "?root=docs&cwd=" + repo, "?root=nope"} {
+		if got, err := terminalDir(httptest.NewRequest(http.MethodGet, "/api/terminal/ws"+q, nil)); err == nil {
+			t.Fatalf("terminalDir(%q) = %q, want error", q, got)
+		}
+	}
+}
+
+// sameDir compares directories after resolving symlinks, since the repo
+// root comes from git and may differ from the temp path (e.g. /private/var).
+func sameDir(a, b string) bool {
+	ea, err1 := filepath.EvalSymlinks(a)
+	eb, err2 := filepath.EvalSymlinks(b)
+	if err1 != nil || err2 != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return ea == eb
+}
@@ -0,0 +1,167 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	cfg "codectl/internal/config"
+	"codectl/internal/system"
+)
+
+// RepoID is the built-in root for the repository containing the working directory.
+const RepoID = "repo"
+
+// Root is a named directory the Web UI APIs may operate on.
+type Root struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// Path is absolute, or relative to the current repository root
+	// (useful for monorepo sub-packages).
+	Path    string `json:"path"`
+	Builtin bool   `json:"builtin,omitempty"`
+}
+
+// Config is the shape of ~/.codectl/workspace.json.
+type Config struct {
+	Roots []Root `json:"roots"`
+}
+
+// ErrUnknownRoot is returned when a root id is not configured.
+var ErrUnknownRoot = errors.New("unknown workspace root")
+
+func filePath() (string, error) {
+	dir, err := cfg.DotDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "workspace.json"), nil
+}
+
+// Load reads workspace.json; a missing file yields an empty config.
+func Load() (Config, error) {
+	p, err := filePath()
+	if err != nil {
+		return Config{}, err
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Config{Roots: []Root{}}, nil
+		}
+		return Config{}, err
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Config{}, err
+	}
+	return normalize(c), nil
+}
+
+// Save validates and writes workspace.json.
+func Save(c Config) error {
+	c = normalize(c)
+	seen := map[string]bool{}
+	for _, r := range c.Roots {
+		if r.ID == "" || r.Path == "" {
+			return errors.New("workspace root requires id and path")
+		}
+		if r.ID == RepoID || strings.ContainsAny(r.ID, `/\:`) {
+			return fmt.Errorf("invalid workspace root id %q", r.ID)
+		}
+		if seen[r.ID] {
+			return fmt.Errorf("duplicate workspace root id %q", r.ID)
+		}
+		seen[r.ID] = true
+	}
+	p, err := filePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, b, 0o644)
+}
+
+func normalize(in Config) Config {
+	out := Config{Roots: make([]Root, 0, len(in.Roots))}
+	for _, r := range in.Roots {
+		r.ID = strings.TrimSpace(r.ID)
+		r.Name = strings.TrimSpace(r.Name)
+		r.Path = strings.TrimSpace(r.Path)
+		r.Builtin = false
+		if r.ID == "" && r.Path == "" {
+			continue
+		}
+		out.Roots = append(out.Roots, r)
+	}
+	sort.SliceStable(out.Roots, func(i, j int) bool { return out.Roots[i].ID < out.Roots[j].ID })
+	return out
+}
+
+// RepoRoot returns the git top-level of the working directory, falling back to it.
+func RepoRoot(ctx context.Context) string {
+	cwd, _ := os.Getwd()
+	cctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if gi, err := system.GitRoot(cctx, cwd); err == nil && strings.TrimSpace(gi) != "" {
+		return gi
+	}
+	return cwd
+}
+
+// Roots returns the built-in repo root followed by configured roots, with resolved paths.
+func Roots(ctx context.Context) ([]Root, error) {
+	repo := RepoRoot(ctx)
+	out := []Root{{ID: RepoID, Name: filepath.Base(repo), Path: repo, Builtin: true}}
+	c, err := Load()
+	if err != nil {
+		return out, err
+	}
+	for _, r := range c.Roots {
+		r.Path = absPath(repo, r.Path)
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// Resolve returns the absolute directory for root id ("" means repo).
+func Resolve(ctx context.Context, id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" || id == RepoID {
+		return RepoRoot(ctx), nil
+	}
+	c, err := Load()
+	if err != nil {
+		return "", err
+	}
+	for _, r := range c.Roots {
+		if r.ID == id {
+			return absPath(RepoRoot(ctx), r.Path), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownRoot, id)
+}
+
+func absPath(repo, p string) string {
+	if strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, p[2:])
+		}
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(repo, p)
+	}
+	return filepath.Clean(p)
+}
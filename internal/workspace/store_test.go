@@ -0,0 +1,64 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	tu "codectl/internal/testutil"
+)
+
+func TestWorkspace_SaveResolve(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+
+	c, err := Load()
+	if err != nil || len(c.Roots) != 0 {
+		t.Fatalf("expected empty config, got %v, %v", c, err)
+	}
+
+	docs := filepath.Join(tmp, "docs")
+	in := Config{Roots: []Root{
+		{ID: "docs", Path: docs},
+		{ID: "pkg", Path: "packages/a"},
+	}}
+	if err := Save(in); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	ctx := context.Background()
+	got, err := Resolve(ctx, "docs")
+	if err != nil || got != docs {
+		t.Fatalf("Resolve docs = %q, %v", got, err)
+	}
+	got, err = Resolve(ctx, "pkg")
+	if err != nil || got != filepath.Join(RepoRoot(ctx), "packages", "a") {
+		t.Fatalf("Resolve pkg = %q, %v", got, err)
+	}
+	if _, err := Resolve(ctx, "nope"); !errors.Is(err, ErrUnknownRoot) {
+		t.Fatalf("expected ErrUnknownRoot, got %v", err)
+	}
+
+	roots, err := Roots(ctx)
+	if err != nil || len(roots) != 3 || roots[0].ID != RepoID || !roots[0].Builtin {
+		t.Fatalf("unexpected roots: %+v, %v", roots, err)
+	}
+}
+
+func TestWorkspace_SaveRejectsInvalid(t *testing.T) {
+	tmp := t.TempDir()
+	defer tu.WithEnv(t, "HOME", tmp)()
+
+	bad := []Config{
+		{Roots: []Root{{ID: "repo", Path: "/x"}}},
+		{Roots: []Root{{ID: "a", Path: "/x"}, {ID: "a", Path: "/y"}}},
+		{Roots: []Root{{ID: "a/b", Path: "/x"}}},
+		{Roots: []Root{{ID: "a"}}},
+	}
+	for i, c := range bad {
+		if err := Save(c); err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+	}
+}